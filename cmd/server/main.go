@@ -1,15 +1,21 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
 	"go.uber.org/dig"
+	"go.uber.org/zap"
 
 	authRepo "github.com/Cora23tt/order_service/internal/repository/auth"
 	authHandler "github.com/Cora23tt/order_service/internal/rest/handlers/auth"
@@ -27,12 +33,54 @@ import (
 	productHandler "github.com/Cora23tt/order_service/internal/rest/handlers/product"
 	productService "github.com/Cora23tt/order_service/internal/usecase/product"
 
+	cartRepo "github.com/Cora23tt/order_service/internal/repository/cart"
+	cartHandler "github.com/Cora23tt/order_service/internal/rest/handlers/cart"
+	cartService "github.com/Cora23tt/order_service/internal/usecase/cart"
+
+	promotionRepo "github.com/Cora23tt/order_service/internal/repository/promotion"
+	promotionHandler "github.com/Cora23tt/order_service/internal/rest/handlers/promotion"
+	promotionService "github.com/Cora23tt/order_service/internal/usecase/promotion"
+
+	paymentRepo "github.com/Cora23tt/order_service/internal/repository/payment"
+	paymentHandler "github.com/Cora23tt/order_service/internal/rest/handlers/payment"
+	paymentService "github.com/Cora23tt/order_service/internal/usecase/payment"
+
+	returnRepo "github.com/Cora23tt/order_service/internal/repository/returns"
+	returnHandler "github.com/Cora23tt/order_service/internal/rest/handlers/returns"
+	returnService "github.com/Cora23tt/order_service/internal/usecase/returns"
+
+	pickupRepo "github.com/Cora23tt/order_service/internal/repository/pickup"
+	pickupHandler "github.com/Cora23tt/order_service/internal/rest/handlers/pickup"
+	pickupService "github.com/Cora23tt/order_service/internal/usecase/pickup"
+
+	deliveryRepo "github.com/Cora23tt/order_service/internal/repository/delivery"
+	deliveryHandler "github.com/Cora23tt/order_service/internal/rest/handlers/delivery"
+	deliveryService "github.com/Cora23tt/order_service/internal/usecase/delivery"
+
+	receiptHandler "github.com/Cora23tt/order_service/internal/rest/handlers/receipt"
+	receiptService "github.com/Cora23tt/order_service/internal/usecase/receipt"
+
+	idempotencyRepo "github.com/Cora23tt/order_service/internal/repository/idempotency"
+	idempotencyService "github.com/Cora23tt/order_service/internal/usecase/idempotency"
+
 	uowRepo "github.com/Cora23tt/order_service/internal/repository/uow"
 
+	healthHandler "github.com/Cora23tt/order_service/internal/rest/handlers/health"
+
 	"github.com/Cora23tt/order_service/internal/rest"
 	"github.com/Cora23tt/order_service/internal/rest/middleware"
+	"github.com/Cora23tt/order_service/internal/worker"
+	"github.com/Cora23tt/order_service/pkg/config"
 	"github.com/Cora23tt/order_service/pkg/db"
+	"github.com/Cora23tt/order_service/pkg/health"
+	"github.com/Cora23tt/order_service/pkg/lifecycle"
 	"github.com/Cora23tt/order_service/pkg/logger"
+	"github.com/Cora23tt/order_service/pkg/metrics"
+	"github.com/Cora23tt/order_service/pkg/payment"
+	"github.com/Cora23tt/order_service/pkg/receipt"
+	"github.com/Cora23tt/order_service/pkg/storage"
+	"github.com/Cora23tt/order_service/pkg/tax"
+	"github.com/Cora23tt/order_service/pkg/tracing"
 )
 
 // @title           Order Service API
@@ -55,7 +103,11 @@ func main() {
 	if err != nil {
 		log.Println("Error loading .env file, using default environment variables:", err)
 	}
-	if err := execute(); err != nil {
+	run := execute
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		run = func() error { return migrate(os.Args[2:]) }
+	}
+	if err := run(); err != nil {
 		log.Println(err)
 		os.Exit(1)
 	}
@@ -63,10 +115,19 @@ func main() {
 
 func execute() error {
 	deps := []any{
+		config.Load,
 		logger.New,
+		lifecycle.New,
+		tracing.New,
 		db.NewDB,
+		db.NewMigrator,
+		tax.New,
+		payment.New,
+		storage.New,
+		receipt.New,
 		gin.New,
 		func(s *authService.Service) middleware.AuthValidator { return s },
+		func(s *idempotencyService.Service) middleware.IdempotencyStore { return s },
 		middleware.New,
 
 		authHandler.NewHandler,
@@ -85,12 +146,47 @@ func execute() error {
 		productHandler.NewHandler,
 		productService.NewService,
 
+		cartRepo.NewRepo,
+		cartService.NewService,
+		cartHandler.NewHandler,
+
+		promotionRepo.NewRepo,
+		promotionService.NewService,
+		promotionHandler.NewHandler,
+
+		paymentRepo.NewRepo,
+		paymentService.NewService,
+		paymentHandler.NewHandler,
+
+		returnRepo.NewRepo,
+		returnService.NewService,
+		returnHandler.NewHandler,
+
+		pickupRepo.NewRepo,
+		pickupService.NewService,
+		pickupHandler.NewHandler,
+
+		deliveryRepo.NewRepo,
+		deliveryService.NewService,
+		deliveryHandler.NewHandler,
+
+		receiptService.NewService,
+		receiptHandler.NewHandler,
+
+		idempotencyRepo.NewRepo,
+		idempotencyService.NewService,
+
 		func(db *pgxpool.Pool) uowRepo.UnitOfWork {
 			return uowRepo.New(db)
 		},
 
+		health.New,
+		metrics.New,
+		healthHandler.NewHandler,
+
 		rest.NewRESTServer,
 		rest.NewHTTPServer,
+		worker.NewOrderExpiry,
 	}
 
 	container := dig.New()
@@ -100,7 +196,27 @@ func execute() error {
 		}
 	}
 
-	err := container.Invoke(
+	// The tracer provider is created first so that it is stopped last and
+	// still exports the spans of requests finished during shutdown.
+	err := container.Invoke(func(*tracing.Provider) {})
+	if err != nil {
+		return fmt.Errorf("failed to initialize tracing: %w", err)
+	}
+
+	err = container.Invoke(
+		func(migrator *db.Migrator, log *zap.SugaredLogger) error {
+			applied, err := migrator.Up(context.Background())
+			if err != nil {
+				return err
+			}
+			log.Infow("database migrated", "applied", applied, "version", migrator.LatestVersion())
+			return nil
+		})
+	if err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	err = container.Invoke(
 		func(server *rest.Server) {
 			server.SetupRoutes()
 		})
@@ -109,7 +225,37 @@ func execute() error {
 	}
 
 	return container.Invoke(
-		func(server *http.Server) error {
-			return fmt.Errorf("failed to start server: %w", server.ListenAndServe())
+		func(_ *http.Server, _ *worker.OrderExpiry, cfg *config.Config, lc *lifecycle.Lifecycle, log *zap.SugaredLogger) error {
+			return run(lc, log, cfg.HTTP.ShutdownTimeout)
 		})
 }
+
+// run starts the registered components, waits for a termination signal or a
+// fatal component error and then stops everything within shutdownTimeout.
+func run(lc *lifecycle.Lifecycle, log *zap.SugaredLogger, shutdownTimeout time.Duration) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := lc.Start(ctx); err != nil {
+		return fmt.Errorf("failed to start server: %w", err)
+	}
+	log.Infow("server started")
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		log.Infow("shutdown signal received")
+	case runErr = <-lc.Err():
+		log.Errorw("component failed, shutting down", "error", runErr)
+	}
+
+	stopCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	log.Infow("shutting down", "timeout", shutdownTimeout)
+	if err := lc.Stop(stopCtx); err != nil {
+		return errors.Join(runErr, fmt.Errorf("failed to stop server: %w", err))
+	}
+	log.Infow("server stopped")
+	return runErr
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Cora23tt/order_service/pkg/config"
+	"github.com/Cora23tt/order_service/pkg/db"
+	"github.com/Cora23tt/order_service/pkg/lifecycle"
+	"github.com/Cora23tt/order_service/pkg/logger"
+)
+
+var errMigrateUsage = errors.New("usage: server migrate up | down N | status")
+
+// migrate implements the "migrate" subcommand of the server binary.
+func migrate(args []string) error {
+	if len(args) == 0 {
+		return errMigrateUsage
+	}
+
+	cfg, err := config.LoadDB()
+	if err != nil {
+		return err
+	}
+
+	log, err := logger.New(cfg)
+	if err != nil {
+		return err
+	}
+	defer log.Sync()
+
+	lc := lifecycle.New(log)
+	pool, err := db.NewDB(cfg, lc)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	if err := lc.Start(ctx); err != nil {
+		return err
+	}
+	defer lc.Stop(ctx)
+
+	migrator, err := db.NewMigrator(pool, log)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("applied %d migration(s)\n", applied)
+	case "down":
+		if len(args) != 2 {
+			return errMigrateUsage
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid number of migrations %q", args[1])
+		}
+		rolledBack, err := migrator.Down(ctx, n)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("rolled back %d migration(s)\n", rolledBack)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if errors.Is(err, db.ErrNotInitialised) {
+			fmt.Println("not initialised: run \"migrate up\" to create the schema")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range statuses {
+			appliedAt := "pending"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format(time.DateTime)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+		return w.Flush()
+	default:
+		return errMigrateUsage
+	}
+	return nil
+}
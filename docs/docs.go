@@ -22,55 +22,78 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/v1/admin/users": {
+        "/api/v1/admin/pickup-points": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Админский доступ. Возвращает список всех зарегистрированных пользователей",
+                "description": "Возвращает все пункты выдачи, включая неактивные. Сортировка по lat и lon — как в публичном списке",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "User"
+                    "pickup-points"
+                ],
+                "summary": "Все пункты выдачи (admin)",
+                "parameters": [
+                    {
+                        "type": "number",
+                        "description": "Широта",
+                        "name": "lat",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Долгота",
+                        "name": "lon",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Получение списка всех пользователей (admin)",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/user.Profile"
+                                "$ref": "#/definitions/pickup.Point"
                             }
                         }
                     },
+                    "400": {
+                        "description": "validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
                     "403": {
                         "description": "forbidden",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     },
                     "500": {
-                        "description": "internal error",
+                        "description": "internal_error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     }
                 }
-            }
-        },
-        "/api/v1/auth/signin": {
+            },
             "post": {
-                "description": "Аутентифицирует пользователя и выдает JWT токен",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Создаёт пункт выдачи. Часы работы задаются интервалами по дням недели (1 — понедельник) в часовом поясе PICKUP_TIMEZONE",
                 "consumes": [
                     "application/json"
                 ],
@@ -78,48 +101,68 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Auth"
+                    "pickup-points"
                 ],
-                "summary": "Вход пользователя",
+                "summary": "Создание пункта выдачи (admin)",
                 "parameters": [
                     {
-                        "description": "Телефон и пароль",
-                        "name": "credentials",
+                        "description": "Пункт выдачи",
+                        "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/auth.Credentials"
+                            "$ref": "#/definitions/pickup.PointRequest"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности: повтор запроса с тем же ключом вернёт сохранённый ответ",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "JWT токен",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/pickup.Point"
                         }
                     },
                     "400": {
-                        "description": "Invalid request body",
+                        "description": "validation_failed",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     },
                     "500": {
-                        "description": "Error initialising session token",
+                        "description": "internal_error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     }
                 }
             }
         },
-        "/api/v1/auth/signup": {
-            "post": {
-                "description": "Регистрирует нового пользователя по номеру телефона и паролю",
+        "/api/v1/admin/pickup-points/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Полностью заменяет параметры пункта выдачи. Уже оформленные заказы сохраняют название и адрес, с которыми были оформлены",
                 "consumes": [
                     "application/json"
                 ],
@@ -127,224 +170,208 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Auth"
+                    "pickup-points"
                 ],
-                "summary": "Регистрация нового пользователя",
+                "summary": "Изменение пункта выдачи (admin)",
                 "parameters": [
                     {
-                        "description": "Данные пользователя",
-                        "name": "credentials",
+                        "type": "integer",
+                        "description": "ID пункта выдачи",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Пункт выдачи",
+                        "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/auth.Credentials"
+                            "$ref": "#/definitions/pickup.PointRequest"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности: повтор запроса с тем же ключом вернёт сохранённый ответ",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "ID нового пользователя",
+                        "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "integer"
-                            }
+                            "$ref": "#/definitions/pickup.Point"
                         }
                     },
                     "400": {
-                        "description": "Invalid request body",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "409": {
-                        "description": "A user with this phone number already exists",
+                        "description": "validation_failed",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     },
-                    "500": {
-                        "description": "Internal server error",
-                        "schema": {
-                            "type": "string"
-                        }
-                    }
-                }
-            }
-        },
-        "/api/v1/me": {
-            "get": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Возвращает информацию о текущем пользователе по JWT-токену",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "User"
-                ],
-                "summary": "Получение профиля текущего пользователя",
-                "responses": {
-                    "200": {
-                        "description": "Профиль пользователя",
+                    "401": {
+                        "description": "unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/user.Profile"
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     },
-                    "401": {
-                        "description": "unauthorized",
+                    "403": {
+                        "description": "forbidden",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     },
                     "404": {
-                        "description": "user not found",
+                        "description": "pickup_point_not_found",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     },
                     "500": {
-                        "description": "internal error",
+                        "description": "internal_error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     }
                 }
             },
-            "patch": {
+            "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Обновляет PINFL и аватар текущего пользователя",
-                "consumes": [
-                    "multipart/form-data"
-                ],
-                "produces": [
-                    "application/json"
-                ],
+                "description": "Удаляет пункт выдачи вместе с его слотами доставки. Пункт со слотами, на которые оформлены заказы, удалить нельзя. Заказы сохраняют название и адрес пункта; чтобы временно не принимать заказы, пункт лучше сделать неактивным",
                 "tags": [
-                    "User"
+                    "pickup-points"
                 ],
-                "summary": "Обновление профиля пользователя",
+                "summary": "Удаление пункта выдачи (admin)",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "ПИНФЛ пользователя",
-                        "name": "pinfl",
-                        "in": "formData"
-                    },
-                    {
-                        "type": "file",
-                        "description": "Аватар (изображение)",
-                        "name": "avatar",
-                        "in": "formData"
+                        "type": "integer",
+                        "description": "ID пункта выдачи",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "profile updated",
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "validation_failed",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     },
                     "401": {
                         "description": "unauthorized",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     },
                     "404": {
-                        "description": "user not found",
+                        "description": "pickup_point_not_found",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "delivery_slot_booked",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     },
                     "500": {
-                        "description": "internal error",
+                        "description": "internal_error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     }
                 }
             }
         },
-        "/api/v1/orders": {
+        "/api/v1/admin/pickup-points/{id}/slots": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Возвращает список заказов",
+                "description": "Возвращает все ещё не закончившиеся слоты доставки пункта выдачи, включая занятые, с числом забронированных мест",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "orders"
+                    "delivery-slots"
+                ],
+                "summary": "Слоты доставки пункта выдачи (admin)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID пункта выдачи",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Get all user orders (user/admin)",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/order.Order"
+                                "$ref": "#/definitions/delivery.Slot"
                             }
                         }
                     },
+                    "400": {
+                        "description": "validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
                     "401": {
-                        "description": "Unauthorized",
+                        "description": "unauthorized",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "pickup_point_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "internal_error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     }
                 }
-            }
-        },
-        "/api/v1/orders/": {
+            },
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Create a new order (user/admin)",
+                "description": "Создаёт слот доставки в пункт выдачи. У пункта не может быть двух слотов с одним временем начала",
                 "consumes": [
                     "application/json"
                 ],
@@ -352,334 +379,403 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "orders"
+                    "delivery-slots"
                 ],
-                "summary": "Create order",
+                "summary": "Создание слота доставки (admin)",
                 "parameters": [
                     {
-                        "description": "Order info",
+                        "type": "integer",
+                        "description": "ID пункта выдачи",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Слот доставки",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/order.CreateOrderRequest"
+                            "$ref": "#/definitions/delivery.SlotRequest"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности: повтор запроса с тем же ключом вернёт сохранённый ответ",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "order_id",
+                        "description": "Created",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "integer"
-                            }
+                            "$ref": "#/definitions/delivery.Slot"
                         }
                     },
                     "400": {
-                        "description": "invalid input",
+                        "description": "validation_failed",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     },
                     "401": {
                         "description": "unauthorized",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "pickup_point_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     },
                     "409": {
-                        "description": "insufficient stock",
+                        "description": "delivery_slot_exists",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     },
                     "500": {
-                        "description": "internal error",
+                        "description": "internal_error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     }
                 }
             }
         },
-        "/api/v1/orders/export": {
-            "get": {
+        "/api/v1/admin/pickup-points/{id}/slots/{slot_id}": {
+            "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Экспорт заказов в JSON по фильтрам",
+                "description": "Полностью заменяет параметры слота. Вместимость нельзя сделать меньше числа забронированных мест, а время слота с бронированиями — изменить",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "orders"
+                    "delivery-slots"
                 ],
-                "summary": "Export orders (admin)",
+                "summary": "Изменение слота доставки (admin)",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Filter by user ID",
-                        "name": "user_id",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Filter by status",
-                        "name": "status",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Filter by min amount",
-                        "name": "min_amount",
-                        "in": "query"
+                        "description": "ID пункта выдачи",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     },
                     {
                         "type": "integer",
-                        "description": "Filter by max amount",
-                        "name": "max_amount",
-                        "in": "query"
+                        "description": "ID слота",
+                        "name": "slot_id",
+                        "in": "path",
+                        "required": true
                     },
                     {
-                        "type": "integer",
-                        "description": "Limit",
-                        "name": "limit",
-                        "in": "query"
+                        "description": "Слот доставки",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/delivery.SlotRequest"
+                        }
                     },
                     {
-                        "type": "integer",
-                        "description": "Offset",
-                        "name": "offset",
-                        "in": "query"
+                        "type": "string",
+                        "description": "Ключ идемпотентности: повтор запроса с тем же ключом вернёт сохранённый ответ",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/delivery.Slot"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "validation_failed",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "delivery_slot_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "delivery_slot_exists, delivery_slot_booked",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "internal_error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     }
                 }
-            }
-        },
-        "/api/v1/orders/export/csv": {
-            "get": {
+            },
+            "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Экспорт заказов в CSV по фильтрам",
+                "description": "Удаляет слот доставки, на который ещё не оформлено ни одного заказа",
                 "tags": [
-                    "orders"
+                    "delivery-slots"
                 ],
-                "summary": "Export orders as CSV (admin)",
+                "summary": "Удаление слота доставки (admin)",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Filter by user ID",
-                        "name": "user_id",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Filter by status",
-                        "name": "status",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Filter by min amount",
-                        "name": "min_amount",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Filter by max amount",
-                        "name": "max_amount",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Limit",
-                        "name": "limit",
-                        "in": "query"
+                        "description": "ID пункта выдачи",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     },
                     {
                         "type": "integer",
-                        "description": "Offset",
-                        "name": "offset",
-                        "in": "query"
+                        "description": "ID слота",
+                        "name": "slot_id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "CSV file",
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "validation_failed",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "unauthorized",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "delivery_slot_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "delivery_slot_booked",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "internal_error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     }
                 }
             }
         },
-        "/api/v1/orders/stats": {
+        "/api/v1/admin/promotions/": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Получает статистику заказов по статусам за период",
-                "tags": [
-                    "orders"
+                "description": "Возвращает все промокоды с числом использований",
+                "produces": [
+                    "application/json"
                 ],
-                "summary": "Get order stats (admin)",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Start date (YYYY-MM-DD)",
-                        "name": "from",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "End date (YYYY-MM-DD)",
-                        "name": "to",
-                        "in": "query"
-                    }
+                "tags": [
+                    "promotions"
                 ],
+                "summary": "Список акций (admin)",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/promotion.Promotion"
+                            }
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "unauthorized",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "internal_error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     }
                 }
-            }
-        },
-        "/api/v1/orders/{id}": {
-            "get": {
+            },
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Возвращает заказ по ID",
+                "description": "Создаёт промокод с процентной или фиксированной скидкой. Код не зависит от регистра",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "orders"
+                    "promotions"
                 ],
-                "summary": "Get order by ID (user/admin)",
+                "summary": "Создание акции (admin)",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Order ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Акция",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/promotion.PromotionRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности: повтор запроса с тем же ключом вернёт сохранённый ответ",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/promotion.Promotion"
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "product_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "promotion_exists",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/promotions/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает промокод по ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "promotions"
+                ],
+                "summary": "Акция по ID (admin)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID акции",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/order.Order"
+                            "$ref": "#/definitions/promotion.Promotion"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "validation_failed",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     },
                     "404": {
-                        "description": "Not Found",
+                        "description": "promotion_not_found",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "internal_error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     }
                 }
@@ -690,64 +786,70 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Обновляет статус заказа по ID",
+                "description": "Полностью заменяет параметры промокода. Уже оформленные заказы сохраняют свою скидку",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "orders"
+                    "promotions"
                 ],
-                "summary": "Update order status (admin)",
+                "summary": "Изменение акции (admin)",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Order ID",
+                        "description": "ID акции",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "New status",
-                        "name": "input",
+                        "description": "Акция",
+                        "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/order.UpdateStatusRequest"
+                            "$ref": "#/definitions/promotion.PromotionRequest"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности: повтор запроса с тем же ключом вернёт сохранённый ответ",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/promotion.Promotion"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "validation_failed",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     },
                     "404": {
-                        "description": "Not Found",
+                        "description": "promotion_not_found, product_not_found",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "promotion_exists",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "internal_error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     }
                 }
@@ -758,15 +860,15 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Удаляет заказ по ID",
+                "description": "Удаляет промокод. Скидки в уже оформленных заказах сохраняются",
                 "tags": [
-                    "orders"
+                    "promotions"
                 ],
-                "summary": "Delete order by ID (admin)",
+                "summary": "Удаление акции (admin)",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Order ID",
+                        "description": "ID акции",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -777,586 +879,3930 @@ const docTemplate = `{
                         "description": "No Content"
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "validation_failed",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     },
                     "404": {
-                        "description": "Not Found",
+                        "description": "promotion_not_found",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "internal_error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     }
                 }
             }
         },
-        "/api/v1/orders/{id}/cancel": {
-            "get": {
+        "/api/v1/admin/returns/{id}": {
+            "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Отмена заказа пользователем",
+                "description": "Переводит заявку на следующий шаг: requested → approved или rejected, approved → received или rejected, received → refunded или replaced. Для отказа причина обязательна. refunded возвращает деньги за позиции заявки через платёжную систему и возвращает товары на склад, replaced создаёт бесплатный заказ на замену в статусе paid",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "orders"
+                    "returns"
                 ],
-                "summary": "Cancel order (user/admin)",
+                "summary": "Обработка заявки на возврат (admin)",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Order ID",
+                        "description": "ID заявки",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Новый статус",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/returns.UpdateReturnStatusRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности: повтор запроса с тем же ключом вернёт сохранённый ответ",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/returns.Return"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "validation_failed",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     },
                     "403": {
-                        "description": "Forbidden",
+                        "description": "forbidden",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     },
                     "404": {
-                        "description": "Not Found",
+                        "description": "return_not_found",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "invalid_status_transition, order_not_refundable, insufficient_stock",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "refund_quantity_exceeded, refund_exceeds_paid, return_quantity_exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "internal_error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "502": {
+                        "description": "payment_provider_error, refund_declined",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     }
                 }
             }
         },
-        "/api/v1/products": {
+        "/api/v1/admin/users": {
             "get": {
-                "description": "Возвращает список всех доступных продуктов",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Админский доступ. Возвращает список всех зарегистрированных пользователей",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "products"
+                    "User"
                 ],
-                "summary": "Get all products (admin/user)",
+                "summary": "Получение списка всех пользователей (admin)",
                 "responses": {
                     "200": {
-                        "description": "products: []Product",
+                        "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/user.Profile"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "internal_error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     }
                 }
-            },
+            }
+        },
+        "/api/v1/auth/logout": {
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Добавляет новый продукт в каталог",
+                "description": "Отзывает сессию, к которой относится токен запроса",
                 "tags": [
-                    "products"
-                ],
-                "summary": "Add new product (admin)",
-                "parameters": [
-                    {
-                        "description": "Product object",
-                        "name": "product",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/internal_rest_handlers_product.Product"
-                        }
-                    }
+                    "Auth"
                 ],
+                "summary": "Выход из текущей сессии",
                 "responses": {
-                    "201": {
-                        "description": "Created",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
+                    "204": {
+                        "description": "No Content"
                     },
-                    "409": {
-                        "description": "Conflict",
+                    "401": {
+                        "description": "unauthorized",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "internal_error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     }
                 }
             }
         },
-        "/api/v1/products/{id}": {
-            "get": {
-                "description": "Возвращает продукт по его ID",
-                "tags": [
-                    "products"
-                ],
-                "summary": "Get product by ID",
+        "/api/v1/auth/logout-all": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Отзывает все сессии текущего пользователя",
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Выход со всех устройств",
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/auth/refresh": {
+            "post": {
+                "description": "Обменивает refresh-токен на новую пару токенов. Старый refresh-токен становится недействительным, а его повторное использование завершает всю сессию",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Обновление токенов",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Product ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Refresh-токен",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/auth.RefreshRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "product: Product",
+                        "description": "Новая пара токенов",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/auth.TokenResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "validation_failed",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "unauthorized",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "internal_error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     }
                 }
-            },
-            "put": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
+            }
+        },
+        "/api/v1/auth/signin": {
+            "post": {
+                "description": "Аутентифицирует пользователя и выдает короткоживущий JWT и refresh-токен",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
                 ],
-                "description": "Обновляет информацию о продукте по ID",
                 "tags": [
-                    "products"
+                    "Auth"
                 ],
-                "summary": "Update product by ID (admin)",
+                "summary": "Вход пользователя",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Product ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Updated product",
-                        "name": "product",
+                        "description": "Телефон и пароль",
+                        "name": "credentials",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/internal_rest_handlers_product.Product"
+                            "$ref": "#/definitions/auth.Credentials"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "JWT и refresh-токен",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/auth.TokenResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "validation_failed",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "invalid_credentials",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/auth/signup": {
+            "post": {
+                "description": "Регистрирует нового пользователя по номеру телефона и паролю",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Регистрация нового пользователя",
+                "parameters": [
+                    {
+                        "description": "Данные пользователя",
+                        "name": "credentials",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/auth.Credentials"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "ID нового пользователя",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
-                                "type": "string"
+                                "type": "integer"
                             }
                         }
                     },
+                    "400": {
+                        "description": "validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
                     "409": {
-                        "description": "Conflict",
+                        "description": "user_exists",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "internal_error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/api/v1/cart/checkout": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Удаляет продукт по ID",
+                "description": "Создаёт заказ из содержимого корзины по актуальным ценам и очищает корзину. Заказ и очистка корзины выполняются в одной транзакции",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "products"
+                    "cart"
                 ],
-                "summary": "Delete product by ID (admin)",
+                "summary": "Оформление заказа из корзины",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Product ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Пункт выдачи, слот доставки и промокод",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/cart.CheckoutRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности: повтор запроса с тем же ключом вернёт сохранённый ответ",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
-                    },
-                    "400": {
-                        "description": "Bad Request",
+                    "201": {
+                        "description": "order_id",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
-                                "type": "string"
+                                "type": "integer"
                             }
                         }
                     },
+                    "400": {
+                        "description": "validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
                     "404": {
-                        "description": "Not Found",
+                        "description": "pickup_point_not_found, delivery_slot_not_found",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "cart_empty, cart_changed, insufficient_stock, promo_code_exhausted, pickup_point_inactive, pickup_point_closed, pickup_point_full, delivery_slot_full",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "promo_code_invalid, promo_min_amount, promo_not_applicable, delivery_slot_past, delivery_slot_beyond_horizon, delivery_slot_mismatch",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "internal_error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
                         }
                     }
                 }
             }
         },
-        "/profile/{id}/photo": {
+        "/api/v1/cart/items": {
             "get": {
-                "description": "Возвращает файл изображения профиля по ID пользователя",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает корзину текущего пользователя с актуальными ценами, предупреждениями об остатках и итоговой суммой",
                 "produces": [
-                    "image/jpeg",
-                    "image/png",
-                    "image/webp"
+                    "application/json"
                 ],
                 "tags": [
-                    "User"
-                ],
-                "summary": "Получение аватара пользователя",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "ID пользователя",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "cart"
                 ],
+                "summary": "Корзина",
                 "responses": {
                     "200": {
-                        "description": "Изображение аватара",
+                        "description": "OK",
                         "schema": {
-                            "type": "file"
+                            "$ref": "#/definitions/cart.Cart"
                         }
                     },
-                    "404": {
-                        "description": "photo not found",
+                    "401": {
+                        "description": "unauthorized",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Добавляет товар в корзину или заменяет его количество. Наличие на складе проверяется при оформлении заказа",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cart"
+                ],
+                "summary": "Добавление товара в корзину",
+                "parameters": [
+                    {
+                        "description": "Товар и количество",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/cart.SetItemRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности: повтор запроса с тем же ключом вернёт сохранённый ответ",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/cart.Cart"
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "product_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Удаляет все товары из корзины текущего пользователя",
+                "tags": [
+                    "cart"
+                ],
+                "summary": "Очистка корзины",
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/cart/items/{product_id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Удаляет товар из корзины текущего пользователя",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cart"
+                ],
+                "summary": "Удаление товара из корзины",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID товара",
+                        "name": "product_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/cart.Cart"
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "cart_item_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/me": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает информацию о текущем пользователе по JWT-токену",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Получение профиля текущего пользователя",
+                "responses": {
+                    "200": {
+                        "description": "Профиль пользователя",
+                        "schema": {
+                            "$ref": "#/definitions/user.Profile"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "user_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Обновляет PINFL, аватар и язык сообщений текущего пользователя",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Обновление профиля пользователя",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ПИНФЛ пользователя",
+                        "name": "pinfl",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "file",
+                        "description": "Аватар (изображение)",
+                        "name": "avatar",
+                        "in": "formData"
+                    },
+                    {
+                        "enum": [
+                            "ru",
+                            "uz",
+                            "en"
+                        ],
+                        "type": "string",
+                        "description": "Язык сообщений API",
+                        "name": "language",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "profile updated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "user_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "file_too_large",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/me/sessions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает список активных сессий текущего пользователя",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Активные сессии",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/auth.ActiveSession"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/me/sessions/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Отзывает одну из сессий текущего пользователя",
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Завершение сессии",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID сессии",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "session_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/orders": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает список заказов",
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Get all user orders (user/admin)",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/order.Order"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/orders/": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a new order (user/admin)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Create order",
+                "parameters": [
+                    {
+                        "description": "Order info",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/order.CreateOrderRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности: повтор запроса с тем же ключом вернёт сохранённый ответ",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "order_id",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "integer"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "pickup_point_not_found, delivery_slot_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "insufficient_stock, price_changed, promo_code_exhausted, pickup_point_inactive, pickup_point_closed, pickup_point_full, delivery_slot_full",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "promo_code_invalid, promo_min_amount, promo_not_applicable, delivery_slot_past, delivery_slot_beyond_horizon, delivery_slot_mismatch",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/orders/export": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Экспорт заказов в JSON по фильтрам",
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Export orders (admin)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Filter by user ID",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Filter by min amount",
+                        "name": "min_amount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Filter by max amount",
+                        "name": "max_amount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Limit",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/orders/export/csv": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Экспорт заказов в CSV по фильтрам",
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Export orders as CSV (admin)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Filter by user ID",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Filter by min amount",
+                        "name": "min_amount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Filter by max amount",
+                        "name": "max_amount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Limit",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "CSV file",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/orders/stats": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Получает статистику заказов по статусам за период",
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Get order stats (admin)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Start date (YYYY-MM-DD)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End date (YYYY-MM-DD)",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/orders/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает заказ по ID",
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Get order by ID (user/admin)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/order.Order"
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "order_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Обновляет статус заказа по ID",
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Update order status (admin)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New status",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/order.UpdateStatusRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности: повтор запроса с тем же ключом вернёт сохранённый ответ",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "order_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "invalid_status_transition, order_completed, refund_status_not_allowed, cancel_requires_refund",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Удаляет заказ по ID",
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Delete order by ID (admin)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "order_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/orders/{id}/cancel": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Отмена заказа пользователем",
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Cancel order (user/admin)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cancellation reason",
+                        "name": "reason",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "order_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "cancel_not_allowed",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/orders/{id}/history": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает историю изменения статусов заказа",
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Get order status history (user/admin)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/order.StatusChange"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "order_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/orders/{id}/payment": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Выставляет счёт на оплату заказа в платёжной системе и возвращает ссылку на оплату. Повторный запрос возвращает уже выставленный неоплаченный счёт",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payments"
+                ],
+                "summary": "Оплата заказа",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID заказа",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности: повтор запроса с тем же ключом вернёт сохранённый ответ",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/payment.Payment"
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "order_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "order_not_payable",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "502": {
+                        "description": "payment_provider_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/orders/{id}/payments": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает все счета заказа с их статусами",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payments"
+                ],
+                "summary": "Платежи заказа",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID заказа",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/payment.Payment"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "order_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/orders/{id}/receipt": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает чек оплаченного заказа: PDF или, если PDF не удалось сформировать, HTML. В чеке перечислены позиции, цены, НДС, пункт выдачи и QR-код с номером заказа. Пользователь может скачать только чеки своих заказов",
+                "produces": [
+                    "application/pdf",
+                    "text/html"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Чек заказа",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID заказа",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Чек",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "order_not_found, receipt_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/orders/{id}/refunds": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает деньги за весь заказ или за выбранные количества позиций через платёжную систему и возвращает товары на склад. Заказ переходит в статус refunded, если возвращены все позиции, иначе в partially_refunded. Сумма возвратов не может превысить оплаченную",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payments"
+                ],
+                "summary": "Возврат средств (admin)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID заказа",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Возврат",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/payment.RefundRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности: повтор запроса с тем же ключом вернёт сохранённый ответ",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/order.Refund"
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "order_not_found, order_item_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "order_not_refundable",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "refund_quantity_exceeded, refund_exceeds_paid",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "502": {
+                        "description": "payment_provider_error, refund_declined",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/orders/{id}/transitions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает текущий статус заказа и статусы, в которые его можно перевести",
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Get allowed next statuses (admin)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/order.NextStatusesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "order_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/payments/webhook/{provider}": {
+            "post": {
+                "description": "Принимает подписанное уведомление о статусе платежа. Подтверждённая оплата переводит заказ в статус paid; повторные уведомления не меняют состояние",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "payments"
+                ],
+                "summary": "Уведомление платёжной системы",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "mock",
+                        "description": "Платёжная система",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "HMAC-SHA256 тела запроса (mock)",
+                        "name": "X-Mock-Signature",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "invalid_callback",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "invalid_signature",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "payment_provider_not_found, payment_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "payment_amount_mismatch",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/pickup-points": {
+            "get": {
+                "description": "Возвращает действующие пункты выдачи. Если переданы lat и lon, пункты отсортированы по расстоянию от этой точки, ближайшие первыми, и содержат distance_km",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pickup-points"
+                ],
+                "summary": "Пункты выдачи",
+                "parameters": [
+                    {
+                        "type": "number",
+                        "description": "Широта",
+                        "name": "lat",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Долгота",
+                        "name": "lon",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/pickup.Point"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/pickup-points/{id}": {
+            "get": {
+                "description": "Возвращает пункт выдачи по ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pickup-points"
+                ],
+                "summary": "Пункт выдачи",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID пункта выдачи",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/pickup.Point"
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "pickup_point_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/pickup-points/{id}/slots": {
+            "get": {
+                "description": "Возвращает слоты доставки пункта выдачи, которые можно забронировать при оформлении заказа: ещё не начались, не дальше DELIVERY_BOOKING_HORIZON, со свободными местами и в часы работы пункта. Время — в часовом поясе PICKUP_TIMEZONE",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "delivery-slots"
+                ],
+                "summary": "Свободные слоты доставки",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID пункта выдачи",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/delivery.Slot"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "pickup_point_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/products": {
+            "get": {
+                "description": "Возвращает список всех доступных продуктов",
+                "tags": [
+                    "products"
+                ],
+                "summary": "Get all products (admin/user)",
+                "responses": {
+                    "200": {
+                        "description": "products: []Product",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Добавляет новый продукт в каталог",
+                "tags": [
+                    "products"
+                ],
+                "summary": "Add new product (admin)",
+                "parameters": [
+                    {
+                        "description": "Product object",
+                        "name": "product",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_rest_handlers_product.Product"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности: повтор запроса с тем же ключом вернёт сохранённый ответ",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "product_exists",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/products/{id}": {
+            "get": {
+                "description": "Возвращает продукт по его ID",
+                "tags": [
+                    "products"
+                ],
+                "summary": "Get product by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "product: Product",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "product_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Обновляет информацию о продукте по ID",
+                "tags": [
+                    "products"
+                ],
+                "summary": "Update product by ID (admin)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated product",
+                        "name": "product",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_rest_handlers_product.Product"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности: повтор запроса с тем же ключом вернёт сохранённый ответ",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "product_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "product_exists",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Удаляет продукт по ID",
+                "tags": [
+                    "products"
+                ],
+                "summary": "Delete product by ID (admin)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "product_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/returns": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает заявки на возврат, новые первыми. Пользователь видит только свои заявки, администратор — все",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "returns"
+                ],
+                "summary": "Заявки на возврат",
+                "parameters": [
+                    {
+                        "enum": [
+                            "requested",
+                            "approved",
+                            "rejected",
+                            "received",
+                            "refunded",
+                            "replaced"
+                        ],
+                        "type": "string",
+                        "description": "Статус",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID заказа",
+                        "name": "order_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/returns.Return"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Открывает заявку на возврат позиций доставленного заказа. Заявку можно подать в течение RETURN_WINDOW после доставки; фотографии прикладываются отдельным запросом",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "returns"
+                ],
+                "summary": "Заявка на возврат",
+                "parameters": [
+                    {
+                        "description": "Заявка",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/returns.CreateReturnRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности: повтор запроса с тем же ключом вернёт сохранённый ответ",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/returns.Return"
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "order_not_found, order_item_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "return_not_allowed, return_window_expired",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "return_quantity_exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/returns/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает заявку с позициями, фотографиями и историей статусов",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "returns"
+                ],
+                "summary": "Заявка на возврат",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID заявки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/returns.Return"
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "return_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/returns/{id}/photos": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Прикладывает фотографию товара к своей заявке, пока она ожидает решения. Количество фотографий ограничено RETURN_MAX_PHOTOS",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "returns"
+                ],
+                "summary": "Фотография к заявке на возврат",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID заявки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "Фотография (jpg, png, webp)",
+                        "name": "photo",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности: повтор запроса с тем же ключом вернёт сохранённый ответ",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/returns.Photo"
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "return_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "return_not_editable",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "file_too_large",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "return_photo_limit",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/returns/{id}/photos/{photo_id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает файл фотографии. Пользователь может скачать только фотографии своих заявок",
+                "produces": [
+                    "image/jpeg",
+                    "image/png",
+                    "image/webp"
+                ],
+                "tags": [
+                    "returns"
+                ],
+                "summary": "Фотография заявки на возврат",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID заявки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID фотографии",
+                        "name": "photo_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Фотография",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "return_not_found, photo_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "Отвечает 200, пока процесс жив. Зависимости не проверяются.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Liveness probe",
+                "responses": {
+                    "200": {
+                        "description": "status: up",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/profile/{id}/photo": {
+            "get": {
+                "description": "Возвращает файл изображения профиля по ID пользователя",
+                "produces": [
+                    "image/jpeg",
+                    "image/png",
+                    "image/webp"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Получение аватара пользователя",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Изображение аватара",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "photo_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/errors.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Проверяет подключение к базе, версию миграций и каталог загрузок. Возвращает 503, если хотя бы одна проверка не прошла или сервер завершает работу.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Readiness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "auth.ActiveSession": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "current": {
+                    "type": "boolean"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "5f0c7d2e-8a4b-4c1e-9d57-2f0e6b1c3a90"
+                },
+                "ip_address": {
+                    "type": "string",
+                    "example": "192.168.1.10"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "user_agent": {
+                    "type": "string",
+                    "example": "okhttp/4.12.0"
+                }
+            }
+        },
+        "auth.Credentials": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "example": "strong_password_123"
+                },
+                "phone_number": {
+                    "type": "string",
+                    "example": "+998901234567"
+                }
+            }
+        },
+        "auth.RefreshRequest": {
+            "type": "object",
+            "required": [
+                "refresh_token"
+            ],
+            "properties": {
+                "refresh_token": {
+                    "type": "string",
+                    "example": "q1z8Yc2Lh0m4r7VbN3kT9wXe5sUa6dGf1JpQ2oR8yHc"
+                }
+            }
+        },
+        "auth.TokenResponse": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "type": "string"
+                },
+                "refresh_token": {
+                    "type": "string",
+                    "example": "q1z8Yc2Lh0m4r7VbN3kT9wXe5sUa6dGf1JpQ2oR8yHc"
+                },
+                "token": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."
+                }
+            }
+        },
+        "cart.Cart": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_usecase_cart.Item"
+                    }
+                },
+                "total": {
+                    "type": "integer",
+                    "example": 48000
+                }
+            }
+        },
+        "cart.CheckoutRequest": {
+            "type": "object",
+            "required": [
+                "pickup_point_id"
+            ],
+            "properties": {
+                "delivery_slot_id": {
+                    "type": "integer",
+                    "example": 7
+                },
+                "pickup_point_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "promo_code": {
+                    "type": "string",
+                    "maxLength": 64,
+                    "example": "SPRING10"
+                }
+            }
+        },
+        "cart.SetItemRequest": {
+            "type": "object",
+            "required": [
+                "product_id",
+                "quantity"
+            ],
+            "properties": {
+                "product_id": {
+                    "type": "integer",
+                    "example": 4
+                },
+                "quantity": {
+                    "type": "integer",
+                    "example": 2
+                }
+            }
+        },
+        "delivery.Slot": {
+            "type": "object",
+            "properties": {
+                "available": {
+                    "type": "integer",
+                    "example": 8
+                },
+                "booked": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "capacity": {
+                    "type": "integer",
+                    "example": 20
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "ends_at": {
+                    "type": "string",
+                    "example": "2025-06-02T12:00:00+05:00"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 7
+                },
+                "pickup_point_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "starts_at": {
+                    "type": "string",
+                    "example": "2025-06-02T10:00:00+05:00"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "delivery.SlotRequest": {
+            "type": "object",
+            "required": [
+                "capacity",
+                "ends_at",
+                "starts_at"
+            ],
+            "properties": {
+                "capacity": {
+                    "type": "integer",
+                    "example": 20
+                },
+                "ends_at": {
+                    "type": "string",
+                    "example": "2025-06-02T12:00:00+05:00"
+                },
+                "starts_at": {
+                    "type": "string",
+                    "example": "2025-06-02T10:00:00+05:00"
+                }
+            }
+        },
+        "enums.DiscountType": {
+            "type": "string",
+            "enum": [
+                "percent",
+                "fixed"
+            ],
+            "x-enum-varnames": [
+                "DiscountPercent",
+                "DiscountFixed"
+            ]
+        },
+        "enums.OrderStatus": {
+            "type": "string",
+            "enum": [
+                "pending_payment",
+                "paid",
+                "processing",
+                "shipped",
+                "delivered",
+                "cancelled",
+                "partially_refunded",
+                "refunded"
+            ],
+            "x-enum-varnames": [
+                "StatusPendingPayment",
+                "StatusPaid",
+                "StatusProcessing",
+                "StatusShipped",
+                "StatusDelivered",
+                "StatusCancelled",
+                "StatusPartiallyRefunded",
+                "StatusRefunded"
+            ]
+        },
+        "enums.PaymentStatus": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "paid",
+                "failed",
+                "refunded"
+            ],
+            "x-enum-varnames": [
+                "PaymentPending",
+                "PaymentPaid",
+                "PaymentFailed",
+                "PaymentRefunded"
+            ]
+        },
+        "enums.ReturnStatus": {
+            "type": "string",
+            "enum": [
+                "requested",
+                "approved",
+                "rejected",
+                "received",
+                "refunded",
+                "replaced"
+            ],
+            "x-enum-varnames": [
+                "ReturnRequested",
+                "ReturnApproved",
+                "ReturnRejected",
+                "ReturnReceived",
+                "ReturnRefunded",
+                "ReturnReplaced"
+            ]
+        },
+        "errors.FieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string",
+                    "example": "items"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "items is required"
+                },
+                "param": {
+                    "type": "string"
+                },
+                "rule": {
+                    "type": "string",
+                    "example": "required"
+                }
+            }
+        },
+        "errors.Problem": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "insufficient_stock"
+                },
+                "detail": {
+                    "type": "string",
+                    "example": "insufficient stock"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/errors.FieldError"
+                    }
+                },
+                "instance": {
+                    "type": "string",
+                    "example": "/api/v1/orders/"
+                },
+                "request_id": {
+                    "type": "string",
+                    "example": "5f0c7d2e-8a4b-4c1e-9d57-2f0e6b1c3a90"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 409
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Conflict"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "about:blank"
+                }
+            }
+        },
+        "health.CheckResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "latency_ms": {
+                    "type": "number",
+                    "example": 1.25
+                },
+                "status": {
+                    "type": "string",
+                    "example": "up"
+                }
+            }
+        },
+        "health.Report": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/health.CheckResult"
+                    }
+                },
+                "status": {
+                    "type": "string",
+                    "example": "up"
+                }
+            }
+        },
+        "internal_rest_handlers_product.Product": {
+            "type": "object",
+            "required": [
+                "name",
+                "price",
+                "quantity"
+            ],
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "image_url": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "quantity": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "vat_rate": {
+                    "type": "integer",
+                    "maximum": 10000,
+                    "minimum": 0,
+                    "example": 1200
+                }
+            }
+        },
+        "internal_usecase_cart.Item": {
+            "type": "object",
+            "properties": {
+                "available": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "image_url": {
+                    "type": "string",
+                    "example": "/images/tea.png"
+                },
+                "line_total": {
+                    "type": "integer",
+                    "example": 48000
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Green tea"
+                },
+                "price": {
+                    "type": "integer",
+                    "example": 12000
+                },
+                "product_id": {
+                    "type": "integer",
+                    "example": 4
+                },
+                "quantity": {
+                    "type": "integer",
+                    "example": 4
+                },
+                "stock_warning": {
+                    "type": "string",
+                    "enum": [
+                        "out_of_stock",
+                        "insufficient_stock"
+                    ],
+                    "example": "insufficient_stock"
+                }
+            }
+        },
+        "order.CreateOrderRequest": {
+            "type": "object",
+            "required": [
+                "items",
+                "pickup_point_id"
+            ],
+            "properties": {
+                "delivery_slot_id": {
+                    "type": "integer",
+                    "example": 7
+                },
+                "items": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/order.OrderItemInput"
+                    }
+                },
+                "pickup_point_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "promo_code": {
+                    "type": "string",
+                    "maxLength": 64,
+                    "example": "SPRING10"
+                }
+            }
+        },
+        "order.NextStatusesResponse": {
+            "type": "object",
+            "properties": {
+                "next_statuses": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/enums.OrderStatus"
+                    },
+                    "example": [
+                        "processing",
+                        "partially_refunded",
+                        "refunded"
+                    ]
+                },
+                "status": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/enums.OrderStatus"
+                        }
+                    ],
+                    "example": "paid"
+                }
+            }
+        },
+        "order.Order": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "delivery_date": {
+                    "type": "string"
+                },
+                "delivery_slot_id": {
+                    "type": "integer"
+                },
+                "discount_amount": {
+                    "type": "integer"
+                },
+                "discounts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/order.OrderDiscount"
+                    }
+                },
+                "history": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/order.StatusChange"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/order.OrderItem"
+                    }
+                },
+                "net_amount": {
+                    "type": "integer"
+                },
+                "order_date": {
+                    "type": "string"
+                },
+                "pickup_point": {
+                    "type": "string"
+                },
+                "pickup_point_id": {
+                    "type": "integer"
+                },
+                "receipt_url": {
+                    "type": "string"
+                },
+                "refunded_amount": {
+                    "type": "integer"
+                },
+                "refunds": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/order.Refund"
+                    }
+                },
+                "status": {
+                    "$ref": "#/definitions/enums.OrderStatus"
+                },
+                "subtotal_amount": {
+                    "type": "integer"
+                },
+                "tax_amount": {
+                    "type": "integer"
+                },
+                "total_amount": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "order.OrderDiscount": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "code": {
+                    "type": "string"
+                },
+                "promotion_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "order.OrderItem": {
+            "type": "object",
+            "properties": {
+                "discount_amount": {
+                    "type": "integer"
+                },
+                "gross_amount": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "net_amount": {
+                    "type": "integer"
+                },
+                "price": {
+                    "type": "integer"
+                },
+                "product_id": {
+                    "type": "integer"
+                },
+                "quantity": {
+                    "type": "integer"
+                },
+                "refunded_quantity": {
+                    "type": "integer"
+                },
+                "tax_amount": {
+                    "type": "integer"
+                },
+                "total_price": {
+                    "type": "integer"
+                },
+                "vat_rate": {
+                    "type": "integer",
+                    "example": 1200
+                }
+            }
+        },
+        "order.OrderItemInput": {
+            "type": "object",
+            "required": [
+                "product_id",
+                "quantity"
+            ],
+            "properties": {
+                "expected_price": {
+                    "type": "integer",
+                    "example": 12000
+                },
+                "product_id": {
+                    "type": "integer",
+                    "example": 4
+                },
+                "quantity": {
+                    "type": "integer",
+                    "example": 4
+                }
+            }
+        },
+        "order.Refund": {
+            "type": "object",
+            "properties": {
+                "actor_user_id": {
+                    "type": "integer"
+                },
+                "amount": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/order.RefundItem"
+                    }
+                },
+                "order_id": {
+                    "type": "integer"
+                },
+                "provider_ref": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "order.RefundItem": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "order_item_id": {
+                    "type": "integer"
+                },
+                "product_id": {
+                    "type": "integer"
+                },
+                "quantity": {
+                    "type": "integer"
+                }
+            }
+        },
+        "order.RefundItemInput": {
+            "type": "object",
+            "required": [
+                "item_id",
+                "quantity"
+            ],
+            "properties": {
+                "item_id": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "quantity": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "order.StatusChange": {
+            "type": "object",
+            "properties": {
+                "actor_role": {
+                    "type": "string"
+                },
+                "actor_user_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "from_status": {
+                    "$ref": "#/definitions/enums.OrderStatus"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "order_id": {
+                    "type": "integer"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "to_status": {
+                    "$ref": "#/definitions/enums.OrderStatus"
+                }
+            }
+        },
+        "order.UpdateStatusRequest": {
+            "type": "object",
+            "required": [
+                "status"
+            ],
+            "properties": {
+                "reason": {
+                    "type": "string",
+                    "example": "handed over to courier"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "processing"
+                }
+            }
+        },
+        "payment.Payment": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer",
+                    "example": 48000
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "order_id": {
+                    "type": "integer",
+                    "example": 15
+                },
+                "paid_at": {
+                    "type": "string"
+                },
+                "payment_url": {
+                    "type": "string",
+                    "example": "https://pay.example.com/mock/mock_5f1c2a7e-7c1b-4f7e-9a53-0d8c6a1e2b3f"
+                },
+                "provider": {
+                    "type": "string",
+                    "example": "mock"
+                },
+                "provider_ref": {
+                    "type": "string",
+                    "example": "mock_5f1c2a7e-7c1b-4f7e-9a53-0d8c6a1e2b3f"
+                },
+                "status": {
+                    "enum": [
+                        "pending",
+                        "paid",
+                        "failed",
+                        "refunded"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/enums.PaymentStatus"
                         }
+                    ],
+                    "example": "pending"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "payment.RefundRequest": {
+            "type": "object",
+            "required": [
+                "reason"
+            ],
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/order.RefundItemInput"
                     }
+                },
+                "reason": {
+                    "type": "string",
+                    "maxLength": 500,
+                    "example": "товар повреждён"
                 }
             }
-        }
-    },
-    "definitions": {
-        "auth.Credentials": {
+        },
+        "pickup.OpeningHours": {
             "type": "object",
+            "required": [
+                "closes",
+                "opens",
+                "weekday"
+            ],
             "properties": {
-                "password": {
+                "closes": {
                     "type": "string",
-                    "example": "strong_password_123"
+                    "example": "20:00"
                 },
-                "phone_number": {
+                "opens": {
                     "type": "string",
-                    "example": "+998901234567"
+                    "example": "09:00"
+                },
+                "weekday": {
+                    "type": "integer",
+                    "maximum": 7,
+                    "minimum": 1,
+                    "example": 1
                 }
             }
         },
-        "enums.OrderStatus": {
-            "type": "string",
-            "enum": [
-                "pending_payment",
-                "paid",
-                "processing",
-                "shipped",
-                "delivered",
-                "cancelled"
-            ],
-            "x-enum-varnames": [
-                "StatusPendingPayment",
-                "StatusPaid",
-                "StatusProcessing",
-                "StatusShipped",
-                "StatusDelivered",
-                "StatusCancelled"
-            ]
+        "pickup.Point": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "address": {
+                    "type": "string",
+                    "example": "Mirzo Ulug'bek. Buyuk Ipak Yoli st. 109. 45"
+                },
+                "capacity": {
+                    "type": "integer",
+                    "example": 200
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "distance_km": {
+                    "type": "number",
+                    "example": 1.7
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "latitude": {
+                    "type": "number",
+                    "example": 41.3262
+                },
+                "longitude": {
+                    "type": "number",
+                    "example": 69.3285
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Buyuk Ipak Yoli"
+                },
+                "open_now": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "opening_hours": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/pickup.OpeningHours"
+                    }
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
         },
-        "internal_rest_handlers_product.Product": {
+        "pickup.PointRequest": {
+            "type": "object"
+        },
+        "promotion.Promotion": {
             "type": "object",
-            "required": [
-                "name",
-                "price",
-                "quantity"
-            ],
             "properties": {
+                "active": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "code": {
+                    "type": "string",
+                    "example": "SPRING10"
+                },
+                "created_at": {
+                    "type": "string"
+                },
                 "description": {
+                    "type": "string",
+                    "example": "Весенняя скидка"
+                },
+                "discount_type": {
+                    "enum": [
+                        "percent",
+                        "fixed"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/enums.DiscountType"
+                        }
+                    ],
+                    "example": "percent"
+                },
+                "discount_value": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "ends_at": {
                     "type": "string"
                 },
-                "image_url": {
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "min_order_amount": {
+                    "type": "integer",
+                    "example": 50000
+                },
+                "per_user_limit": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "product_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "starts_at": {
                     "type": "string"
                 },
-                "name": {
+                "times_used": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "updated_at": {
                     "type": "string"
                 },
-                "price": {
+                "usage_limit": {
                     "type": "integer",
-                    "minimum": 0
+                    "example": 1000
+                }
+            }
+        },
+        "promotion.PromotionRequest": {
+            "type": "object",
+            "required": [
+                "code",
+                "discount_type",
+                "discount_value"
+            ],
+            "properties": {
+                "active": {
+                    "type": "boolean",
+                    "example": true
                 },
-                "quantity": {
+                "code": {
+                    "type": "string",
+                    "maxLength": 64,
+                    "example": "SPRING10"
+                },
+                "description": {
+                    "type": "string",
+                    "example": "Весенняя скидка"
+                },
+                "discount_type": {
+                    "type": "string",
+                    "enum": [
+                        "percent",
+                        "fixed"
+                    ],
+                    "example": "percent"
+                },
+                "discount_value": {
                     "type": "integer",
-                    "minimum": 0
+                    "example": 10
+                },
+                "ends_at": {
+                    "type": "string",
+                    "example": "2026-06-01T00:00:00+05:00"
+                },
+                "min_order_amount": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 50000
+                },
+                "per_user_limit": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "product_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    },
+                    "example": [
+                        4,
+                        7
+                    ]
+                },
+                "starts_at": {
+                    "type": "string",
+                    "example": "2026-03-01T00:00:00+05:00"
+                },
+                "usage_limit": {
+                    "type": "integer",
+                    "example": 1000
                 }
             }
         },
-        "order.CreateOrderRequest": {
+        "returns.CreateReturnRequest": {
             "type": "object",
             "required": [
                 "items",
-                "pickup_point"
+                "order_id",
+                "reason"
             ],
             "properties": {
                 "items": {
                     "type": "array",
+                    "minItems": 1,
                     "items": {
-                        "$ref": "#/definitions/order.OrderItemInput"
+                        "$ref": "#/definitions/order.RefundItemInput"
                     }
                 },
-                "pickup_point": {
+                "order_id": {
+                    "type": "integer",
+                    "example": 15
+                },
+                "reason": {
                     "type": "string",
-                    "example": "Mirzo Ulug'bek. Buyuk Ipak Yoli st. 109. 45"
+                    "maxLength": 1000,
+                    "example": "товар повреждён"
                 }
             }
         },
-        "order.Order": {
+        "returns.Item": {
+            "type": "object",
+            "properties": {
+                "order_item_id": {
+                    "type": "integer",
+                    "example": 31
+                },
+                "product_id": {
+                    "type": "integer",
+                    "example": 4
+                },
+                "quantity": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "returns.Photo": {
             "type": "object",
             "properties": {
                 "created_at": {
                     "type": "string"
                 },
-                "delivery_date": {
+                "id": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "url": {
+                    "type": "string",
+                    "example": "/api/v1/returns/1/photos/2"
+                }
+            }
+        },
+        "returns.Return": {
+            "type": "object",
+            "properties": {
+                "created_at": {
                     "type": "string"
                 },
+                "history": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/returns.StatusChange"
+                    }
+                },
                 "id": {
-                    "type": "integer"
+                    "type": "integer",
+                    "example": 1
                 },
                 "items": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/order.OrderItem"
+                        "$ref": "#/definitions/returns.Item"
                     }
                 },
-                "order_date": {
-                    "type": "string"
+                "order_id": {
+                    "type": "integer",
+                    "example": 15
                 },
-                "pickup_point": {
-                    "type": "string"
+                "photos": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/returns.Photo"
+                    }
                 },
-                "receipt_url": {
-                    "type": "string"
+                "reason": {
+                    "type": "string",
+                    "example": "товар повреждён"
                 },
-                "status": {
-                    "$ref": "#/definitions/enums.OrderStatus"
+                "refund_id": {
+                    "type": "integer",
+                    "example": 4
                 },
-                "total_amount": {
-                    "type": "integer"
+                "replacement_order_id": {
+                    "type": "integer",
+                    "example": 27
+                },
+                "status": {
+                    "enum": [
+                        "requested",
+                        "approved",
+                        "rejected",
+                        "received",
+                        "refunded",
+                        "replaced"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/enums.ReturnStatus"
+                        }
+                    ],
+                    "example": "requested"
                 },
                 "updated_at": {
                     "type": "string"
                 },
                 "user_id": {
-                    "type": "integer"
+                    "type": "integer",
+                    "example": 3
                 }
             }
         },
-        "order.OrderItem": {
+        "returns.StatusChange": {
             "type": "object",
             "properties": {
-                "price": {
-                    "type": "integer"
+                "actor_role": {
+                    "type": "string"
                 },
-                "product_id": {
+                "actor_user_id": {
                     "type": "integer"
                 },
-                "quantity": {
-                    "type": "integer"
+                "created_at": {
+                    "type": "string"
                 },
-                "total_price": {
+                "from_status": {
+                    "$ref": "#/definitions/enums.ReturnStatus"
+                },
+                "id": {
                     "type": "integer"
-                }
-            }
-        },
-        "order.OrderItemInput": {
-            "type": "object",
-            "required": [
-                "price",
-                "product_id",
-                "quantity"
-            ],
-            "properties": {
-                "price": {
-                    "type": "integer",
-                    "example": 12000
                 },
-                "product_id": {
-                    "type": "integer",
-                    "example": 4
+                "reason": {
+                    "type": "string"
                 },
-                "quantity": {
-                    "type": "integer",
-                    "example": 4
+                "return_id": {
+                    "type": "integer"
+                },
+                "to_status": {
+                    "$ref": "#/definitions/enums.ReturnStatus"
                 }
             }
         },
-        "order.UpdateStatusRequest": {
+        "returns.UpdateReturnStatusRequest": {
             "type": "object",
             "required": [
                 "status"
             ],
             "properties": {
+                "reason": {
+                    "type": "string",
+                    "maxLength": 500,
+                    "example": "товар принят на склад"
+                },
                 "status": {
                     "type": "string",
-                    "example": "processing"
+                    "enum": [
+                        "approved",
+                        "rejected",
+                        "received",
+                        "refunded",
+                        "replaced"
+                    ],
+                    "example": "approved"
                 }
             }
         },
@@ -1371,6 +4817,10 @@ const docTemplate = `{
                     "type": "integer",
                     "example": 1
                 },
+                "language": {
+                    "type": "string",
+                    "example": "uz"
+                },
                 "phone_number": {
                     "type": "string",
                     "example": "+998901234567"
@@ -13,13 +13,23 @@ import (
 )
 
 type Repo struct {
-	db *pgxpool.Pool
+	db TxExecutor
 }
 
 func NewRepo(db *pgxpool.Pool) *Repo {
 	return &Repo{db: db}
 }
 
+func NewWithTx(tx pgx.Tx) *Repo {
+	return &Repo{db: tx}
+}
+
+type TxExecutor interface {
+	Exec(context.Context, string, ...any) (pgconn.CommandTag, error)
+	Query(context.Context, string, ...any) (pgx.Rows, error)
+	QueryRow(context.Context, string, ...any) pgx.Row
+}
+
 type User struct {
 	CreatedAt      time.Time
 	PhoneNumber    string
@@ -28,6 +38,25 @@ type User struct {
 	ID             int64
 }
 
+// Session is one refresh token. All tokens issued by rotating the token of
+// a single sign-in share the same FamilyID.
+type Session struct {
+	ID        int64
+	FamilyID  string
+	UserID    int64
+	TokenHash string
+	UserAgent *string
+	IPAddress *string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	RotatedAt *time.Time
+	RevokedAt *time.Time
+
+	// SignedInAt is the creation time of the first token of the family. It
+	// is only filled by ListActiveSessions.
+	SignedInAt time.Time
+}
+
 func (r *Repo) Create(ctx context.Context, user *User) (int64, error) {
 	var userID int64 = 0
 	err := r.db.QueryRow(ctx, `
@@ -61,40 +90,30 @@ func (r *Repo) GetByPhoneNumber(ctx context.Context, phoneNumber string) (*User,
 	return &user, nil
 }
 
-func (r *Repo) GetUserID(ctx context.Context, token string) (int64, error) {
-	var userID int64
+func (r *Repo) GetUser(ctx context.Context, phoneNumber string) (User, error) {
+	var user User
 	err := r.db.QueryRow(ctx, `
-		SELECT user_id
-		FROM sessions
-		WHERE token = $1
-	`, token).Scan(&userID)
+		SELECT id, phone_number, password_hash, role
+		FROM users
+		WHERE phone_number = $1
+	`, phoneNumber).Scan(&user.ID, &user.PhoneNumber, &user.HashedPassword, &user.Role)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return 0, pkgErrors.ErrNotFound
+		if err == pgx.ErrNoRows {
+			return User{}, pkgErrors.ErrNotFound
 		}
-		return 0, err
+		return User{}, err
 	}
-	return userID, nil
-}
 
-func (r *Repo) StoreSessionToken(ctx context.Context, token string, userID int64) error {
-	_, err := r.db.Exec(ctx, `
-		INSERT INTO sessions (token, user_id)
-		VALUES ($1, $2)
-	`, token, userID)
-	if err != nil {
-		return err
-	}
-	return nil
+	return user, nil
 }
 
-func (r *Repo) GetUser(ctx context.Context, phoneNumber string) (User, error) {
+func (r *Repo) GetUserByID(ctx context.Context, userID int64) (User, error) {
 	var user User
 	err := r.db.QueryRow(ctx, `
 		SELECT id, phone_number, password_hash, role
 		FROM users
-		WHERE phone_number = $1
-	`, phoneNumber).Scan(&user.ID, &user.PhoneNumber, &user.HashedPassword, &user.Role)
+		WHERE id = $1
+	`, userID).Scan(&user.ID, &user.PhoneNumber, &user.HashedPassword, &user.Role)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return User{}, pkgErrors.ErrNotFound
@@ -104,3 +123,139 @@ func (r *Repo) GetUser(ctx context.Context, phoneNumber string) (User, error) {
 
 	return user, nil
 }
+
+func (r *Repo) CreateSession(ctx context.Context, s *Session) (int64, error) {
+	var id int64
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO sessions (family_id, user_id, token_hash, user_agent, ip_address, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`, s.FamilyID, s.UserID, s.TokenHash, s.UserAgent, s.IPAddress, s.ExpiresAt).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// GetSessionByTokenHash returns the refresh token row and locks it, so two
+// concurrent refreshes with the same token are serialized.
+func (r *Repo) GetSessionByTokenHash(ctx context.Context, tokenHash string) (*Session, error) {
+	var s Session
+	err := r.db.QueryRow(ctx, `
+		SELECT id, family_id, user_id, token_hash, user_agent, ip_address, created_at, expires_at, rotated_at, revoked_at
+		FROM sessions
+		WHERE token_hash = $1
+		FOR UPDATE
+	`, tokenHash).Scan(&s.ID, &s.FamilyID, &s.UserID, &s.TokenHash, &s.UserAgent, &s.IPAddress,
+		&s.CreatedAt, &s.ExpiresAt, &s.RotatedAt, &s.RevokedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, pkgErrors.ErrNotFound
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+// MarkSessionRotated revokes a refresh token because a newer one was issued
+// in its place.
+func (r *Repo) MarkSessionRotated(ctx context.Context, id int64) error {
+	cmd, err := r.db.Exec(ctx, `
+		UPDATE sessions SET rotated_at = NOW(), revoked_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL
+	`, id)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return pkgErrors.ErrNotFound
+	}
+	return nil
+}
+
+func (r *Repo) RevokeSessionFamily(ctx context.Context, userID int64, familyID string) (int64, error) {
+	cmd, err := r.db.Exec(ctx, `
+		UPDATE sessions SET revoked_at = NOW()
+		WHERE user_id = $1 AND family_id = $2 AND revoked_at IS NULL
+	`, userID, familyID)
+	if err != nil {
+		return 0, err
+	}
+	return cmd.RowsAffected(), nil
+}
+
+func (r *Repo) RevokeAllSessions(ctx context.Context, userID int64) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE sessions SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`, userID)
+	return err
+}
+
+// DeleteDeadSessions removes the refresh tokens of the user that can never be
+// exchanged again: expired ones and all tokens of sessions that have no active
+// token left. Rotated tokens of active sessions are kept so that their reuse
+// is still detected.
+func (r *Repo) DeleteDeadSessions(ctx context.Context, userID int64) (int64, error) {
+	cmd, err := r.db.Exec(ctx, `
+		DELETE FROM sessions s
+		WHERE s.user_id = $1
+		  AND (s.expires_at <= NOW() OR NOT EXISTS (
+		      SELECT 1 FROM sessions a
+		      WHERE a.family_id = s.family_id AND a.revoked_at IS NULL AND a.expires_at > NOW()
+		  ))
+	`, userID)
+	if err != nil {
+		return 0, err
+	}
+	return cmd.RowsAffected(), nil
+}
+
+// IsSessionActive reports whether the session family still has a refresh
+// token that is neither revoked nor expired. For active sessions it also
+// returns the language preferred by the session's user, or an empty string.
+func (r *Repo) IsSessionActive(ctx context.Context, familyID string) (bool, string, error) {
+	var language string
+	err := r.db.QueryRow(ctx, `
+		SELECT COALESCE(u.language, '')
+		FROM sessions s
+		JOIN users u ON u.id = s.user_id
+		WHERE s.family_id = $1 AND s.revoked_at IS NULL AND s.expires_at > NOW()
+		LIMIT 1
+	`, familyID).Scan(&language)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, "", nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+	return true, language, nil
+}
+
+// ListActiveSessions returns the current refresh token of every active
+// session of the user.
+func (r *Repo) ListActiveSessions(ctx context.Context, userID int64) ([]Session, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT s.id, s.family_id, s.user_id, s.token_hash, s.user_agent, s.ip_address,
+		       s.created_at, s.expires_at, s.rotated_at, s.revoked_at,
+		       (SELECT MIN(f.created_at) FROM sessions f WHERE f.family_id = s.family_id)
+		FROM sessions s
+		WHERE s.user_id = $1 AND s.revoked_at IS NULL AND s.expires_at > NOW()
+		ORDER BY s.created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []Session
+	for rows.Next() {
+		var s Session
+		if err := rows.Scan(&s.ID, &s.FamilyID, &s.UserID, &s.TokenHash, &s.UserAgent, &s.IPAddress,
+			&s.CreatedAt, &s.ExpiresAt, &s.RotatedAt, &s.RevokedAt, &s.SignedInAt); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
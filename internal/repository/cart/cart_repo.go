@@ -0,0 +1,163 @@
+package cart
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pkgerrors "github.com/Cora23tt/order_service/pkg/errors"
+	"github.com/Cora23tt/order_service/pkg/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type Repo struct {
+	db  TxExecutor
+	log *zap.SugaredLogger
+}
+
+func NewRepo(db *pgxpool.Pool, log *zap.SugaredLogger) *Repo {
+	return &Repo{db: db, log: log}
+}
+
+func NewWithTx(tx pgx.Tx, log *zap.SugaredLogger) *Repo {
+	return &Repo{db: tx, log: log}
+}
+
+type TxExecutor interface {
+	Exec(context.Context, string, ...any) (pgconn.CommandTag, error)
+	Query(context.Context, string, ...any) (pgx.Rows, error)
+	QueryRow(context.Context, string, ...any) pgx.Row
+}
+
+// Item is a cart line together with the current state of its product.
+type Item struct {
+	ProductID     int64
+	Name          string
+	ImageURL      string
+	Price         int64
+	StockQuantity int64
+	Quantity      int64
+	UpdatedAt     time.Time
+}
+
+// Line is a product and the quantity of it in the cart.
+type Line struct {
+	ProductID int64
+	Quantity  int64
+}
+
+// ListItems returns the cart of the user in the order the products were
+// added.
+func (r *Repo) ListItems(ctx context.Context, userID int64) ([]Item, error) {
+	log := logger.FromContext(ctx, r.log)
+
+	rows, err := r.db.Query(ctx, `
+		SELECT c.product_id, p.name, COALESCE(p.image_url, ''), p.price, p.stock_quantity, c.quantity, c.updated_at
+		FROM cart_items c
+		JOIN products p ON p.id = c.product_id
+		WHERE c.user_id = $1
+		ORDER BY c.created_at, c.product_id
+	`, userID)
+	if err != nil {
+		log.Errorw("list cart items failed", "userID", userID, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	defer rows.Close()
+
+	var items []Item
+	for rows.Next() {
+		var it Item
+		if err := rows.Scan(&it.ProductID, &it.Name, &it.ImageURL, &it.Price, &it.StockQuantity, &it.Quantity, &it.UpdatedAt); err != nil {
+			log.Errorw("scan cart item failed", "userID", userID, "error", err)
+			return nil, pkgerrors.ErrInternal
+		}
+		items = append(items, it)
+	}
+	if err := rows.Err(); err != nil {
+		log.Errorw("iterate cart items failed", "userID", userID, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	return items, nil
+}
+
+// LockLines returns the cart lines of the user and locks them until the end
+// of the transaction, so the cart cannot change while it is checked out.
+func (r *Repo) LockLines(ctx context.Context, userID int64) ([]Line, error) {
+	log := logger.FromContext(ctx, r.log)
+
+	rows, err := r.db.Query(ctx, `
+		SELECT product_id, quantity
+		FROM cart_items
+		WHERE user_id = $1
+		ORDER BY product_id
+		FOR UPDATE
+	`, userID)
+	if err != nil {
+		log.Errorw("lock cart items failed", "userID", userID, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	defer rows.Close()
+
+	var lines []Line
+	for rows.Next() {
+		var l Line
+		if err := rows.Scan(&l.ProductID, &l.Quantity); err != nil {
+			log.Errorw("scan cart line failed", "userID", userID, "error", err)
+			return nil, pkgerrors.ErrInternal
+		}
+		lines = append(lines, l)
+	}
+	if err := rows.Err(); err != nil {
+		log.Errorw("iterate cart lines failed", "userID", userID, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	return lines, nil
+}
+
+// SetItem puts the product into the cart or replaces its quantity.
+func (r *Repo) SetItem(ctx context.Context, userID, productID, quantity int64) error {
+	log := logger.FromContext(ctx, r.log)
+
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO cart_items (user_id, product_id, quantity)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, product_id)
+		DO UPDATE SET quantity = EXCLUDED.quantity, updated_at = NOW()
+	`, userID, productID, quantity)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pkgerrors.PGErrForeignKeyViolation {
+			return pkgerrors.ErrNotFound
+		}
+		log.Errorw("set cart item failed", "userID", userID, "productID", productID, "error", err)
+		return pkgerrors.ErrInternal
+	}
+	return nil
+}
+
+func (r *Repo) DeleteItem(ctx context.Context, userID, productID int64) error {
+	log := logger.FromContext(ctx, r.log)
+
+	tag, err := r.db.Exec(ctx, `DELETE FROM cart_items WHERE user_id = $1 AND product_id = $2`, userID, productID)
+	if err != nil {
+		log.Errorw("delete cart item failed", "userID", userID, "productID", productID, "error", err)
+		return pkgerrors.ErrInternal
+	}
+	if tag.RowsAffected() == 0 {
+		return pkgerrors.ErrNotFound
+	}
+	return nil
+}
+
+func (r *Repo) Clear(ctx context.Context, userID int64) error {
+	log := logger.FromContext(ctx, r.log)
+
+	if _, err := r.db.Exec(ctx, `DELETE FROM cart_items WHERE user_id = $1`, userID); err != nil {
+		log.Errorw("clear cart failed", "userID", userID, "error", err)
+		return pkgerrors.ErrInternal
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pkgerrors "github.com/Cora23tt/order_service/pkg/errors"
+	"github.com/Cora23tt/order_service/pkg/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type Repo struct {
+	db  TxExecutor
+	log *zap.SugaredLogger
+}
+
+func NewRepo(db *pgxpool.Pool, log *zap.SugaredLogger) *Repo {
+	return &Repo{db: db, log: log}
+}
+
+func NewWithTx(tx pgx.Tx, log *zap.SugaredLogger) *Repo {
+	return &Repo{db: tx, log: log}
+}
+
+type TxExecutor interface {
+	Exec(context.Context, string, ...any) (pgconn.CommandTag, error)
+	Query(context.Context, string, ...any) (pgx.Rows, error)
+	QueryRow(context.Context, string, ...any) pgx.Row
+}
+
+// Slot is a time window in which orders are delivered to a pickup point.
+// Capacity is how many orders it takes; Booked counts its orders that are
+// neither cancelled nor refunded and Available is the number of places left.
+type Slot struct {
+	ID            int64     `json:"id" example:"7"`
+	PickupPointID int64     `json:"pickup_point_id" example:"1"`
+	StartsAt      time.Time `json:"starts_at" example:"2025-06-02T10:00:00+05:00"`
+	EndsAt        time.Time `json:"ends_at" example:"2025-06-02T12:00:00+05:00"`
+	Capacity      int64     `json:"capacity" example:"20"`
+	Booked        int64     `json:"booked" example:"12"`
+	Available     int64     `json:"available" example:"8"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// ListFilter selects the slots of a pickup point. Slots that have ended are
+// never listed.
+type ListFilter struct {
+	PickupPointID int64
+	// Until, if set, leaves out slots starting after it.
+	Until *time.Time
+	// AvailableOnly leaves out slots that have started or are fully booked.
+	AvailableOnly bool
+}
+
+// booked counts the orders holding a place in the slot s. Cancelled and
+// refunded orders give their place back.
+const booked = `(SELECT COUNT(*) FROM orders o WHERE o.delivery_slot_id = s.id AND o.status NOT IN ('cancelled', 'refunded'))`
+
+func (r *Repo) Create(ctx context.Context, s *Slot) error {
+	log := logger.FromContext(ctx, r.log)
+
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO delivery_slots (pickup_point_id, starts_at, ends_at, capacity)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at
+	`, s.PickupPointID, s.StartsAt, s.EndsAt, s.Capacity).Scan(&s.ID, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return r.handlePgError(ctx, err, "create delivery slot")
+	}
+	log.Infow("delivery slot created", "slotID", s.ID, "pickupPointID", s.PickupPointID)
+	return nil
+}
+
+func (r *Repo) Update(ctx context.Context, s *Slot) error {
+	err := r.db.QueryRow(ctx, `
+		UPDATE delivery_slots
+		SET starts_at = $2, ends_at = $3, capacity = $4, updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at
+	`, s.ID, s.StartsAt, s.EndsAt, s.Capacity).Scan(&s.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return pkgerrors.ErrNotFound
+	}
+	if err != nil {
+		return r.handlePgError(ctx, err, "update delivery slot")
+	}
+	return nil
+}
+
+func (r *Repo) Delete(ctx context.Context, id int64) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM delivery_slots WHERE id = $1`, id)
+	if err != nil {
+		return r.handlePgError(ctx, err, "delete delivery slot")
+	}
+	if tag.RowsAffected() == 0 {
+		return pkgerrors.ErrNotFound
+	}
+	return nil
+}
+
+// GetByIDForUpdate loads the slot and locks it until the end of the
+// transaction, so concurrent bookings of one slot are serialized. Booked and
+// Available are not filled in: count them with CountBooked once the lock is
+// held.
+func (r *Repo) GetByIDForUpdate(ctx context.Context, id int64) (*Slot, error) {
+	log := logger.FromContext(ctx, r.log)
+
+	var s Slot
+	err := r.db.QueryRow(ctx, `
+		SELECT id, pickup_point_id, starts_at, ends_at, capacity, created_at, updated_at
+		FROM delivery_slots
+		WHERE id = $1
+		FOR UPDATE
+	`, id).Scan(&s.ID, &s.PickupPointID, &s.StartsAt, &s.EndsAt, &s.Capacity, &s.CreatedAt, &s.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, pkgerrors.ErrNotFound
+	}
+	if err != nil {
+		log.Errorw("get delivery slot failed", "slotID", id, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	return &s, nil
+}
+
+// CountBooked returns how many orders that are neither cancelled nor refunded
+// hold a place in the slot.
+func (r *Repo) CountBooked(ctx context.Context, id int64) (int64, error) {
+	log := logger.FromContext(ctx, r.log)
+
+	var n int64
+	err := r.db.QueryRow(ctx, `
+		SELECT COUNT(*) FROM orders WHERE delivery_slot_id = $1 AND status NOT IN ('cancelled', 'refunded')
+	`, id).Scan(&n)
+	if err != nil {
+		log.Errorw("count delivery slot bookings failed", "slotID", id, "error", err)
+		return 0, pkgerrors.ErrInternal
+	}
+	return n, nil
+}
+
+// List returns the slots matching the filter, earliest first.
+func (r *Repo) List(ctx context.Context, f ListFilter) ([]*Slot, error) {
+	log := logger.FromContext(ctx, r.log)
+
+	query := `
+		SELECT id, pickup_point_id, starts_at, ends_at, capacity, booked, created_at, updated_at
+		FROM (
+			SELECT s.*, ` + booked + ` AS booked
+			FROM delivery_slots s
+			WHERE s.pickup_point_id = $1 AND s.ends_at > NOW()
+		) s
+		WHERE 1=1`
+	args := []any{f.PickupPointID}
+	if f.Until != nil {
+		args = append(args, *f.Until)
+		query += ` AND starts_at <= $2`
+	}
+	if f.AvailableOnly {
+		query += ` AND starts_at > NOW() AND booked < capacity`
+	}
+	query += ` ORDER BY starts_at, id`
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		log.Errorw("list delivery slots failed", "pickupPointID", f.PickupPointID, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	defer rows.Close()
+
+	slots := []*Slot{}
+	for rows.Next() {
+		var s Slot
+		if err := rows.Scan(&s.ID, &s.PickupPointID, &s.StartsAt, &s.EndsAt, &s.Capacity, &s.Booked, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			log.Errorw("scan delivery slot failed", "error", err)
+			return nil, pkgerrors.ErrInternal
+		}
+		s.Available = max(s.Capacity-s.Booked, 0)
+		slots = append(slots, &s)
+	}
+	if err := rows.Err(); err != nil {
+		log.Errorw("iterate delivery slots failed", "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	return slots, nil
+}
+
+func (r *Repo) handlePgError(ctx context.Context, err error, op string) error {
+	log := logger.FromContext(ctx, r.log)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case pkgerrors.PGErrForeignKeyViolation:
+			return pkgerrors.ErrInvalidInput
+		case pkgerrors.PGErrUniqueViolation:
+			return pkgerrors.ErrAlreadyExists
+		}
+	}
+	log.Errorw(op+" failed", "error", err)
+	return pkgerrors.ErrInternal
+}
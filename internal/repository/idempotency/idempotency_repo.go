@@ -0,0 +1,140 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pkgerrors "github.com/Cora23tt/order_service/pkg/errors"
+	"github.com/Cora23tt/order_service/pkg/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type Repo struct {
+	db  TxExecutor
+	log *zap.SugaredLogger
+}
+
+func NewRepo(db *pgxpool.Pool, log *zap.SugaredLogger) *Repo {
+	return &Repo{db: db, log: log}
+}
+
+func NewWithTx(tx pgx.Tx, log *zap.SugaredLogger) *Repo {
+	return &Repo{db: tx, log: log}
+}
+
+type TxExecutor interface {
+	Exec(context.Context, string, ...any) (pgconn.CommandTag, error)
+	Query(context.Context, string, ...any) (pgx.Rows, error)
+	QueryRow(context.Context, string, ...any) pgx.Row
+}
+
+// Record is a stored idempotency key. StatusCode is nil while the request
+// that reserved the key is still being processed.
+type Record struct {
+	Fingerprint string
+	StatusCode  *int
+	ContentType string
+	Body        []byte
+}
+
+// Reserve stores the key for a new request, which holds it for lock. token
+// identifies the reservation: only its request can Complete or Delete it. A
+// key whose record has expired, or whose request has not finished within its
+// lock, is taken over. It reports false if the key is already in use.
+func (r *Repo) Reserve(ctx context.Context, userID int64, key, fingerprint, token string, ttl, lock time.Duration) (bool, error) {
+	log := logger.FromContext(ctx, r.log)
+
+	var id int64
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO idempotency_keys (user_id, key, fingerprint, expires_at, locked_until, token)
+		VALUES ($1, $2, $3, NOW() + make_interval(secs => $4), NOW() + make_interval(secs => $5), $6)
+		ON CONFLICT (user_id, key) DO UPDATE
+		SET fingerprint = EXCLUDED.fingerprint,
+		    status_code = NULL,
+		    content_type = NULL,
+		    response_body = NULL,
+		    created_at = NOW(),
+		    expires_at = EXCLUDED.expires_at,
+		    locked_until = EXCLUDED.locked_until,
+		    token = EXCLUDED.token
+		WHERE idempotency_keys.expires_at <= NOW()
+		   OR (idempotency_keys.status_code IS NULL AND idempotency_keys.locked_until <= NOW())
+		RETURNING user_id
+	`, userID, key, fingerprint, ttl.Seconds(), lock.Seconds(), token).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		log.Errorw("reserve idempotency key failed", "userID", userID, "error", err)
+		return false, pkgerrors.ErrInternal
+	}
+	return true, nil
+}
+
+// Get returns the unexpired record of the key.
+func (r *Repo) Get(ctx context.Context, userID int64, key string) (*Record, error) {
+	log := logger.FromContext(ctx, r.log)
+
+	var rec Record
+	var contentType *string
+	err := r.db.QueryRow(ctx, `
+		SELECT fingerprint, status_code, content_type, response_body
+		FROM idempotency_keys
+		WHERE user_id = $1 AND key = $2 AND expires_at > NOW()
+	`, userID, key).Scan(&rec.Fingerprint, &rec.StatusCode, &contentType, &rec.Body)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, pkgerrors.ErrNotFound
+	}
+	if err != nil {
+		log.Errorw("get idempotency key failed", "userID", userID, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	if contentType != nil {
+		rec.ContentType = *contentType
+	}
+	return &rec, nil
+}
+
+// Complete stores the response of the request that reserved the key with
+// token. A reservation taken over by another request is left alone.
+func (r *Repo) Complete(ctx context.Context, userID int64, key, token string, statusCode int, contentType string, body []byte) error {
+	log := logger.FromContext(ctx, r.log)
+
+	_, err := r.db.Exec(ctx, `
+		UPDATE idempotency_keys
+		SET status_code = $4, content_type = $5, response_body = $6
+		WHERE user_id = $1 AND key = $2 AND token = $3 AND status_code IS NULL
+	`, userID, key, token, statusCode, contentType, body)
+	if err != nil {
+		log.Errorw("complete idempotency key failed", "userID", userID, "error", err)
+		return pkgerrors.ErrInternal
+	}
+	return nil
+}
+
+// Delete removes the reservation of the key with token, unless a response has
+// already been stored for it.
+func (r *Repo) Delete(ctx context.Context, userID int64, key, token string) error {
+	log := logger.FromContext(ctx, r.log)
+
+	if _, err := r.db.Exec(ctx, `DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND token = $3 AND status_code IS NULL`, userID, key, token); err != nil {
+		log.Errorw("delete idempotency key failed", "userID", userID, "error", err)
+		return pkgerrors.ErrInternal
+	}
+	return nil
+}
+
+// DeleteExpired removes the expired keys of the user.
+func (r *Repo) DeleteExpired(ctx context.Context, userID int64) error {
+	log := logger.FromContext(ctx, r.log)
+
+	if _, err := r.db.Exec(ctx, `DELETE FROM idempotency_keys WHERE user_id = $1 AND expires_at <= NOW()`, userID); err != nil {
+		log.Errorw("delete expired idempotency keys failed", "userID", userID, "error", err)
+		return pkgerrors.ErrInternal
+	}
+	return nil
+}
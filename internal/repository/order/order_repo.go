@@ -8,6 +8,7 @@ import (
 
 	"github.com/Cora23tt/order_service/pkg/enums"
 	pkgerrors "github.com/Cora23tt/order_service/pkg/errors"
+	"github.com/Cora23tt/order_service/pkg/logger"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -33,98 +34,350 @@ type TxExecutor interface {
 	QueryRow(context.Context, string, ...any) pgx.Row
 }
 
+// Order is a stored order. SubtotalAmount is the sum of the items at their
+// prices and DiscountAmount the promo code discount. TotalAmount is what the
+// customer pays, the gross amount: NetAmount plus TaxAmount. RefundedAmount is
+// the part of it refunded so far. PickupPoint is the name and address of the
+// pickup point as they were when the order was placed; PickupPointID is unset
+// once the point is deleted. DeliveryDate is the day of the booked delivery
+// slot in the pickup point timezone and outlives the slot itself.
 type Order struct {
-	ID           int64             `json:"id"`
-	UserID       int64             `json:"user_id"`
-	Status       enums.OrderStatus `json:"status"`
-	DeliveryDate *time.Time        `json:"delivery_date,omitempty"`
-	PickupPoint  string            `json:"pickup_point"`
-	OrderDate    time.Time         `json:"order_date"`
-	TotalAmount  int64             `json:"total_amount"`
-	ReceiptURL   *string           `json:"receipt_url,omitempty"`
-	CreatedAt    time.Time         `json:"created_at"`
-	UpdatedAt    time.Time         `json:"updated_at"`
-	Items        []OrderItem       `json:"items,omitempty"`
+	ID             int64             `json:"id"`
+	UserID         int64             `json:"user_id"`
+	Status         enums.OrderStatus `json:"status"`
+	DeliveryDate   *time.Time        `json:"delivery_date,omitempty"`
+	DeliverySlotID *int64            `json:"delivery_slot_id,omitempty"`
+	PickupPointID  *int64            `json:"pickup_point_id,omitempty"`
+	PickupPoint    string            `json:"pickup_point"`
+	OrderDate      time.Time         `json:"order_date"`
+	SubtotalAmount int64             `json:"subtotal_amount"`
+	DiscountAmount int64             `json:"discount_amount"`
+	NetAmount      int64             `json:"net_amount"`
+	TaxAmount      int64             `json:"tax_amount"`
+	TotalAmount    int64             `json:"total_amount"`
+	RefundedAmount int64             `json:"refunded_amount"`
+	ReceiptURL     *string           `json:"receipt_url,omitempty"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+	Items          []OrderItem       `json:"items,omitempty"`
+	Discounts      []OrderDiscount   `json:"discounts,omitempty"`
+	Refunds        []Refund          `json:"refunds,omitempty"`
+	History        []StatusChange    `json:"history,omitempty"`
 }
 
+// OrderItem is an order line. TotalPrice is Price * Quantity; the line's share
+// of the order discount is taken off it before VAT at VATRate basis points is
+// computed, giving GrossAmount = NetAmount + TaxAmount. RefundedQuantity of
+// the items has been returned.
 type OrderItem struct {
-	ProductID  int64 `json:"product_id"`
-	Quantity   int64 `json:"quantity"`
-	Price      int64 `json:"price"`
-	TotalPrice int64 `json:"total_price"`
+	ID               int64 `json:"id"`
+	ProductID        int64 `json:"product_id"`
+	Quantity         int64 `json:"quantity"`
+	Price            int64 `json:"price"`
+	TotalPrice       int64 `json:"total_price"`
+	DiscountAmount   int64 `json:"discount_amount"`
+	VATRate          int64 `json:"vat_rate" example:"1200"`
+	NetAmount        int64 `json:"net_amount"`
+	TaxAmount        int64 `json:"tax_amount"`
+	GrossAmount      int64 `json:"gross_amount"`
+	RefundedQuantity int64 `json:"refunded_quantity"`
+}
+
+// OrderDiscount is a promo code applied to the order. PromotionID is empty
+// once the promotion is deleted.
+type OrderDiscount struct {
+	PromotionID *int64 `json:"promotion_id,omitempty"`
+	Code        string `json:"code"`
+	Amount      int64  `json:"amount"`
+}
+
+// Refund returns money and items of a paid order. ProviderRef identifies the
+// refund at the payment provider and is empty if no money was returned.
+// ActorUserID is the admin who issued it.
+type Refund struct {
+	ID          int64        `json:"id"`
+	OrderID     int64        `json:"order_id"`
+	Amount      int64        `json:"amount"`
+	Reason      string       `json:"reason"`
+	ProviderRef string       `json:"provider_ref,omitempty"`
+	ActorUserID *int64       `json:"actor_user_id,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	Items       []RefundItem `json:"items"`
+}
+
+// RefundItem is a returned quantity of an order line and its share of the
+// refund amount.
+type RefundItem struct {
+	OrderItemID int64 `json:"order_item_id"`
+	ProductID   int64 `json:"product_id"`
+	Quantity    int64 `json:"quantity"`
+	Amount      int64 `json:"amount"`
+}
+
+// StatusChange is one entry of the order timeline. FromStatus is empty for
+// the entry written when the order is created, ActorUserID is empty for
+// changes made by the system.
+type StatusChange struct {
+	ID          int64              `json:"id"`
+	OrderID     int64              `json:"order_id"`
+	FromStatus  *enums.OrderStatus `json:"from_status,omitempty"`
+	ToStatus    enums.OrderStatus  `json:"to_status"`
+	ActorUserID *int64             `json:"actor_user_id,omitempty"`
+	ActorRole   string             `json:"actor_role"`
+	Reason      *string            `json:"reason,omitempty"`
+	CreatedAt   time.Time          `json:"created_at"`
 }
 
 func (r *Repo) Create(ctx context.Context, o *Order) (int64, error) {
+	log := logger.FromContext(ctx, r.log)
+
 	var orderID int64
 	err := r.db.QueryRow(ctx, `
-		INSERT INTO orders (user_id, status, delivery_date, pickup_point, total_amount)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO orders (user_id, status, delivery_date, delivery_slot_id, pickup_point_id, pickup_point, subtotal_amount, discount_amount, net_amount, tax_amount, total_amount)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 		RETURNING id
-	`, o.UserID, o.Status, o.DeliveryDate, o.PickupPoint, o.TotalAmount).Scan(&orderID)
+	`, o.UserID, o.Status, o.DeliveryDate, o.DeliverySlotID, o.PickupPointID, o.PickupPoint, o.SubtotalAmount, o.DiscountAmount, o.NetAmount, o.TaxAmount, o.TotalAmount).Scan(&orderID)
 	if err != nil {
-		r.log.Errorw("insert order failed", "userID", o.UserID, "error", err)
-		return 0, r.handlePgError(err, "create order")
+		log.Errorw("insert order failed", "userID", o.UserID, "error", err)
+		return 0, r.handlePgError(ctx, err, "create order")
 	}
 
 	for _, item := range o.Items {
 		_, err := r.db.Exec(ctx, `
-			INSERT INTO order_items (order_id, product_id, quantity, price)
+			INSERT INTO order_items (order_id, product_id, quantity, price, discount_amount, vat_rate, net_amount, tax_amount, gross_amount)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		`, orderID, item.ProductID, item.Quantity, item.Price, item.DiscountAmount, item.VATRate, item.NetAmount, item.TaxAmount, item.GrossAmount)
+		if err != nil {
+			log.Errorw("insert order item failed", "orderID", orderID, "productID", item.ProductID, "error", err)
+			return 0, r.handlePgError(ctx, err, "insert order item")
+		}
+	}
+
+	for _, d := range o.Discounts {
+		_, err := r.db.Exec(ctx, `
+			INSERT INTO order_discounts (order_id, promotion_id, code, amount)
 			VALUES ($1, $2, $3, $4)
-		`, orderID, item.ProductID, item.Quantity, item.Price)
+		`, orderID, d.PromotionID, d.Code, d.Amount)
 		if err != nil {
-			r.log.Errorw("insert order item failed", "orderID", orderID, "productID", item.ProductID, "error", err)
-			return 0, r.handlePgError(err, "insert order item")
+			log.Errorw("insert order discount failed", "orderID", orderID, "code", d.Code, "error", err)
+			return 0, r.handlePgError(ctx, err, "insert order discount")
 		}
 	}
 
-	r.log.Infow("order created", "orderID", orderID, "userID", o.UserID)
+	log.Infow("order created", "orderID", orderID, "userID", o.UserID)
 	return orderID, nil
 }
 
 func (r *Repo) GetByID(ctx context.Context, orderID int64) (*Order, error) {
+	return r.getByID(ctx, orderID, false)
+}
+
+// GetByIDForUpdate loads the order and locks its row until the end of the
+// transaction, so concurrent status changes are serialized.
+func (r *Repo) GetByIDForUpdate(ctx context.Context, orderID int64) (*Order, error) {
+	return r.getByID(ctx, orderID, true)
+}
+
+func (r *Repo) getByID(ctx context.Context, orderID int64, forUpdate bool) (*Order, error) {
+	log := logger.FromContext(ctx, r.log)
+
+	query := `
+		SELECT id, user_id, status, delivery_date, delivery_slot_id, pickup_point_id, pickup_point, order_date, subtotal_amount, discount_amount, net_amount, tax_amount, total_amount, refunded_amount, receipt_url, created_at, updated_at
+		FROM orders WHERE id = $1`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+
 	var o Order
-	err := r.db.QueryRow(ctx, `
-		SELECT id, user_id, status, delivery_date, pickup_point, order_date, total_amount, receipt_url, created_at, updated_at
-		FROM orders WHERE id = $1
-	`, orderID).Scan(&o.ID, &o.UserID, &o.Status, &o.DeliveryDate, &o.PickupPoint, &o.OrderDate, &o.TotalAmount, &o.ReceiptURL, &o.CreatedAt, &o.UpdatedAt)
+	err := r.db.QueryRow(ctx, query, orderID).Scan(&o.ID, &o.UserID, &o.Status, &o.DeliveryDate, &o.DeliverySlotID, &o.PickupPointID, &o.PickupPoint, &o.OrderDate, &o.SubtotalAmount, &o.DiscountAmount, &o.NetAmount, &o.TaxAmount, &o.TotalAmount, &o.RefundedAmount, &o.ReceiptURL, &o.CreatedAt, &o.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			r.log.Warnw("order not found", "orderID", orderID)
+			log.Warnw("order not found", "orderID", orderID)
 			return nil, pkgerrors.ErrNotFound
 		}
-		r.log.Errorw("get order failed", "orderID", orderID, "error", err)
+		log.Errorw("get order failed", "orderID", orderID, "error", err)
 		return nil, pkgerrors.ErrInternal
 	}
 
+	items, err := r.itemsByOrder(ctx, []int64{orderID})
+	if err != nil {
+		return nil, err
+	}
+	o.Items = items[orderID]
+
 	rows, err := r.db.Query(ctx, `
-		SELECT product_id, quantity, price, total_price
-		FROM order_items WHERE order_id = $1
+		SELECT promotion_id, code, amount
+		FROM order_discounts WHERE order_id = $1 ORDER BY id
 	`, orderID)
 	if err != nil {
-		r.log.Errorw("get order items failed", "orderID", orderID, "error", err)
+		log.Errorw("get order discounts failed", "orderID", orderID, "error", err)
 		return nil, pkgerrors.ErrInternal
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var item OrderItem
-		if err := rows.Scan(&item.ProductID, &item.Quantity, &item.Price, &item.TotalPrice); err != nil {
-			r.log.Errorw("scan order item failed", "orderID", orderID, "error", err)
+		var d OrderDiscount
+		if err := rows.Scan(&d.PromotionID, &d.Code, &d.Amount); err != nil {
+			log.Errorw("scan order discount failed", "orderID", orderID, "error", err)
 			return nil, pkgerrors.ErrInternal
 		}
-		o.Items = append(o.Items, item)
+		o.Discounts = append(o.Discounts, d)
+	}
+	rows.Close()
+
+	o.Refunds, err = r.refunds(ctx, orderID)
+	if err != nil {
+		return nil, err
 	}
 
 	return &o, nil
 }
 
+// refunds returns the refunds of the order with their items, oldest first.
+func (r *Repo) refunds(ctx context.Context, orderID int64) ([]Refund, error) {
+	log := logger.FromContext(ctx, r.log)
+
+	rows, err := r.db.Query(ctx, `
+		SELECT f.id, f.order_id, f.amount, f.reason, f.provider_ref, f.actor_user_id, f.created_at,
+		       ri.order_item_id, oi.product_id, ri.quantity, ri.amount
+		FROM refunds f
+		JOIN refund_items ri ON ri.refund_id = f.id
+		JOIN order_items oi ON oi.id = ri.order_item_id
+		WHERE f.order_id = $1
+		ORDER BY f.created_at, f.id, ri.order_item_id
+	`, orderID)
+	if err != nil {
+		log.Errorw("get order refunds failed", "orderID", orderID, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	defer rows.Close()
+
+	var refunds []Refund
+	for rows.Next() {
+		var f Refund
+		var item RefundItem
+		if err := rows.Scan(&f.ID, &f.OrderID, &f.Amount, &f.Reason, &f.ProviderRef, &f.ActorUserID, &f.CreatedAt,
+			&item.OrderItemID, &item.ProductID, &item.Quantity, &item.Amount); err != nil {
+			log.Errorw("scan order refund failed", "orderID", orderID, "error", err)
+			return nil, pkgerrors.ErrInternal
+		}
+		if n := len(refunds); n == 0 || refunds[n-1].ID != f.ID {
+			refunds = append(refunds, f)
+		}
+		last := &refunds[len(refunds)-1]
+		last.Items = append(last.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		log.Errorw("iterate order refunds failed", "orderID", orderID, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	return refunds, nil
+}
+
+// AddRefund stores the refund and sets its ID and creation time. The returned
+// quantities and the amount are added to the order lines and the order. It
+// must run in a transaction.
+func (r *Repo) AddRefund(ctx context.Context, f *Refund) error {
+	log := logger.FromContext(ctx, r.log)
+
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO refunds (order_id, amount, reason, provider_ref, actor_user_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, f.OrderID, f.Amount, f.Reason, f.ProviderRef, f.ActorUserID).Scan(&f.ID, &f.CreatedAt)
+	if err != nil {
+		log.Errorw("insert refund failed", "orderID", f.OrderID, "error", err)
+		return r.handlePgError(ctx, err, "add refund")
+	}
+
+	for _, item := range f.Items {
+		_, err := r.db.Exec(ctx, `
+			INSERT INTO refund_items (refund_id, order_item_id, quantity, amount)
+			VALUES ($1, $2, $3, $4)
+		`, f.ID, item.OrderItemID, item.Quantity, item.Amount)
+		if err != nil {
+			log.Errorw("insert refund item failed", "refundID", f.ID, "orderItemID", item.OrderItemID, "error", err)
+			return r.handlePgError(ctx, err, "add refund item")
+		}
+
+		_, err = r.db.Exec(ctx, `
+			UPDATE order_items SET refunded_quantity = refunded_quantity + $2 WHERE id = $1
+		`, item.OrderItemID, item.Quantity)
+		if err != nil {
+			log.Errorw("update refunded quantity failed", "orderItemID", item.OrderItemID, "error", err)
+			return r.handlePgError(ctx, err, "update refunded quantity")
+		}
+	}
+
+	_, err = r.db.Exec(ctx, `
+		UPDATE orders SET refunded_amount = refunded_amount + $2, updated_at = NOW() WHERE id = $1
+	`, f.OrderID, f.Amount)
+	if err != nil {
+		log.Errorw("update refunded amount failed", "orderID", f.OrderID, "error", err)
+		return r.handlePgError(ctx, err, "update refunded amount")
+	}
+
+	log.Infow("refund added", "orderID", f.OrderID, "refundID", f.ID, "amount", f.Amount)
+	return nil
+}
+
+// SetRefundProviderRef stores the reference of the refund at the payment
+// provider.
+func (r *Repo) SetRefundProviderRef(ctx context.Context, refundID int64, providerRef string) error {
+	log := logger.FromContext(ctx, r.log)
+
+	tag, err := r.db.Exec(ctx, `UPDATE refunds SET provider_ref = $2 WHERE id = $1`, refundID, providerRef)
+	if err != nil {
+		log.Errorw("set refund provider ref failed", "refundID", refundID, "error", err)
+		return pkgerrors.ErrInternal
+	}
+	if tag.RowsAffected() == 0 {
+		return pkgerrors.ErrNotFound
+	}
+	return nil
+}
+
+// itemsByOrder returns the items of the orders keyed by order ID.
+func (r *Repo) itemsByOrder(ctx context.Context, orderIDs []int64) (map[int64][]OrderItem, error) {
+	log := logger.FromContext(ctx, r.log)
+
+	rows, err := r.db.Query(ctx, `
+		SELECT order_id, id, product_id, quantity, price, total_price, discount_amount, vat_rate, net_amount, tax_amount, gross_amount, refunded_quantity
+		FROM order_items WHERE order_id = ANY($1) ORDER BY order_id, id
+	`, orderIDs)
+	if err != nil {
+		log.Errorw("get order items failed", "orderIDs", orderIDs, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	defer rows.Close()
+
+	items := make(map[int64][]OrderItem, len(orderIDs))
+	for rows.Next() {
+		var orderID int64
+		var item OrderItem
+		if err := rows.Scan(&orderID, &item.ID, &item.ProductID, &item.Quantity, &item.Price, &item.TotalPrice,
+			&item.DiscountAmount, &item.VATRate, &item.NetAmount, &item.TaxAmount, &item.GrossAmount, &item.RefundedQuantity); err != nil {
+			log.Errorw("scan order item failed", "orderID", orderID, "error", err)
+			return nil, pkgerrors.ErrInternal
+		}
+		items[orderID] = append(items[orderID], item)
+	}
+	if err := rows.Err(); err != nil {
+		log.Errorw("iterate order items failed", "orderIDs", orderIDs, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	return items, nil
+}
+
 func (r *Repo) GetAllByUser(ctx context.Context, userID int64) ([]*Order, error) {
+	log := logger.FromContext(ctx, r.log)
+
 	rows, err := r.db.Query(ctx, `
-		SELECT id, user_id, status, delivery_date, pickup_point, order_date, total_amount, receipt_url, created_at, updated_at
+		SELECT id, user_id, status, delivery_date, delivery_slot_id, pickup_point_id, pickup_point, order_date, subtotal_amount, discount_amount, net_amount, tax_amount, total_amount, refunded_amount, receipt_url, created_at, updated_at
 		FROM orders WHERE user_id = $1 ORDER BY order_date DESC
 	`, userID)
 	if err != nil {
-		r.log.Errorw("get all orders failed", "userID", userID, "error", err)
+		log.Errorw("get all orders failed", "userID", userID, "error", err)
 		return nil, pkgerrors.ErrInternal
 	}
 	defer rows.Close()
@@ -132,8 +385,8 @@ func (r *Repo) GetAllByUser(ctx context.Context, userID int64) ([]*Order, error)
 	var orders []*Order
 	for rows.Next() {
 		var o Order
-		if err := rows.Scan(&o.ID, &o.UserID, &o.Status, &o.DeliveryDate, &o.PickupPoint, &o.OrderDate, &o.TotalAmount, &o.ReceiptURL, &o.CreatedAt, &o.UpdatedAt); err != nil {
-			r.log.Errorw("scan order failed", "userID", userID, "error", err)
+		if err := rows.Scan(&o.ID, &o.UserID, &o.Status, &o.DeliveryDate, &o.DeliverySlotID, &o.PickupPointID, &o.PickupPoint, &o.OrderDate, &o.SubtotalAmount, &o.DiscountAmount, &o.NetAmount, &o.TaxAmount, &o.TotalAmount, &o.RefundedAmount, &o.ReceiptURL, &o.CreatedAt, &o.UpdatedAt); err != nil {
+			log.Errorw("scan order failed", "userID", userID, "error", err)
 			return nil, pkgerrors.ErrInternal
 		}
 		orders = append(orders, &o)
@@ -141,36 +394,151 @@ func (r *Repo) GetAllByUser(ctx context.Context, userID int64) ([]*Order, error)
 	return orders, nil
 }
 
-func (r *Repo) UpdateStatus(ctx context.Context, orderID int64, status string) error {
+func (r *Repo) UpdateStatus(ctx context.Context, orderID int64, status enums.OrderStatus) error {
+	log := logger.FromContext(ctx, r.log)
+
 	cmd, err := r.db.Exec(ctx, `
 		UPDATE orders SET status = $1, updated_at = NOW() WHERE id = $2
 	`, status, orderID)
 	if err != nil {
-		r.log.Errorw("update order status failed", "orderID", orderID, "status", status, "error", err)
-		return r.handlePgError(err, "update status")
+		log.Errorw("update order status failed", "orderID", orderID, "status", status, "error", err)
+		return r.handlePgError(ctx, err, "update status")
+	}
+	if cmd.RowsAffected() == 0 {
+		log.Warnw("order not found for update", "orderID", orderID)
+		return pkgerrors.ErrNotFound
+	}
+	return nil
+}
+
+// LockExpired locks up to limit orders that have been pending payment for
+// longer than timeout and returns their IDs, oldest first. Orders locked by
+// other transactions are skipped, so several instances can expire orders at
+// the same time without waiting for or cancelling the same ones.
+func (r *Repo) LockExpired(ctx context.Context, timeout time.Duration, limit int) ([]int64, error) {
+	log := logger.FromContext(ctx, r.log)
+
+	rows, err := r.db.Query(ctx, `
+		SELECT id FROM orders
+		WHERE status = $1 AND created_at < NOW() - make_interval(secs => $2)
+		ORDER BY created_at, id
+		LIMIT $3
+		FOR UPDATE SKIP LOCKED
+	`, enums.StatusPendingPayment, timeout.Seconds(), limit)
+	if err != nil {
+		log.Errorw("lock expired orders failed", "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			log.Errorw("scan expired order failed", "error", err)
+			return nil, pkgerrors.ErrInternal
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		log.Errorw("iterate expired orders failed", "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	return ids, nil
+}
+
+func (r *Repo) AddStatusChange(ctx context.Context, c *StatusChange) error {
+	log := logger.FromContext(ctx, r.log)
+
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO order_status_history (order_id, from_status, to_status, actor_user_id, actor_role, reason)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, c.OrderID, c.FromStatus, c.ToStatus, c.ActorUserID, c.ActorRole, c.Reason)
+	if err != nil {
+		log.Errorw("insert order status change failed", "orderID", c.OrderID, "to", c.ToStatus, "error", err)
+		return r.handlePgError(ctx, err, "add status change")
+	}
+	return nil
+}
+
+func (r *Repo) GetStatusHistory(ctx context.Context, orderID int64) ([]StatusChange, error) {
+	log := logger.FromContext(ctx, r.log)
+
+	rows, err := r.db.Query(ctx, `
+		SELECT id, order_id, from_status, to_status, actor_user_id, actor_role, reason, created_at
+		FROM order_status_history WHERE order_id = $1 ORDER BY created_at, id
+	`, orderID)
+	if err != nil {
+		log.Errorw("get order status history failed", "orderID", orderID, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	defer rows.Close()
+
+	var history []StatusChange
+	for rows.Next() {
+		var c StatusChange
+		if err := rows.Scan(&c.ID, &c.OrderID, &c.FromStatus, &c.ToStatus, &c.ActorUserID, &c.ActorRole, &c.Reason, &c.CreatedAt); err != nil {
+			log.Errorw("scan order status change failed", "orderID", orderID, "error", err)
+			return nil, pkgerrors.ErrInternal
+		}
+		history = append(history, c)
+	}
+	return history, nil
+}
+
+// StatusReachedAt returns when the order was last moved to status, or nil if
+// it never was.
+func (r *Repo) StatusReachedAt(ctx context.Context, orderID int64, status enums.OrderStatus) (*time.Time, error) {
+	log := logger.FromContext(ctx, r.log)
+
+	var at *time.Time
+	err := r.db.QueryRow(ctx, `
+		SELECT MAX(created_at) FROM order_status_history WHERE order_id = $1 AND to_status = $2
+	`, orderID, status).Scan(&at)
+	if err != nil {
+		log.Errorw("get order status time failed", "orderID", orderID, "status", status, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	return at, nil
+}
+
+// SetReceiptURL records where the receipt of the order can be downloaded.
+func (r *Repo) SetReceiptURL(ctx context.Context, orderID int64, url string) error {
+	log := logger.FromContext(ctx, r.log)
+
+	cmd, err := r.db.Exec(ctx, `
+		UPDATE orders SET receipt_url = $1, updated_at = NOW() WHERE id = $2
+	`, url, orderID)
+	if err != nil {
+		log.Errorw("set receipt url failed", "orderID", orderID, "error", err)
+		return r.handlePgError(ctx, err, "set receipt url")
 	}
 	if cmd.RowsAffected() == 0 {
-		r.log.Warnw("order not found for update", "orderID", orderID)
+		log.Warnw("order not found for receipt", "orderID", orderID)
 		return pkgerrors.ErrNotFound
 	}
 	return nil
 }
 
 func (r *Repo) Delete(ctx context.Context, orderID int64) error {
+	log := logger.FromContext(ctx, r.log)
+
 	cmd, err := r.db.Exec(ctx, `DELETE FROM orders WHERE id = $1`, orderID)
 	if err != nil {
-		r.log.Errorw("delete order failed", "orderID", orderID, "error", err)
-		return r.handlePgError(err, "delete order")
+		log.Errorw("delete order failed", "orderID", orderID, "error", err)
+		return r.handlePgError(ctx, err, "delete order")
 	}
 	if cmd.RowsAffected() == 0 {
-		r.log.Warnw("order not found for delete", "orderID", orderID)
+		log.Warnw("order not found for delete", "orderID", orderID)
 		return pkgerrors.ErrNotFound
 	}
-	r.log.Infow("order deleted", "orderID", orderID)
+	log.Infow("order deleted", "orderID", orderID)
 	return nil
 }
 
-func (r *Repo) handlePgError(err error, context string) error {
+func (r *Repo) handlePgError(ctx context.Context, err error, op string) error {
+	log := logger.FromContext(ctx, r.log)
+
 	if pgErr, ok := err.(*pgconn.PgError); ok {
 		switch pgErr.Code {
 		case pkgerrors.PGErrForeignKeyViolation:
@@ -180,11 +548,11 @@ func (r *Repo) handlePgError(err error, context string) error {
 		case pkgerrors.PGErrInvalidTextRep, pkgerrors.PGErrInvalidType:
 			return pkgerrors.ErrInvalidInput
 		default:
-			r.log.Errorw(context+" failed", "pg_code", pgErr.Code, "pg_msg", pgErr.Message)
+			log.Errorw(op+" failed", "pg_code", pgErr.Code, "pg_msg", pgErr.Message)
 			return pkgerrors.ErrInternal
 		}
 	}
-	r.log.Errorw(context+" failed (non-pg)", "error", err)
+	log.Errorw(op+" failed (non-pg)", "error", err)
 	return pkgerrors.ErrInternal
 }
 
@@ -194,6 +562,8 @@ type OrderStats struct {
 }
 
 func (r *Repo) GetStats(ctx context.Context, from, to time.Time) ([]OrderStats, error) {
+	log := logger.FromContext(ctx, r.log)
+
 	query := `
 		SELECT status, COUNT(*) 
 		FROM orders 
@@ -202,7 +572,7 @@ func (r *Repo) GetStats(ctx context.Context, from, to time.Time) ([]OrderStats,
 	`
 	rows, err := r.db.Query(ctx, query, from, to)
 	if err != nil {
-		r.log.Errorw("get order stats failed", "error", err)
+		log.Errorw("get order stats failed", "error", err)
 		return nil, pkgerrors.ErrInternal
 	}
 	defer rows.Close()
@@ -211,7 +581,7 @@ func (r *Repo) GetStats(ctx context.Context, from, to time.Time) ([]OrderStats,
 	for rows.Next() {
 		var s OrderStats
 		if err := rows.Scan(&s.Status, &s.Count); err != nil {
-			r.log.Errorw("scan order stats failed", "error", err)
+			log.Errorw("scan order stats failed", "error", err)
 			return nil, pkgerrors.ErrInternal
 		}
 		stats = append(stats, s)
@@ -229,8 +599,10 @@ type ExportFilter struct {
 }
 
 func (r *Repo) Export(ctx context.Context, f ExportFilter) ([]*Order, error) {
+	log := logger.FromContext(ctx, r.log)
+
 	var (
-		query  = `SELECT id, user_id, status, delivery_date, pickup_point, order_date, total_amount, receipt_url, created_at, updated_at FROM orders WHERE 1=1`
+		query  = `SELECT id, user_id, status, delivery_date, delivery_slot_id, pickup_point_id, pickup_point, order_date, subtotal_amount, discount_amount, net_amount, tax_amount, total_amount, refunded_amount, receipt_url, created_at, updated_at FROM orders WHERE 1=1`
 		params []interface{}
 		index  = 1
 	)
@@ -265,7 +637,7 @@ func (r *Repo) Export(ctx context.Context, f ExportFilter) ([]*Order, error) {
 
 	rows, err := r.db.Query(ctx, query, params...)
 	if err != nil {
-		r.log.Errorw("export orders failed", "error", err)
+		log.Errorw("export orders failed", "error", err)
 		return nil, pkgerrors.ErrInternal
 	}
 	defer rows.Close()
@@ -273,12 +645,25 @@ func (r *Repo) Export(ctx context.Context, f ExportFilter) ([]*Order, error) {
 	var orders []*Order
 	for rows.Next() {
 		var o Order
-		if err := rows.Scan(&o.ID, &o.UserID, &o.Status, &o.DeliveryDate, &o.PickupPoint, &o.OrderDate, &o.TotalAmount, &o.ReceiptURL, &o.CreatedAt, &o.UpdatedAt); err != nil {
-			r.log.Errorw("scan order failed", "error", err)
+		if err := rows.Scan(&o.ID, &o.UserID, &o.Status, &o.DeliveryDate, &o.DeliverySlotID, &o.PickupPointID, &o.PickupPoint, &o.OrderDate, &o.SubtotalAmount, &o.DiscountAmount, &o.NetAmount, &o.TaxAmount, &o.TotalAmount, &o.RefundedAmount, &o.ReceiptURL, &o.CreatedAt, &o.UpdatedAt); err != nil {
+			log.Errorw("scan order failed", "error", err)
 			return nil, pkgerrors.ErrInternal
 		}
 		orders = append(orders, &o)
 	}
+	rows.Close()
+
+	ids := make([]int64, len(orders))
+	for i, o := range orders {
+		ids[i] = o.ID
+	}
+	items, err := r.itemsByOrder(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range orders {
+		o.Items = items[o.ID]
+	}
 
 	return orders, nil
 }
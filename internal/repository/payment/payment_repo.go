@@ -0,0 +1,181 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Cora23tt/order_service/pkg/enums"
+	pkgerrors "github.com/Cora23tt/order_service/pkg/errors"
+	"github.com/Cora23tt/order_service/pkg/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type Repo struct {
+	db  TxExecutor
+	log *zap.SugaredLogger
+}
+
+func NewRepo(db *pgxpool.Pool, log *zap.SugaredLogger) *Repo {
+	return &Repo{db: db, log: log}
+}
+
+func NewWithTx(tx pgx.Tx, log *zap.SugaredLogger) *Repo {
+	return &Repo{db: tx, log: log}
+}
+
+type TxExecutor interface {
+	Exec(context.Context, string, ...any) (pgconn.CommandTag, error)
+	Query(context.Context, string, ...any) (pgx.Rows, error)
+	QueryRow(context.Context, string, ...any) pgx.Row
+}
+
+// Payment is an invoice for an order at a payment provider. ProviderRef
+// identifies it at the provider; the customer pays it at PaymentURL.
+type Payment struct {
+	ID          int64               `json:"id" example:"1"`
+	OrderID     int64               `json:"order_id" example:"15"`
+	Provider    string              `json:"provider" example:"mock"`
+	ProviderRef string              `json:"provider_ref" example:"mock_5f1c2a7e-7c1b-4f7e-9a53-0d8c6a1e2b3f"`
+	Amount      int64               `json:"amount" example:"48000"`
+	Status      enums.PaymentStatus `json:"status" enums:"pending,paid,failed,refunded" example:"pending"`
+	PaymentURL  *string             `json:"payment_url,omitempty" example:"https://pay.example.com/mock/mock_5f1c2a7e-7c1b-4f7e-9a53-0d8c6a1e2b3f"`
+	PaidAt      *time.Time          `json:"paid_at,omitempty"`
+	CreatedAt   time.Time           `json:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+}
+
+const selectPayment = `
+	SELECT id, order_id, provider, provider_ref, amount, status, payment_url, paid_at, created_at, updated_at
+	FROM payments`
+
+func scanPayment(row pgx.Row) (*Payment, error) {
+	var p Payment
+	err := row.Scan(&p.ID, &p.OrderID, &p.Provider, &p.ProviderRef, &p.Amount, &p.Status,
+		&p.PaymentURL, &p.PaidAt, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Create stores the payment and sets its ID and timestamps. It returns
+// ErrAlreadyExists if the order already has a pending payment.
+func (r *Repo) Create(ctx context.Context, p *Payment) error {
+	log := logger.FromContext(ctx, r.log)
+
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO payments (order_id, provider, provider_ref, amount, status, payment_url)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at
+	`, p.OrderID, p.Provider, p.ProviderRef, p.Amount, p.Status, p.PaymentURL).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			switch pgErr.Code {
+			case pkgerrors.PGErrUniqueViolation:
+				return pkgerrors.ErrAlreadyExists
+			case pkgerrors.PGErrForeignKeyViolation:
+				return pkgerrors.ErrInvalidInput
+			}
+		}
+		log.Errorw("create payment failed", "order_id", p.OrderID, "error", err)
+		return pkgerrors.ErrInternal
+	}
+	return nil
+}
+
+func (r *Repo) GetByProviderRef(ctx context.Context, provider, ref string) (*Payment, error) {
+	log := logger.FromContext(ctx, r.log)
+
+	p, err := scanPayment(r.db.QueryRow(ctx, selectPayment+` WHERE provider = $1 AND provider_ref = $2`, provider, ref))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, pkgerrors.ErrNotFound
+	}
+	if err != nil {
+		log.Errorw("get payment by provider ref failed", "provider", provider, "provider_ref", ref, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	return p, nil
+}
+
+// GetPendingByOrder returns the invoice of the order that is waiting to be
+// paid.
+func (r *Repo) GetPendingByOrder(ctx context.Context, orderID int64) (*Payment, error) {
+	log := logger.FromContext(ctx, r.log)
+
+	p, err := scanPayment(r.db.QueryRow(ctx, selectPayment+` WHERE order_id = $1 AND status = 'pending'`, orderID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, pkgerrors.ErrNotFound
+	}
+	if err != nil {
+		log.Errorw("get pending payment failed", "order_id", orderID, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	return p, nil
+}
+
+// GetPaidByOrder returns the confirmed payment of the order. If there are
+// several, the latest one is returned.
+func (r *Repo) GetPaidByOrder(ctx context.Context, orderID int64) (*Payment, error) {
+	log := logger.FromContext(ctx, r.log)
+
+	p, err := scanPayment(r.db.QueryRow(ctx, selectPayment+` WHERE order_id = $1 AND status = 'paid' ORDER BY paid_at DESC, id DESC LIMIT 1`, orderID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, pkgerrors.ErrNotFound
+	}
+	if err != nil {
+		log.Errorw("get paid payment failed", "order_id", orderID, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	return p, nil
+}
+
+func (r *Repo) ListByOrder(ctx context.Context, orderID int64) ([]*Payment, error) {
+	log := logger.FromContext(ctx, r.log)
+
+	rows, err := r.db.Query(ctx, selectPayment+` WHERE order_id = $1 ORDER BY created_at, id`, orderID)
+	if err != nil {
+		log.Errorw("list payments failed", "order_id", orderID, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	defer rows.Close()
+
+	payments := []*Payment{}
+	for rows.Next() {
+		p, err := scanPayment(rows)
+		if err != nil {
+			log.Errorw("scan payment failed", "order_id", orderID, "error", err)
+			return nil, pkgerrors.ErrInternal
+		}
+		payments = append(payments, p)
+	}
+	if err := rows.Err(); err != nil {
+		log.Errorw("iterate payments failed", "order_id", orderID, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	return payments, nil
+}
+
+// UpdateStatus moves the payment from one status to another and reports
+// whether it was in the from status. Only one of concurrent updates from the
+// same status succeeds, which makes repeated callbacks harmless.
+func (r *Repo) UpdateStatus(ctx context.Context, id int64, from, to enums.PaymentStatus) (bool, error) {
+	log := logger.FromContext(ctx, r.log)
+
+	tag, err := r.db.Exec(ctx, `
+		UPDATE payments
+		SET status = $3::TEXT,
+		    paid_at = CASE WHEN $3::TEXT = 'paid' THEN NOW() ELSE paid_at END,
+		    updated_at = NOW()
+		WHERE id = $1 AND status = $2
+	`, id, from, to)
+	if err != nil {
+		log.Errorw("update payment status failed", "payment_id", id, "from", from, "to", to, "error", err)
+		return false, pkgerrors.ErrInternal
+	}
+	return tag.RowsAffected() == 1, nil
+}
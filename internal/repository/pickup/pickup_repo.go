@@ -0,0 +1,291 @@
+package pickup
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pkgerrors "github.com/Cora23tt/order_service/pkg/errors"
+	"github.com/Cora23tt/order_service/pkg/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type Repo struct {
+	db  TxExecutor
+	log *zap.SugaredLogger
+}
+
+func NewRepo(db *pgxpool.Pool, log *zap.SugaredLogger) *Repo {
+	return &Repo{db: db, log: log}
+}
+
+func NewWithTx(tx pgx.Tx, log *zap.SugaredLogger) *Repo {
+	return &Repo{db: tx, log: log}
+}
+
+type TxExecutor interface {
+	Exec(context.Context, string, ...any) (pgconn.CommandTag, error)
+	Query(context.Context, string, ...any) (pgx.Rows, error)
+	QueryRow(context.Context, string, ...any) pgx.Row
+}
+
+// Point is a pickup point. Capacity is how many orders it can hold at once.
+// DistanceKm is only set when points are listed by distance; OpenNow is
+// filled in by the service.
+type Point struct {
+	ID           int64          `json:"id" example:"1"`
+	Name         string         `json:"name" example:"Buyuk Ipak Yoli"`
+	Address      string         `json:"address" example:"Mirzo Ulug'bek. Buyuk Ipak Yoli st. 109. 45"`
+	Latitude     float64        `json:"latitude" example:"41.3262"`
+	Longitude    float64        `json:"longitude" example:"69.3285"`
+	OpeningHours []OpeningHours `json:"opening_hours"`
+	Capacity     int64          `json:"capacity" example:"200"`
+	Active       bool           `json:"active" example:"true"`
+	DistanceKm   *float64       `json:"distance_km,omitempty" example:"1.7"`
+	OpenNow      bool           `json:"open_now" example:"true"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+}
+
+// OpeningHours is an interval a pickup point is open on one day of the week,
+// 1 being Monday and 7 Sunday. Times are HH:MM in the pickup point timezone;
+// an interval lasting until midnight closes at "24:00".
+type OpeningHours struct {
+	Weekday int    `json:"weekday" binding:"required,min=1,max=7" example:"1"`
+	Opens   string `json:"opens" binding:"required,datetime=15:04" example:"09:00"`
+	Closes  string `json:"closes" binding:"required" example:"20:00"`
+}
+
+// ListFilter selects pickup points. With a location the points are sorted
+// nearest first, otherwise by name.
+type ListFilter struct {
+	ActiveOnly bool
+	Latitude   *float64
+	Longitude  *float64
+}
+
+const selectPoint = `
+	SELECT id, name, address, latitude, longitude, opening_hours, capacity, active, created_at, updated_at
+	FROM pickup_points`
+
+// distance is the great-circle distance in kilometres between a point and
+// the location in $1 (latitude) and $2 (longitude), by the haversine
+// formula. LEAST keeps rounding errors out of the domain of ASIN.
+const distance = `
+	2 * 6371 * ASIN(LEAST(1, SQRT(
+		POWER(SIN(RADIANS(latitude - $1) / 2), 2) +
+		COS(RADIANS($1)) * COS(RADIANS(latitude)) * POWER(SIN(RADIANS(longitude - $2) / 2), 2)
+	)))`
+
+func scanPoint(row pgx.Row, extra ...any) (*Point, error) {
+	var p Point
+	dest := append([]any{&p.ID, &p.Name, &p.Address, &p.Latitude, &p.Longitude, &p.OpeningHours,
+		&p.Capacity, &p.Active, &p.CreatedAt, &p.UpdatedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *Repo) Create(ctx context.Context, p *Point) error {
+	log := logger.FromContext(ctx, r.log)
+
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO pickup_points (name, address, latitude, longitude, opening_hours, capacity, active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`, p.Name, p.Address, p.Latitude, p.Longitude, p.OpeningHours, p.Capacity, p.Active).Scan(&p.ID)
+	if err != nil {
+		return r.handlePgError(ctx, err, "create pickup point")
+	}
+	log.Infow("pickup point created", "pickupPointID", p.ID)
+	return nil
+}
+
+func (r *Repo) Update(ctx context.Context, p *Point) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE pickup_points
+		SET name = $2, address = $3, latitude = $4, longitude = $5, opening_hours = $6,
+		    capacity = $7, active = $8, updated_at = NOW()
+		WHERE id = $1
+	`, p.ID, p.Name, p.Address, p.Latitude, p.Longitude, p.OpeningHours, p.Capacity, p.Active)
+	if err != nil {
+		return r.handlePgError(ctx, err, "update pickup point")
+	}
+	if tag.RowsAffected() == 0 {
+		return pkgerrors.ErrNotFound
+	}
+	return nil
+}
+
+// Delete removes the pickup point. Its orders keep the name and address they
+// were placed with.
+func (r *Repo) Delete(ctx context.Context, id int64) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM pickup_points WHERE id = $1`, id)
+	if err != nil {
+		return r.handlePgError(ctx, err, "delete pickup point")
+	}
+	if tag.RowsAffected() == 0 {
+		return pkgerrors.ErrNotFound
+	}
+	return nil
+}
+
+func (r *Repo) GetByID(ctx context.Context, id int64) (*Point, error) {
+	return r.getByID(ctx, id, "")
+}
+
+// GetByIDForUpdate loads the pickup point and locks it until the end of the
+// transaction, so an order is not placed at a point that is being deactivated
+// at the same time, and concurrent orders to one point are counted against
+// its capacity one after another.
+func (r *Repo) GetByIDForUpdate(ctx context.Context, id int64) (*Point, error) {
+	return r.getByID(ctx, id, " FOR UPDATE")
+}
+
+// LockDeliverySlots locks the delivery slots of the point until the end of
+// the transaction, in the order bookings lock a slot before its point.
+func (r *Repo) LockDeliverySlots(ctx context.Context, id int64) error {
+	log := logger.FromContext(ctx, r.log)
+
+	rows, err := r.db.Query(ctx, `SELECT id FROM delivery_slots WHERE pickup_point_id = $1 ORDER BY id FOR UPDATE`, id)
+	if err != nil {
+		log.Errorw("lock delivery slots failed", "pickupPointID", id, "error", err)
+		return pkgerrors.ErrInternal
+	}
+	defer rows.Close()
+	for rows.Next() {
+	}
+	if err := rows.Err(); err != nil {
+		log.Errorw("lock delivery slots failed", "pickupPointID", id, "error", err)
+		return pkgerrors.ErrInternal
+	}
+	return nil
+}
+
+// CountBookedSlotOrders returns how many orders hold a place in a delivery
+// slot of the point.
+func (r *Repo) CountBookedSlotOrders(ctx context.Context, id int64) (int64, error) {
+	log := logger.FromContext(ctx, r.log)
+
+	var n int64
+	err := r.db.QueryRow(ctx, `
+		SELECT COUNT(*) FROM orders o
+		JOIN delivery_slots s ON s.id = o.delivery_slot_id
+		WHERE s.pickup_point_id = $1 AND o.status NOT IN ('cancelled', 'refunded')
+	`, id).Scan(&n)
+	if err != nil {
+		log.Errorw("count booked delivery slots failed", "pickupPointID", id, "error", err)
+		return 0, pkgerrors.ErrInternal
+	}
+	return n, nil
+}
+
+// CountHeldOrders returns how many orders at the point have not been picked
+// up yet: those neither cancelled, refunded nor delivered.
+func (r *Repo) CountHeldOrders(ctx context.Context, id int64) (int64, error) {
+	log := logger.FromContext(ctx, r.log)
+
+	var n int64
+	err := r.db.QueryRow(ctx, `
+		SELECT COUNT(*) FROM orders o
+		WHERE o.pickup_point_id = $1
+		  AND (o.status IN ('pending_payment', 'paid', 'processing', 'shipped')
+		       OR (o.status = 'partially_refunded' AND NOT EXISTS (
+		           SELECT 1 FROM order_status_history h WHERE h.order_id = o.id AND h.to_status = 'delivered'
+		       )))
+	`, id).Scan(&n)
+	if err != nil {
+		log.Errorw("count pickup point orders failed", "pickupPointID", id, "error", err)
+		return 0, pkgerrors.ErrInternal
+	}
+	return n, nil
+}
+
+func (r *Repo) getByID(ctx context.Context, id int64, lock string) (*Point, error) {
+	log := logger.FromContext(ctx, r.log)
+
+	p, err := scanPoint(r.db.QueryRow(ctx, selectPoint+` WHERE id = $1`+lock, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, pkgerrors.ErrNotFound
+	}
+	if err != nil {
+		log.Errorw("get pickup point failed", "pickupPointID", id, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	return p, nil
+}
+
+func (r *Repo) List(ctx context.Context, f ListFilter) ([]*Point, error) {
+	log := logger.FromContext(ctx, r.log)
+
+	var (
+		rows pgx.Rows
+		err  error
+	)
+	where := ``
+	if f.ActiveOnly {
+		where = ` WHERE active`
+	}
+	byDistance := f.Latitude != nil && f.Longitude != nil
+	if byDistance {
+		rows, err = r.db.Query(ctx, `
+			SELECT id, name, address, latitude, longitude, opening_hours, capacity, active, created_at, updated_at,
+			       `+distance+` AS distance_km
+			FROM pickup_points`+where+`
+			ORDER BY distance_km, id
+		`, *f.Latitude, *f.Longitude)
+	} else {
+		rows, err = r.db.Query(ctx, selectPoint+where+` ORDER BY name, id`)
+	}
+	if err != nil {
+		log.Errorw("list pickup points failed", "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	defer rows.Close()
+
+	points := []*Point{}
+	for rows.Next() {
+		var (
+			p  *Point
+			km float64
+		)
+		if byDistance {
+			p, err = scanPoint(rows, &km)
+			if err == nil {
+				p.DistanceKm = &km
+			}
+		} else {
+			p, err = scanPoint(rows)
+		}
+		if err != nil {
+			log.Errorw("scan pickup point failed", "error", err)
+			return nil, pkgerrors.ErrInternal
+		}
+		points = append(points, p)
+	}
+	if err := rows.Err(); err != nil {
+		log.Errorw("iterate pickup points failed", "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	return points, nil
+}
+
+func (r *Repo) handlePgError(ctx context.Context, err error, op string) error {
+	log := logger.FromContext(ctx, r.log)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case pkgerrors.PGErrForeignKeyViolation:
+			return pkgerrors.ErrInvalidInput
+		case pkgerrors.PGErrUniqueViolation:
+			return pkgerrors.ErrAlreadyExists
+		}
+	}
+	log.Errorw(op+" failed", "error", err)
+	return pkgerrors.ErrInternal
+}
@@ -7,12 +7,14 @@ import (
 	"time"
 
 	pkgerrors "github.com/Cora23tt/order_service/pkg/errors"
+	"github.com/Cora23tt/order_service/pkg/logger"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 )
 
+// Product is a catalog item. VATRate is in basis points: 1200 is 12%.
 type Product struct {
 	ID            int64
 	Name          string
@@ -20,6 +22,7 @@ type Product struct {
 	ImageUrl      string
 	Price         int64
 	StockQuantity int64
+	VATRate       int64
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
 }
@@ -44,37 +47,44 @@ type TxExecutor interface {
 }
 
 func (r *Repo) CreateProduct(ctx context.Context, product *Product) error {
+	log := logger.FromContext(ctx, r.log)
+
 	query := `
-		INSERT INTO products (name, description, image_url, price, stock_quantity)
-		VALUES ($1, $2, $3, $4, $5)`
+		INSERT INTO products (name, description, image_url, price, stock_quantity, vat_rate)
+		VALUES ($1, $2, $3, $4, $5, $6)`
 	_, err := r.db.Exec(ctx, query,
 		product.Name,
 		product.Description,
 		product.ImageUrl,
 		product.Price,
 		product.StockQuantity,
+		product.VATRate,
 	)
-	r.log.Infow("product created", "name", product.Name)
+	log.Infow("product created", "name", product.Name)
 	return r.handlePgError("create product", err)
 }
 
 func (r *Repo) DeleteProduct(ctx context.Context, productID int64) error {
+	log := logger.FromContext(ctx, r.log)
+
 	cmd, err := r.db.Exec(ctx, `DELETE FROM products WHERE id = $1`, productID)
 	if err != nil {
-		r.log.Errorw("failed to delete product", "id", productID, "error", err)
+		log.Errorw("failed to delete product", "id", productID, "error", err)
 		return r.handlePgError("delete product", err)
 	}
 	if cmd.RowsAffected() == 0 {
-		r.log.Warnw("product not found for deletion", "id", productID)
+		log.Warnw("product not found for deletion", "id", productID)
 		return pkgerrors.ErrNotFound
 	}
-	r.log.Infow("product deleted", "id", productID)
+	log.Infow("product deleted", "id", productID)
 	return nil
 }
 
 func (r *Repo) GetProductByID(ctx context.Context, productID int64) (*Product, error) {
+	log := logger.FromContext(ctx, r.log)
+
 	query := `
-		SELECT id, name, description, image_url, price, stock_quantity, created_at, updated_at
+		SELECT id, name, description, image_url, price, stock_quantity, vat_rate, created_at, updated_at
 		FROM products WHERE id = $1`
 	row := r.db.QueryRow(ctx, query, productID)
 
@@ -86,27 +96,111 @@ func (r *Repo) GetProductByID(ctx context.Context, productID int64) (*Product, e
 		&product.ImageUrl,
 		&product.Price,
 		&product.StockQuantity,
+		&product.VATRate,
 		&product.CreatedAt,
 		&product.UpdatedAt,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			r.log.Warnw("product not found", "id", productID)
+			log.Warnw("product not found", "id", productID)
 			return nil, pkgerrors.ErrNotFound
 		}
-		r.log.Errorw("failed to get product", "id", productID, "error", err)
+		log.Errorw("failed to get product", "id", productID, "error", err)
 		return nil, r.handlePgError("get product by id", err)
 	}
 	return &product, nil
 }
 
+func (r *Repo) GetProductByIDForUpdate(ctx context.Context, productID int64) (*Product, error) {
+	log := logger.FromContext(ctx, r.log)
+
+	query := `
+		SELECT id, name, description, image_url, price, stock_quantity, vat_rate, created_at, updated_at
+		FROM products WHERE id = $1
+		FOR UPDATE`
+	row := r.db.QueryRow(ctx, query, productID)
+
+	var product Product
+	err := row.Scan(
+		&product.ID,
+		&product.Name,
+		&product.Description,
+		&product.ImageUrl,
+		&product.Price,
+		&product.StockQuantity,
+		&product.VATRate,
+		&product.CreatedAt,
+		&product.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.Warnw("product not found", "id", productID)
+			return nil, pkgerrors.ErrNotFound
+		}
+		log.Errorw("failed to lock product", "id", productID, "error", err)
+		return nil, r.handlePgError("get product for update", err)
+	}
+	return &product, nil
+}
+
+// DecreaseStock reserves quantity units of the product. The update is
+// conditional, so the stock can never go below zero even without a row lock.
+// quantity must be positive.
+func (r *Repo) DecreaseStock(ctx context.Context, productID, quantity int64) error {
+	log := logger.FromContext(ctx, r.log)
+
+	if quantity <= 0 {
+		log.Warnw("non-positive stock decrease", "id", productID, "quantity", quantity)
+		return pkgerrors.ErrInvalidInput
+	}
+
+	cmd, err := r.db.Exec(ctx, `
+		UPDATE products
+		SET stock_quantity = stock_quantity - $2, updated_at = NOW()
+		WHERE id = $1 AND stock_quantity >= $2`,
+		productID, quantity,
+	)
+	if err != nil {
+		log.Errorw("failed to decrease stock", "id", productID, "quantity", quantity, "error", err)
+		return r.handlePgError("decrease stock", err)
+	}
+	if cmd.RowsAffected() == 0 {
+		log.Warnw("insufficient stock for decrease", "id", productID, "quantity", quantity)
+		return pkgerrors.ErrInsufficientStock
+	}
+	return nil
+}
+
+// IncreaseStock returns quantity units of the product back to the stock.
+func (r *Repo) IncreaseStock(ctx context.Context, productID, quantity int64) error {
+	log := logger.FromContext(ctx, r.log)
+
+	cmd, err := r.db.Exec(ctx, `
+		UPDATE products
+		SET stock_quantity = stock_quantity + $2, updated_at = NOW()
+		WHERE id = $1`,
+		productID, quantity,
+	)
+	if err != nil {
+		log.Errorw("failed to increase stock", "id", productID, "quantity", quantity, "error", err)
+		return r.handlePgError("increase stock", err)
+	}
+	if cmd.RowsAffected() == 0 {
+		log.Warnw("product not found for stock increase", "id", productID)
+		return pkgerrors.ErrNotFound
+	}
+	return nil
+}
+
 func (r *Repo) GetProducts(ctx context.Context) ([]*Product, error) {
+	log := logger.FromContext(ctx, r.log)
+
 	query := `
-		SELECT id, name, description, image_url, price, stock_quantity, created_at, updated_at
+		SELECT id, name, description, image_url, price, stock_quantity, vat_rate, created_at, updated_at
 		FROM products ORDER BY created_at DESC LIMIT 100`
 	rows, err := r.db.Query(ctx, query)
 	if err != nil {
-		r.log.Errorw("failed to get products", "error", err)
+		log.Errorw("failed to get products", "error", err)
 		return nil, r.handlePgError("get products", err)
 	}
 	defer rows.Close()
@@ -117,41 +211,44 @@ func (r *Repo) GetProducts(ctx context.Context) ([]*Product, error) {
 		if err := rows.Scan(
 			&product.ID, &product.Name, &product.Description,
 			&product.ImageUrl, &product.Price, &product.StockQuantity,
-			&product.CreatedAt, &product.UpdatedAt,
+			&product.VATRate, &product.CreatedAt, &product.UpdatedAt,
 		); err != nil {
-			r.log.Errorw("failed to scan product", "error", err)
+			log.Errorw("failed to scan product", "error", err)
 			return nil, r.handlePgError("scan product", err)
 		}
 		products = append(products, &product)
 	}
 	if err := rows.Err(); err != nil {
-		r.log.Errorw("rows iteration failed", "error", err)
+		log.Errorw("rows iteration failed", "error", err)
 		return nil, r.handlePgError("rows iteration", err)
 	}
 	return products, nil
 }
 
 func (r *Repo) UpdateProduct(ctx context.Context, product *Product) error {
+	log := logger.FromContext(ctx, r.log)
+
 	cmd, err := r.db.Exec(ctx, `
 		UPDATE products
-		SET name = $1, description = $2, image_url = $3, price = $4, stock_quantity = $5, updated_at = NOW()
-		WHERE id = $6`,
+		SET name = $1, description = $2, image_url = $3, price = $4, stock_quantity = $5, vat_rate = $6, updated_at = NOW()
+		WHERE id = $7`,
 		product.Name,
 		product.Description,
 		product.ImageUrl,
 		product.Price,
 		product.StockQuantity,
+		product.VATRate,
 		product.ID,
 	)
 	if err != nil {
-		r.log.Errorw("failed to update product", "id", product.ID, "error", err)
+		log.Errorw("failed to update product", "id", product.ID, "error", err)
 		return r.handlePgError("update product", err)
 	}
 	if cmd.RowsAffected() == 0 {
-		r.log.Warnw("product not found for update", "id", product.ID)
+		log.Warnw("product not found for update", "id", product.ID)
 		return pkgerrors.ErrNotFound
 	}
-	r.log.Infow("product updated", "id", product.ID)
+	log.Infow("product updated", "id", product.ID)
 	return nil
 }
 
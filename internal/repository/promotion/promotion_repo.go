@@ -0,0 +1,240 @@
+package promotion
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Cora23tt/order_service/pkg/enums"
+	pkgerrors "github.com/Cora23tt/order_service/pkg/errors"
+	"github.com/Cora23tt/order_service/pkg/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type Repo struct {
+	db  TxExecutor
+	log *zap.SugaredLogger
+}
+
+func NewRepo(db *pgxpool.Pool, log *zap.SugaredLogger) *Repo {
+	return &Repo{db: db, log: log}
+}
+
+func NewWithTx(tx pgx.Tx, log *zap.SugaredLogger) *Repo {
+	return &Repo{db: tx, log: log}
+}
+
+type TxExecutor interface {
+	Exec(context.Context, string, ...any) (pgconn.CommandTag, error)
+	Query(context.Context, string, ...any) (pgx.Rows, error)
+	QueryRow(context.Context, string, ...any) pgx.Row
+}
+
+// Promotion is a promo code. Empty ProductIDs means the code applies to every
+// product. TimesUsed counts the orders the code was applied to, not counting
+// cancelled ones.
+type Promotion struct {
+	ID             int64              `json:"id" example:"1"`
+	Code           string             `json:"code" example:"SPRING10"`
+	Description    string             `json:"description" example:"Весенняя скидка"`
+	DiscountType   enums.DiscountType `json:"discount_type" enums:"percent,fixed" example:"percent"`
+	DiscountValue  int64              `json:"discount_value" example:"10"`
+	MinOrderAmount int64              `json:"min_order_amount" example:"50000"`
+	StartsAt       *time.Time         `json:"starts_at,omitempty"`
+	EndsAt         *time.Time         `json:"ends_at,omitempty"`
+	UsageLimit     *int64             `json:"usage_limit,omitempty" example:"1000"`
+	PerUserLimit   *int64             `json:"per_user_limit,omitempty" example:"1"`
+	Active         bool               `json:"active" example:"true"`
+	ProductIDs     []int64            `json:"product_ids"`
+	TimesUsed      int64              `json:"times_used" example:"42"`
+	CreatedAt      time.Time          `json:"created_at"`
+	UpdatedAt      time.Time          `json:"updated_at"`
+}
+
+const selectPromotion = `
+	SELECT p.id, p.code, p.description, p.discount_type, p.discount_value, p.min_order_amount,
+	       p.starts_at, p.ends_at, p.usage_limit, p.per_user_limit, p.active,
+	       COALESCE((SELECT array_agg(pp.product_id ORDER BY pp.product_id)
+	                 FROM promotion_products pp WHERE pp.promotion_id = p.id), '{}'),
+	       (SELECT COUNT(*) FROM order_discounts d JOIN orders o ON o.id = d.order_id
+	        WHERE d.promotion_id = p.id AND o.status <> 'cancelled'),
+	       p.created_at, p.updated_at
+	FROM promotions p`
+
+func scanPromotion(row pgx.Row) (*Promotion, error) {
+	var p Promotion
+	err := row.Scan(&p.ID, &p.Code, &p.Description, &p.DiscountType, &p.DiscountValue, &p.MinOrderAmount,
+		&p.StartsAt, &p.EndsAt, &p.UsageLimit, &p.PerUserLimit, &p.Active,
+		&p.ProductIDs, &p.TimesUsed, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Create stores the promotion with its product restrictions and sets its ID.
+// It must run in a transaction.
+func (r *Repo) Create(ctx context.Context, p *Promotion) error {
+	log := logger.FromContext(ctx, r.log)
+
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO promotions (code, description, discount_type, discount_value, min_order_amount,
+		                        starts_at, ends_at, usage_limit, per_user_limit, active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id
+	`, p.Code, p.Description, p.DiscountType, p.DiscountValue, p.MinOrderAmount,
+		p.StartsAt, p.EndsAt, p.UsageLimit, p.PerUserLimit, p.Active).Scan(&p.ID)
+	if err != nil {
+		return r.handlePgError(ctx, err, "create promotion")
+	}
+
+	if err := r.setProducts(ctx, p.ID, p.ProductIDs); err != nil {
+		return err
+	}
+	log.Infow("promotion created", "promotionID", p.ID, "code", p.Code)
+	return nil
+}
+
+// Update replaces the promotion and its product restrictions. It must run in
+// a transaction.
+func (r *Repo) Update(ctx context.Context, p *Promotion) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE promotions
+		SET code = $2, description = $3, discount_type = $4, discount_value = $5, min_order_amount = $6,
+		    starts_at = $7, ends_at = $8, usage_limit = $9, per_user_limit = $10, active = $11,
+		    updated_at = NOW()
+		WHERE id = $1
+	`, p.ID, p.Code, p.Description, p.DiscountType, p.DiscountValue, p.MinOrderAmount,
+		p.StartsAt, p.EndsAt, p.UsageLimit, p.PerUserLimit, p.Active)
+	if err != nil {
+		return r.handlePgError(ctx, err, "update promotion")
+	}
+	if tag.RowsAffected() == 0 {
+		return pkgerrors.ErrNotFound
+	}
+
+	if _, err := r.db.Exec(ctx, `DELETE FROM promotion_products WHERE promotion_id = $1`, p.ID); err != nil {
+		return r.handlePgError(ctx, err, "clear promotion products")
+	}
+	return r.setProducts(ctx, p.ID, p.ProductIDs)
+}
+
+func (r *Repo) setProducts(ctx context.Context, promotionID int64, productIDs []int64) error {
+	if len(productIDs) == 0 {
+		return nil
+	}
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO promotion_products (promotion_id, product_id)
+		SELECT $1, UNNEST($2::INTEGER[])
+		ON CONFLICT DO NOTHING
+	`, promotionID, productIDs)
+	if err != nil {
+		return r.handlePgError(ctx, err, "set promotion products")
+	}
+	return nil
+}
+
+func (r *Repo) Delete(ctx context.Context, id int64) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM promotions WHERE id = $1`, id)
+	if err != nil {
+		return r.handlePgError(ctx, err, "delete promotion")
+	}
+	if tag.RowsAffected() == 0 {
+		return pkgerrors.ErrNotFound
+	}
+	return nil
+}
+
+func (r *Repo) GetByID(ctx context.Context, id int64) (*Promotion, error) {
+	log := logger.FromContext(ctx, r.log)
+
+	p, err := scanPromotion(r.db.QueryRow(ctx, selectPromotion+` WHERE p.id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, pkgerrors.ErrNotFound
+	}
+	if err != nil {
+		log.Errorw("get promotion failed", "promotionID", id, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	return p, nil
+}
+
+// GetByCodeForUpdate loads the promotion and locks its row until the end of
+// the transaction, so concurrent orders cannot redeem it past its limits.
+func (r *Repo) GetByCodeForUpdate(ctx context.Context, code string) (*Promotion, error) {
+	log := logger.FromContext(ctx, r.log)
+
+	p, err := scanPromotion(r.db.QueryRow(ctx, selectPromotion+` WHERE p.code = $1 FOR UPDATE OF p`, code))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, pkgerrors.ErrNotFound
+	}
+	if err != nil {
+		log.Errorw("lock promotion failed", "code", code, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	return p, nil
+}
+
+func (r *Repo) List(ctx context.Context) ([]*Promotion, error) {
+	log := logger.FromContext(ctx, r.log)
+
+	rows, err := r.db.Query(ctx, selectPromotion+` ORDER BY p.created_at DESC, p.id DESC`)
+	if err != nil {
+		log.Errorw("list promotions failed", "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	defer rows.Close()
+
+	promotions := []*Promotion{}
+	for rows.Next() {
+		p, err := scanPromotion(rows)
+		if err != nil {
+			log.Errorw("scan promotion failed", "error", err)
+			return nil, pkgerrors.ErrInternal
+		}
+		promotions = append(promotions, p)
+	}
+	if err := rows.Err(); err != nil {
+		log.Errorw("iterate promotions failed", "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	return promotions, nil
+}
+
+// CountRedemptions returns how many orders, not counting cancelled ones, the
+// promotion was applied to: in total and of the user. Call it after locking
+// the promotion, so that the counts include orders committed meanwhile.
+func (r *Repo) CountRedemptions(ctx context.Context, promotionID, userID int64) (total, byUser int64, err error) {
+	log := logger.FromContext(ctx, r.log)
+
+	err = r.db.QueryRow(ctx, `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE o.user_id = $2)
+		FROM order_discounts d
+		JOIN orders o ON o.id = d.order_id
+		WHERE d.promotion_id = $1 AND o.status <> 'cancelled'
+	`, promotionID, userID).Scan(&total, &byUser)
+	if err != nil {
+		log.Errorw("count promotion redemptions failed", "promotionID", promotionID, "userID", userID, "error", err)
+		return 0, 0, pkgerrors.ErrInternal
+	}
+	return total, byUser, nil
+}
+
+func (r *Repo) handlePgError(ctx context.Context, err error, op string) error {
+	log := logger.FromContext(ctx, r.log)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case pkgerrors.PGErrForeignKeyViolation:
+			return pkgerrors.ErrInvalidInput
+		case pkgerrors.PGErrUniqueViolation:
+			return pkgerrors.ErrAlreadyExists
+		}
+	}
+	log.Errorw(op+" failed", "error", err)
+	return pkgerrors.ErrInternal
+}
@@ -0,0 +1,424 @@
+package returns
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/Cora23tt/order_service/pkg/enums"
+	pkgerrors "github.com/Cora23tt/order_service/pkg/errors"
+	"github.com/Cora23tt/order_service/pkg/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type Repo struct {
+	db  TxExecutor
+	log *zap.SugaredLogger
+}
+
+func NewRepo(db *pgxpool.Pool, log *zap.SugaredLogger) *Repo {
+	return &Repo{db: db, log: log}
+}
+
+func NewWithTx(tx pgx.Tx, log *zap.SugaredLogger) *Repo {
+	return &Repo{db: tx, log: log}
+}
+
+type TxExecutor interface {
+	Exec(context.Context, string, ...any) (pgconn.CommandTag, error)
+	Query(context.Context, string, ...any) (pgx.Rows, error)
+	QueryRow(context.Context, string, ...any) pgx.Row
+}
+
+// Return is a customer's request to return items of a delivered order. Once
+// settled, RefundID or ReplacementOrderID tells how.
+type Return struct {
+	ID                 int64              `json:"id" example:"1"`
+	OrderID            int64              `json:"order_id" example:"15"`
+	UserID             int64              `json:"user_id" example:"3"`
+	Status             enums.ReturnStatus `json:"status" enums:"requested,approved,rejected,received,refunded,replaced" example:"requested"`
+	Reason             string             `json:"reason" example:"товар повреждён"`
+	RefundID           *int64             `json:"refund_id,omitempty" example:"4"`
+	ReplacementOrderID *int64             `json:"replacement_order_id,omitempty" example:"27"`
+	CreatedAt          time.Time          `json:"created_at"`
+	UpdatedAt          time.Time          `json:"updated_at"`
+	Items              []Item             `json:"items"`
+	Photos             []Photo            `json:"photos,omitempty"`
+	History            []StatusChange     `json:"history,omitempty"`
+}
+
+// Item is a returned quantity of an order line.
+type Item struct {
+	OrderItemID int64 `json:"order_item_id" example:"31"`
+	ProductID   int64 `json:"product_id" example:"4"`
+	Quantity    int64 `json:"quantity" example:"1"`
+}
+
+// Photo is a picture of the returned goods. Path is relative to the upload
+// directory; clients download the photo from URL.
+type Photo struct {
+	ID        int64     `json:"id" example:"2"`
+	Path      string    `json:"-"`
+	URL       string    `json:"url" example:"/api/v1/returns/1/photos/2"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// StatusChange is one entry of the return timeline. FromStatus is empty for
+// the entry written when the return is requested.
+type StatusChange struct {
+	ID          int64               `json:"id"`
+	ReturnID    int64               `json:"return_id"`
+	FromStatus  *enums.ReturnStatus `json:"from_status,omitempty"`
+	ToStatus    enums.ReturnStatus  `json:"to_status"`
+	ActorUserID *int64              `json:"actor_user_id,omitempty"`
+	ActorRole   string              `json:"actor_role"`
+	Reason      *string             `json:"reason,omitempty"`
+	CreatedAt   time.Time           `json:"created_at"`
+}
+
+type ListFilter struct {
+	UserID  *int64
+	OrderID *int64
+	Status  *enums.ReturnStatus
+}
+
+const selectReturn = `
+	SELECT id, order_id, user_id, status, reason, refund_id, replacement_order_id, created_at, updated_at
+	FROM returns`
+
+func scanReturn(row pgx.Row) (*Return, error) {
+	var r Return
+	err := row.Scan(&r.ID, &r.OrderID, &r.UserID, &r.Status, &r.Reason, &r.RefundID, &r.ReplacementOrderID, &r.CreatedAt, &r.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Create stores the return with its items and sets its ID, status and
+// timestamps. It must run in a transaction.
+func (r *Repo) Create(ctx context.Context, ret *Return) error {
+	log := logger.FromContext(ctx, r.log)
+
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO returns (order_id, user_id, reason)
+		VALUES ($1, $2, $3)
+		RETURNING id, status, created_at, updated_at
+	`, ret.OrderID, ret.UserID, ret.Reason).Scan(&ret.ID, &ret.Status, &ret.CreatedAt, &ret.UpdatedAt)
+	if err != nil {
+		return r.handlePgError(ctx, err, "create return")
+	}
+
+	for _, item := range ret.Items {
+		_, err := r.db.Exec(ctx, `
+			INSERT INTO return_items (return_id, order_item_id, quantity)
+			VALUES ($1, $2, $3)
+		`, ret.ID, item.OrderItemID, item.Quantity)
+		if err != nil {
+			return r.handlePgError(ctx, err, "add return item")
+		}
+	}
+
+	log.Infow("return created", "return_id", ret.ID, "order_id", ret.OrderID)
+	return nil
+}
+
+// GetByID returns the return with its items, photos and history.
+func (r *Repo) GetByID(ctx context.Context, id int64) (*Return, error) {
+	return r.getByID(ctx, id, false)
+}
+
+// GetByIDForUpdate loads the return and locks its row until the end of the
+// transaction.
+func (r *Repo) GetByIDForUpdate(ctx context.Context, id int64) (*Return, error) {
+	return r.getByID(ctx, id, true)
+}
+
+func (r *Repo) getByID(ctx context.Context, id int64, forUpdate bool) (*Return, error) {
+	log := logger.FromContext(ctx, r.log)
+
+	query := selectReturn + ` WHERE id = $1`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+	ret, err := scanReturn(r.db.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, pkgerrors.ErrNotFound
+	}
+	if err != nil {
+		log.Errorw("get return failed", "return_id", id, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+
+	items, err := r.itemsByReturn(ctx, []int64{id})
+	if err != nil {
+		return nil, err
+	}
+	ret.Items = items[id]
+
+	if ret.Photos, err = r.photos(ctx, id); err != nil {
+		return nil, err
+	}
+	if ret.History, err = r.history(ctx, id); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// List returns the returns matching the filter, newest first, with their
+// items.
+func (r *Repo) List(ctx context.Context, f ListFilter) ([]*Return, error) {
+	log := logger.FromContext(ctx, r.log)
+
+	var (
+		query  = selectReturn + ` WHERE 1=1`
+		params []any
+	)
+	if f.UserID != nil {
+		params = append(params, *f.UserID)
+		query += ` AND user_id = $` + strconv.Itoa(len(params))
+	}
+	if f.OrderID != nil {
+		params = append(params, *f.OrderID)
+		query += ` AND order_id = $` + strconv.Itoa(len(params))
+	}
+	if f.Status != nil {
+		params = append(params, *f.Status)
+		query += ` AND status = $` + strconv.Itoa(len(params))
+	}
+	query += ` ORDER BY created_at DESC, id DESC`
+
+	rows, err := r.db.Query(ctx, query, params...)
+	if err != nil {
+		log.Errorw("list returns failed", "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	defer rows.Close()
+
+	returns := []*Return{}
+	var ids []int64
+	for rows.Next() {
+		ret, err := scanReturn(rows)
+		if err != nil {
+			log.Errorw("scan return failed", "error", err)
+			return nil, pkgerrors.ErrInternal
+		}
+		returns = append(returns, ret)
+		ids = append(ids, ret.ID)
+	}
+	if err := rows.Err(); err != nil {
+		log.Errorw("iterate returns failed", "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	rows.Close()
+
+	if len(ids) == 0 {
+		return returns, nil
+	}
+	items, err := r.itemsByReturn(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, ret := range returns {
+		ret.Items = items[ret.ID]
+	}
+	return returns, nil
+}
+
+// itemsByReturn returns the items of the returns keyed by return ID.
+func (r *Repo) itemsByReturn(ctx context.Context, ids []int64) (map[int64][]Item, error) {
+	log := logger.FromContext(ctx, r.log)
+
+	rows, err := r.db.Query(ctx, `
+		SELECT ri.return_id, ri.order_item_id, oi.product_id, ri.quantity
+		FROM return_items ri
+		JOIN order_items oi ON oi.id = ri.order_item_id
+		WHERE ri.return_id = ANY($1)
+		ORDER BY ri.return_id, ri.order_item_id
+	`, ids)
+	if err != nil {
+		log.Errorw("get return items failed", "return_ids", ids, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	defer rows.Close()
+
+	items := make(map[int64][]Item, len(ids))
+	for rows.Next() {
+		var returnID int64
+		var item Item
+		if err := rows.Scan(&returnID, &item.OrderItemID, &item.ProductID, &item.Quantity); err != nil {
+			log.Errorw("scan return item failed", "return_id", returnID, "error", err)
+			return nil, pkgerrors.ErrInternal
+		}
+		items[returnID] = append(items[returnID], item)
+	}
+	if err := rows.Err(); err != nil {
+		log.Errorw("iterate return items failed", "return_ids", ids, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	return items, nil
+}
+
+func (r *Repo) photos(ctx context.Context, returnID int64) ([]Photo, error) {
+	log := logger.FromContext(ctx, r.log)
+
+	rows, err := r.db.Query(ctx, `
+		SELECT id, path, created_at FROM return_photos WHERE return_id = $1 ORDER BY id
+	`, returnID)
+	if err != nil {
+		log.Errorw("get return photos failed", "return_id", returnID, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	defer rows.Close()
+
+	var photos []Photo
+	for rows.Next() {
+		var p Photo
+		if err := rows.Scan(&p.ID, &p.Path, &p.CreatedAt); err != nil {
+			log.Errorw("scan return photo failed", "return_id", returnID, "error", err)
+			return nil, pkgerrors.ErrInternal
+		}
+		photos = append(photos, p)
+	}
+	if err := rows.Err(); err != nil {
+		log.Errorw("iterate return photos failed", "return_id", returnID, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	return photos, nil
+}
+
+func (r *Repo) history(ctx context.Context, returnID int64) ([]StatusChange, error) {
+	log := logger.FromContext(ctx, r.log)
+
+	rows, err := r.db.Query(ctx, `
+		SELECT id, return_id, from_status, to_status, actor_user_id, actor_role, reason, created_at
+		FROM return_status_history WHERE return_id = $1 ORDER BY created_at, id
+	`, returnID)
+	if err != nil {
+		log.Errorw("get return history failed", "return_id", returnID, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	defer rows.Close()
+
+	var history []StatusChange
+	for rows.Next() {
+		var c StatusChange
+		if err := rows.Scan(&c.ID, &c.ReturnID, &c.FromStatus, &c.ToStatus, &c.ActorUserID, &c.ActorRole, &c.Reason, &c.CreatedAt); err != nil {
+			log.Errorw("scan return status change failed", "return_id", returnID, "error", err)
+			return nil, pkgerrors.ErrInternal
+		}
+		history = append(history, c)
+	}
+	if err := rows.Err(); err != nil {
+		log.Errorw("iterate return history failed", "return_id", returnID, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	return history, nil
+}
+
+// HeldQuantities returns, keyed by order line, the quantities of the order
+// that are part of returns still in progress or settled by a replacement.
+// Rejected returns give their items back and refunded ones are counted as
+// refunded quantities of the order.
+func (r *Repo) HeldQuantities(ctx context.Context, orderID int64) (map[int64]int64, error) {
+	log := logger.FromContext(ctx, r.log)
+
+	rows, err := r.db.Query(ctx, `
+		SELECT ri.order_item_id, SUM(ri.quantity)
+		FROM return_items ri
+		JOIN returns rt ON rt.id = ri.return_id
+		WHERE rt.order_id = $1 AND rt.status NOT IN ('rejected', 'refunded')
+		GROUP BY ri.order_item_id
+	`, orderID)
+	if err != nil {
+		log.Errorw("get held return quantities failed", "order_id", orderID, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	defer rows.Close()
+
+	held := make(map[int64]int64)
+	for rows.Next() {
+		var itemID, quantity int64
+		if err := rows.Scan(&itemID, &quantity); err != nil {
+			log.Errorw("scan held return quantity failed", "order_id", orderID, "error", err)
+			return nil, pkgerrors.ErrInternal
+		}
+		held[itemID] = quantity
+	}
+	if err := rows.Err(); err != nil {
+		log.Errorw("iterate held return quantities failed", "order_id", orderID, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	return held, nil
+}
+
+// UpdateStatus moves the return from one status to another and reports
+// whether it was in the from status.
+func (r *Repo) UpdateStatus(ctx context.Context, id int64, from, to enums.ReturnStatus) (bool, error) {
+	log := logger.FromContext(ctx, r.log)
+
+	tag, err := r.db.Exec(ctx, `
+		UPDATE returns SET status = $3, updated_at = NOW() WHERE id = $1 AND status = $2
+	`, id, from, to)
+	if err != nil {
+		log.Errorw("update return status failed", "return_id", id, "from", from, "to", to, "error", err)
+		return false, pkgerrors.ErrInternal
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// SetSettlement records the refund or the replacement order the return was
+// settled with.
+func (r *Repo) SetSettlement(ctx context.Context, id int64, refundID, replacementOrderID *int64) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE returns SET refund_id = $2, replacement_order_id = $3, updated_at = NOW() WHERE id = $1
+	`, id, refundID, replacementOrderID)
+	if err != nil {
+		return r.handlePgError(ctx, err, "set return settlement")
+	}
+	return nil
+}
+
+func (r *Repo) AddStatusChange(ctx context.Context, c *StatusChange) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO return_status_history (return_id, from_status, to_status, actor_user_id, actor_role, reason)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, c.ReturnID, c.FromStatus, c.ToStatus, c.ActorUserID, c.ActorRole, c.Reason)
+	if err != nil {
+		return r.handlePgError(ctx, err, "add return status change")
+	}
+	return nil
+}
+
+// AddPhoto stores the photo and sets its ID and creation time.
+func (r *Repo) AddPhoto(ctx context.Context, returnID int64, p *Photo) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO return_photos (return_id, path)
+		VALUES ($1, $2)
+		RETURNING id, created_at
+	`, returnID, p.Path).Scan(&p.ID, &p.CreatedAt)
+	if err != nil {
+		return r.handlePgError(ctx, err, "add return photo")
+	}
+	return nil
+}
+
+func (r *Repo) handlePgError(ctx context.Context, err error, op string) error {
+	log := logger.FromContext(ctx, r.log)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case pkgerrors.PGErrForeignKeyViolation, pkgerrors.PGErrInvalidTextRep, pkgerrors.PGErrInvalidType:
+			return pkgerrors.ErrInvalidInput
+		case pkgerrors.PGErrUniqueViolation:
+			return pkgerrors.ErrAlreadyExists
+		}
+	}
+	log.Errorw(op+" failed", "error", err)
+	return pkgerrors.ErrInternal
+}
@@ -23,6 +23,7 @@ type User struct {
 	PINFL        *string
 	Role         string
 	AvatarURL    *string
+	Language     *string
 	PasswordHash string
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
@@ -31,10 +32,10 @@ type User struct {
 func (r *Repo) GetByID(ctx context.Context, id int64) (*User, error) {
 	var u User
 	err := r.db.QueryRow(ctx, `
-		SELECT id, phone_number, pinfl, role, avatar_url, password_hash, created_at, updated_at
+		SELECT id, phone_number, pinfl, role, avatar_url, language, password_hash, created_at, updated_at
 		FROM users
 		WHERE id = $1
-	`, id).Scan(&u.ID, &u.PhoneNumber, &u.PINFL, &u.Role, &u.AvatarURL, &u.PasswordHash, &u.CreatedAt, &u.UpdatedAt)
+	`, id).Scan(&u.ID, &u.PhoneNumber, &u.PINFL, &u.Role, &u.AvatarURL, &u.Language, &u.PasswordHash, &u.CreatedAt, &u.UpdatedAt)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, errors.ErrNotFound
@@ -47,9 +48,9 @@ func (r *Repo) GetByID(ctx context.Context, id int64) (*User, error) {
 func (r *Repo) Update(ctx context.Context, u *User) error {
 	_, err := r.db.Exec(ctx, `
 		UPDATE users
-		SET pinfl = $1, avatar_url = $2, updated_at = NOW()
-		WHERE id = $3
-	`, u.PINFL, u.AvatarURL, u.ID)
+		SET pinfl = $1, avatar_url = $2, language = $3, updated_at = NOW()
+		WHERE id = $4
+	`, u.PINFL, u.AvatarURL, u.Language, u.ID)
 	if err != nil {
 		return err
 	}
@@ -58,7 +59,7 @@ func (r *Repo) Update(ctx context.Context, u *User) error {
 
 func (r *Repo) ListAll(ctx context.Context) ([]User, error) {
 	rows, err := r.db.Query(ctx, `
-		SELECT id, phone_number, pinfl, role, avatar_url, password_hash, created_at, updated_at
+		SELECT id, phone_number, pinfl, role, avatar_url, language, password_hash, created_at, updated_at
 		FROM users
 	`)
 	if err != nil {
@@ -69,7 +70,7 @@ func (r *Repo) ListAll(ctx context.Context) ([]User, error) {
 	var users []User
 	for rows.Next() {
 		var u User
-		err := rows.Scan(&u.ID, &u.PhoneNumber, &u.PINFL, &u.Role, &u.AvatarURL, &u.PasswordHash, &u.CreatedAt, &u.UpdatedAt)
+		err := rows.Scan(&u.ID, &u.PhoneNumber, &u.PINFL, &u.Role, &u.AvatarURL, &u.Language, &u.PasswordHash, &u.CreatedAt, &u.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -3,6 +3,7 @@ package auth
 import (
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/Cora23tt/order_service/internal/usecase/auth"
 	pkgerrors "github.com/Cora23tt/order_service/pkg/errors"
@@ -32,58 +33,180 @@ type Credentials struct {
 // @Produce json
 // @Param credentials body Credentials true "Данные пользователя"
 // @Success 200 {object} map[string]int64 "ID нового пользователя"
-// @Failure 400 {string} string "Invalid request body"
-// @Failure 409 {string} string "A user with this phone number already exists"
-// @Failure 500 {string} string "Internal server error"
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 409 {object} errors.Problem "user_exists"
+// @Failure 500 {object} errors.Problem "internal_error"
 // @Router /api/v1/auth/signup [post]
 func (h *Handler) SignUp(c *gin.Context) {
 	var creds Credentials
 	if err := c.ShouldBindJSON(&creds); err != nil {
-		http.Error(c.Writer, "Invalid request body", http.StatusBadRequest)
+		_ = c.Error(pkgerrors.Validation(err))
 		return
 	}
 
 	userID, err := h.service.CreateUser(c.Request.Context(), creds.PhoneNumber, creds.Password)
 	if err != nil {
-		switch {
-		case errors.Is(err, pkgerrors.ErrAlreadyExists):
-			http.Error(c.Writer, "A user with this phone number already exists", http.StatusConflict)
-		default:
-			http.Error(c.Writer, "Internal server error", http.StatusInternalServerError)
-		}
+		_ = c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"id": userID})
 }
 
+type TokenResponse struct {
+	Token        string    `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	RefreshToken string    `json:"refresh_token" example:"q1z8Yc2Lh0m4r7VbN3kT9wXe5sUa6dGf1JpQ2oR8yHc"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required" example:"q1z8Yc2Lh0m4r7VbN3kT9wXe5sUa6dGf1JpQ2oR8yHc"`
+}
+
 // SignIn godoc
 // @Summary Вход пользователя
-// @Description Аутентифицирует пользователя и выдает JWT токен
+// @Description Аутентифицирует пользователя и выдает короткоживущий JWT и refresh-токен
 // @Tags Auth
 // @Accept json
 // @Produce json
 // @Param credentials body Credentials true "Телефон и пароль"
-// @Success 200 {object} map[string]string "JWT токен"
-// @Failure 400 {string} string "Invalid request body"
-// @Failure 500 {string} string "Error initialising session token"
+// @Success 200 {object} TokenResponse "JWT и refresh-токен"
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 401 {object} errors.Problem "invalid_credentials"
+// @Failure 500 {object} errors.Problem "internal_error"
 // @Router /api/v1/auth/signin [post]
 func (h *Handler) SignIn(c *gin.Context) {
 	var creds Credentials
 	if err := c.ShouldBindJSON(&creds); err != nil {
-		http.Error(c.Writer, "Invalid request body", http.StatusBadRequest)
+		_ = c.Error(pkgerrors.Validation(err))
+		return
+	}
+
+	tokens, err := h.service.SignIn(c.Request.Context(), creds.PhoneNumber, creds.Password, clientInfo(c))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokenResponse(tokens))
+}
+
+// Refresh godoc
+// @Summary Обновление токенов
+// @Description Обменивает refresh-токен на новую пару токенов. Старый refresh-токен становится недействительным, а его повторное использование завершает всю сессию
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body RefreshRequest true "Refresh-токен"
+// @Success 200 {object} TokenResponse "Новая пара токенов"
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 401 {object} errors.Problem "unauthorized"
+// @Failure 500 {object} errors.Problem "internal_error"
+// @Router /api/v1/auth/refresh [post]
+func (h *Handler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(pkgerrors.Validation(err))
+		return
+	}
+
+	tokens, err := h.service.Refresh(c.Request.Context(), req.RefreshToken, clientInfo(c))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokenResponse(tokens))
+}
+
+// Logout godoc
+// @Summary Выход из текущей сессии
+// @Description Отзывает сессию, к которой относится токен запроса
+// @Tags Auth
+// @Security BearerAuth
+// @Success 204 "No Content"
+// @Failure 401 {object} errors.Problem "unauthorized"
+// @Failure 500 {object} errors.Problem "internal_error"
+// @Router /api/v1/auth/logout [post]
+func (h *Handler) Logout(c *gin.Context) {
+	userID := c.GetInt64("userID")
+	sessionID := c.GetString("sessionID")
+
+	// Logging out of a session that is already gone is not an error.
+	if err := h.service.Logout(c.Request.Context(), userID, sessionID); err != nil && !errors.Is(err, pkgerrors.ErrNotFound) {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// LogoutAll godoc
+// @Summary Выход со всех устройств
+// @Description Отзывает все сессии текущего пользователя
+// @Tags Auth
+// @Security BearerAuth
+// @Success 204 "No Content"
+// @Failure 401 {object} errors.Problem "unauthorized"
+// @Failure 500 {object} errors.Problem "internal_error"
+// @Router /api/v1/auth/logout-all [post]
+func (h *Handler) LogoutAll(c *gin.Context) {
+	if err := h.service.LogoutAll(c.Request.Context(), c.GetInt64("userID")); err != nil {
+		_ = c.Error(err)
 		return
 	}
 
-	token, err := h.service.GenerateJWT(c.Request.Context(), creds.PhoneNumber, creds.Password)
+	c.Status(http.StatusNoContent)
+}
+
+// ListSessions godoc
+// @Summary Активные сессии
+// @Description Возвращает список активных сессий текущего пользователя
+// @Tags Auth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} auth.ActiveSession
+// @Failure 401 {object} errors.Problem "unauthorized"
+// @Failure 500 {object} errors.Problem "internal_error"
+// @Router /api/v1/me/sessions [get]
+func (h *Handler) ListSessions(c *gin.Context) {
+	sessions, err := h.service.ListSessions(c.Request.Context(), c.GetInt64("userID"), c.GetString("sessionID"))
 	if err != nil {
-		if errors.Is(err, pkgerrors.ErrNotFound) || errors.Is(err, pkgerrors.ErrInvalidCredentials) {
-			http.Error(c.Writer, "Invalid phone number or password", http.StatusUnauthorized)
-			return
-		}
-		http.Error(c.Writer, "Error initialising session token", http.StatusInternalServerError)
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeSession godoc
+// @Summary Завершение сессии
+// @Description Отзывает одну из сессий текущего пользователя
+// @Tags Auth
+// @Security BearerAuth
+// @Param id path string true "ID сессии"
+// @Success 204 "No Content"
+// @Failure 401 {object} errors.Problem "unauthorized"
+// @Failure 404 {object} errors.Problem "session_not_found"
+// @Failure 500 {object} errors.Problem "internal_error"
+// @Router /api/v1/me/sessions/{id} [delete]
+func (h *Handler) RevokeSession(c *gin.Context) {
+	if err := h.service.Logout(c.Request.Context(), c.GetInt64("userID"), c.Param("id")); err != nil {
+		_ = c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"token": token})
+	c.Status(http.StatusNoContent)
+}
+
+func clientInfo(c *gin.Context) auth.ClientInfo {
+	return auth.ClientInfo{UserAgent: c.Request.UserAgent(), IPAddress: c.ClientIP()}
+}
+
+func tokenResponse(tokens *auth.TokenPair) TokenResponse {
+	return TokenResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresAt:    tokens.ExpiresAt,
+	}
 }
@@ -0,0 +1,168 @@
+package cart
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Cora23tt/order_service/internal/usecase/cart"
+	pkgerrors "github.com/Cora23tt/order_service/pkg/errors"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type Handler struct {
+	service *cart.Service
+	log     *zap.SugaredLogger
+}
+
+func NewHandler(service *cart.Service, log *zap.SugaredLogger) *Handler {
+	return &Handler{service: service, log: log}
+}
+
+type SetItemRequest struct {
+	ProductID int64 `json:"product_id" binding:"required" example:"4"`
+	Quantity  int64 `json:"quantity" binding:"required,gt=0" example:"2"`
+}
+
+type CheckoutRequest struct {
+	PickupPointID  int64  `json:"pickup_point_id" binding:"required" example:"1"`
+	DeliverySlotID *int64 `json:"delivery_slot_id,omitempty" example:"7"`
+	PromoCode      string `json:"promo_code,omitempty" binding:"max=64" example:"SPRING10"`
+}
+
+// GetItems godoc
+// @Summary Корзина
+// @Description Возвращает корзину текущего пользователя с актуальными ценами, предупреждениями об остатках и итоговой суммой
+// @Tags cart
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} cart.Cart
+// @Failure 401 {object} errors.Problem "unauthorized"
+// @Failure 500 {object} errors.Problem "internal_error"
+// @Router /api/v1/cart/items [get]
+func (h *Handler) GetItems(c *gin.Context) {
+	result, err := h.service.GetCart(c.Request.Context(), c.GetInt64("userID"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// SetItem godoc
+// @Summary Добавление товара в корзину
+// @Description Добавляет товар в корзину или заменяет его количество. Наличие на складе проверяется при оформлении заказа
+// @Tags cart
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body SetItemRequest true "Товар и количество"
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор запроса с тем же ключом вернёт сохранённый ответ"
+// @Success 200 {object} cart.Cart
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 401 {object} errors.Problem "unauthorized"
+// @Failure 404 {object} errors.Problem "product_not_found"
+// @Failure 500 {object} errors.Problem "internal_error"
+// @Router /api/v1/cart/items [put]
+func (h *Handler) SetItem(c *gin.Context) {
+	var req SetItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(pkgerrors.Validation(err))
+		return
+	}
+
+	result, err := h.service.SetItem(c.Request.Context(), c.GetInt64("userID"), req.ProductID, req.Quantity)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// RemoveItem godoc
+// @Summary Удаление товара из корзины
+// @Description Удаляет товар из корзины текущего пользователя
+// @Tags cart
+// @Security BearerAuth
+// @Produce json
+// @Param product_id path int true "ID товара"
+// @Success 200 {object} cart.Cart
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 401 {object} errors.Problem "unauthorized"
+// @Failure 404 {object} errors.Problem "cart_item_not_found"
+// @Failure 500 {object} errors.Problem "internal_error"
+// @Router /api/v1/cart/items/{product_id} [delete]
+func (h *Handler) RemoveItem(c *gin.Context) {
+	productID, err := strconv.ParseInt(c.Param("product_id"), 10, 64)
+	if err != nil {
+		_ = c.Error(pkgerrors.InvalidField("product_id", "integer", ""))
+		return
+	}
+
+	result, err := h.service.RemoveItem(c.Request.Context(), c.GetInt64("userID"), productID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// Clear godoc
+// @Summary Очистка корзины
+// @Description Удаляет все товары из корзины текущего пользователя
+// @Tags cart
+// @Security BearerAuth
+// @Success 204 "No Content"
+// @Failure 401 {object} errors.Problem "unauthorized"
+// @Failure 500 {object} errors.Problem "internal_error"
+// @Router /api/v1/cart/items [delete]
+func (h *Handler) Clear(c *gin.Context) {
+	if err := h.service.Clear(c.Request.Context(), c.GetInt64("userID")); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Checkout godoc
+// @Summary Оформление заказа из корзины
+// @Description Создаёт заказ из содержимого корзины по актуальным ценам и очищает корзину. Заказ и очистка корзины выполняются в одной транзакции
+// @Tags cart
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body CheckoutRequest true "Пункт выдачи, слот доставки и промокод"
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор запроса с тем же ключом вернёт сохранённый ответ"
+// @Success 201 {object} map[string]int64 "order_id"
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 401 {object} errors.Problem "unauthorized"
+// @Failure 404 {object} errors.Problem "pickup_point_not_found, delivery_slot_not_found"
+// @Failure 409 {object} errors.Problem "cart_empty, cart_changed, insufficient_stock, promo_code_exhausted, pickup_point_inactive, pickup_point_closed, pickup_point_full, delivery_slot_full"
+// @Failure 422 {object} errors.Problem "promo_code_invalid, promo_min_amount, promo_not_applicable, delivery_slot_past, delivery_slot_beyond_horizon, delivery_slot_mismatch"
+// @Failure 500 {object} errors.Problem "internal_error"
+// @Router /api/v1/cart/checkout [post]
+func (h *Handler) Checkout(c *gin.Context) {
+	var req CheckoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(pkgerrors.Validation(err))
+		return
+	}
+
+	orderID, err := h.service.Checkout(c.Request.Context(), cart.CheckoutInput{
+		UserID:         c.GetInt64("userID"),
+		Role:           c.GetString("role"),
+		PickupPointID:  req.PickupPointID,
+		DeliverySlotID: req.DeliverySlotID,
+		PromoCode:      req.PromoCode,
+	})
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"order_id": orderID})
+}
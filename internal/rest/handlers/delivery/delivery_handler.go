@@ -0,0 +1,220 @@
+package delivery
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/Cora23tt/order_service/internal/usecase/delivery"
+	pkgerrors "github.com/Cora23tt/order_service/pkg/errors"
+)
+
+type Handler struct {
+	service *delivery.Service
+	log     *zap.SugaredLogger
+}
+
+func NewHandler(service *delivery.Service, log *zap.SugaredLogger) *Handler {
+	return &Handler{service: service, log: log}
+}
+
+// SlotRequest is the full state of a delivery slot. Capacity is how many
+// orders can be delivered in it.
+type SlotRequest struct {
+	StartsAt time.Time `json:"starts_at" binding:"required" example:"2025-06-02T10:00:00+05:00"`
+	EndsAt   time.Time `json:"ends_at" binding:"required" example:"2025-06-02T12:00:00+05:00"`
+	Capacity int64     `json:"capacity" binding:"required,gt=0" example:"20"`
+}
+
+func (r SlotRequest) input() delivery.SlotInput {
+	return delivery.SlotInput{StartsAt: r.StartsAt, EndsAt: r.EndsAt, Capacity: r.Capacity}
+}
+
+// ListAvailable godoc
+// @Summary Свободные слоты доставки
+// @Description Возвращает слоты доставки пункта выдачи, которые можно забронировать при оформлении заказа: ещё не начались, не дальше DELIVERY_BOOKING_HORIZON, со свободными местами и в часы работы пункта. Время — в часовом поясе PICKUP_TIMEZONE
+// @Tags delivery-slots
+// @Produce json
+// @Param id path int true "ID пункта выдачи"
+// @Success 200 {array} delivery.Slot
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 404 {object} errors.Problem "pickup_point_not_found"
+// @Failure 500 {object} errors.Problem "internal_error"
+// @Router /api/v1/pickup-points/{id}/slots [get]
+func (h *Handler) ListAvailable(c *gin.Context) {
+	pointID, err := parseID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	slots, err := h.service.ListAvailable(c.Request.Context(), pointID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, slots)
+}
+
+// List godoc
+// @Summary Слоты доставки пункта выдачи (admin)
+// @Description Возвращает все ещё не закончившиеся слоты доставки пункта выдачи, включая занятые, с числом забронированных мест
+// @Tags delivery-slots
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "ID пункта выдачи"
+// @Success 200 {array} delivery.Slot
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 401 {object} errors.Problem "unauthorized"
+// @Failure 403 {object} errors.Problem "forbidden"
+// @Failure 404 {object} errors.Problem "pickup_point_not_found"
+// @Failure 500 {object} errors.Problem "internal_error"
+// @Router /api/v1/admin/pickup-points/{id}/slots [get]
+func (h *Handler) List(c *gin.Context) {
+	pointID, err := parseID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	slots, err := h.service.List(c.Request.Context(), pointID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, slots)
+}
+
+// Create godoc
+// @Summary Создание слота доставки (admin)
+// @Description Создаёт слот доставки в пункт выдачи. У пункта не может быть двух слотов с одним временем начала
+// @Tags delivery-slots
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "ID пункта выдачи"
+// @Param request body SlotRequest true "Слот доставки"
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор запроса с тем же ключом вернёт сохранённый ответ"
+// @Success 201 {object} delivery.Slot
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 401 {object} errors.Problem "unauthorized"
+// @Failure 403 {object} errors.Problem "forbidden"
+// @Failure 404 {object} errors.Problem "pickup_point_not_found"
+// @Failure 409 {object} errors.Problem "delivery_slot_exists"
+// @Failure 500 {object} errors.Problem "internal_error"
+// @Router /api/v1/admin/pickup-points/{id}/slots [post]
+func (h *Handler) Create(c *gin.Context) {
+	pointID, err := parseID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req SlotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(pkgerrors.Validation(err))
+		return
+	}
+
+	slot, err := h.service.Create(c.Request.Context(), pointID, req.input())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, slot)
+}
+
+// Update godoc
+// @Summary Изменение слота доставки (admin)
+// @Description Полностью заменяет параметры слота. Вместимость нельзя сделать меньше числа забронированных мест, а время слота с бронированиями — изменить
+// @Tags delivery-slots
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "ID пункта выдачи"
+// @Param slot_id path int true "ID слота"
+// @Param request body SlotRequest true "Слот доставки"
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор запроса с тем же ключом вернёт сохранённый ответ"
+// @Success 200 {object} delivery.Slot
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 401 {object} errors.Problem "unauthorized"
+// @Failure 403 {object} errors.Problem "forbidden"
+// @Failure 404 {object} errors.Problem "delivery_slot_not_found"
+// @Failure 409 {object} errors.Problem "delivery_slot_exists, delivery_slot_booked"
+// @Failure 500 {object} errors.Problem "internal_error"
+// @Router /api/v1/admin/pickup-points/{id}/slots/{slot_id} [put]
+func (h *Handler) Update(c *gin.Context) {
+	pointID, err := parseID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	slotID, err := parseID(c, "slot_id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req SlotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(pkgerrors.Validation(err))
+		return
+	}
+
+	slot, err := h.service.Update(c.Request.Context(), pointID, slotID, req.input())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, slot)
+}
+
+// Delete godoc
+// @Summary Удаление слота доставки (admin)
+// @Description Удаляет слот доставки, на который ещё не оформлено ни одного заказа
+// @Tags delivery-slots
+// @Security BearerAuth
+// @Param id path int true "ID пункта выдачи"
+// @Param slot_id path int true "ID слота"
+// @Success 204 "No Content"
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 401 {object} errors.Problem "unauthorized"
+// @Failure 403 {object} errors.Problem "forbidden"
+// @Failure 404 {object} errors.Problem "delivery_slot_not_found"
+// @Failure 409 {object} errors.Problem "delivery_slot_booked"
+// @Failure 500 {object} errors.Problem "internal_error"
+// @Router /api/v1/admin/pickup-points/{id}/slots/{slot_id} [delete]
+func (h *Handler) Delete(c *gin.Context) {
+	pointID, err := parseID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	slotID, err := parseID(c, "slot_id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	if err := h.service.Delete(c.Request.Context(), pointID, slotID); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func parseID(c *gin.Context, name string) (int64, error) {
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil {
+		return 0, pkgerrors.InvalidField(name, "integer", "")
+	}
+	return id, nil
+}
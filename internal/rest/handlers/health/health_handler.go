@@ -0,0 +1,46 @@
+package health
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Cora23tt/order_service/pkg/health"
+)
+
+type Handler struct {
+	checker *health.Checker
+}
+
+func NewHandler(checker *health.Checker) *Handler {
+	return &Handler{checker: checker}
+}
+
+// Liveness godoc
+// @Summary Liveness probe
+// @Description Отвечает 200, пока процесс жив. Зависимости не проверяются.
+// @Tags health
+// @Produce json
+// @Success 200 {object} map[string]string "status: up"
+// @Router /healthz [get]
+func (h *Handler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": health.StatusUp})
+}
+
+// Readiness godoc
+// @Summary Readiness probe
+// @Description Проверяет подключение к базе, версию миграций и каталог загрузок. Возвращает 503, если хотя бы одна проверка не прошла или сервер завершает работу.
+// @Tags health
+// @Produce json
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report
+// @Router /readyz [get]
+func (h *Handler) Readiness(c *gin.Context) {
+	report := h.checker.Check(c.Request.Context())
+
+	status := http.StatusOK
+	if report.Status != health.StatusUp {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
@@ -2,7 +2,6 @@ package order
 
 import (
 	"encoding/csv"
-	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -10,10 +9,14 @@ import (
 	"github.com/Cora23tt/order_service/internal/usecase/order"
 	"github.com/Cora23tt/order_service/pkg/enums"
 	pkgerrors "github.com/Cora23tt/order_service/pkg/errors"
+	"github.com/Cora23tt/order_service/pkg/logger"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
+// orderStatuses lists the accepted status values in validator oneof format.
+const orderStatuses = "pending_payment paid processing shipped delivered cancelled partially_refunded refunded"
+
 type Handler struct {
 	service *order.Service
 	log     *zap.SugaredLogger
@@ -24,9 +27,10 @@ func NewHandler(service *order.Service, log *zap.SugaredLogger) *Handler {
 }
 
 type CreateOrderRequest struct {
-	Items        []order.OrderItemInput `json:"items" binding:"required"`
-	PickupPoint  string                 `json:"pickup_point" binding:"required" example:"Mirzo Ulug'bek. Buyuk Ipak Yoli st. 109. 45"`
-	DeliveryDate *time.Time             `json:"-"`
+	Items          []order.OrderItemInput `json:"items" binding:"required,min=1,dive"`
+	PickupPointID  int64                  `json:"pickup_point_id" binding:"required" example:"1"`
+	DeliverySlotID *int64                 `json:"delivery_slot_id,omitempty" example:"7"`
+	PromoCode      string                 `json:"promo_code,omitempty" binding:"max=64" example:"SPRING10"`
 }
 
 // Create godoc
@@ -36,46 +40,41 @@ type CreateOrderRequest struct {
 // @Accept json
 // @Produce json
 // @Param request body CreateOrderRequest true "Order info"
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор запроса с тем же ключом вернёт сохранённый ответ"
 // @Success 201 {object} map[string]int64 "order_id"
-// @Failure 400 {object} map[string]string "invalid input"
-// @Failure 401 {object} map[string]string "unauthorized"
-// @Failure 409 {object} map[string]string "insufficient stock"
-// @Failure 500 {object} map[string]string "internal error"
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 401 {object} errors.Problem "unauthorized"
+// @Failure 404 {object} errors.Problem "pickup_point_not_found, delivery_slot_not_found"
+// @Failure 409 {object} errors.Problem "insufficient_stock, price_changed, promo_code_exhausted, pickup_point_inactive, pickup_point_closed, pickup_point_full, delivery_slot_full"
+// @Failure 422 {object} errors.Problem "promo_code_invalid, promo_min_amount, promo_not_applicable, delivery_slot_past, delivery_slot_beyond_horizon, delivery_slot_mismatch"
+// @Failure 500 {object} errors.Problem "internal_error"
 // @Router /api/v1/orders/ [post]
 // @Security BearerAuth
 func (h *Handler) Create(c *gin.Context) {
-	userIDRaw, exists := c.Get("userID")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+	userIDRaw, ok1 := c.Get("userID")
+	roleRaw, ok2 := c.Get("role")
+	if !ok1 || !ok2 {
+		_ = c.Error(pkgerrors.ErrUnauthorized)
 		return
 	}
 	userID := userIDRaw.(int64)
 
 	var req CreateOrderRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.log.Warnw("invalid create order request", "userID", userID, "error", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
+		_ = c.Error(pkgerrors.Validation(err))
 		return
 	}
 
 	id, err := h.service.CreateOrder(c.Request.Context(), order.CreateOrderInput{
-		UserID:       userID,
-		Items:        req.Items,
-		PickupPoint:  req.PickupPoint,
-		DeliveryDate: req.DeliveryDate,
+		UserID:         userID,
+		Role:           roleRaw.(string),
+		Items:          req.Items,
+		PickupPointID:  req.PickupPointID,
+		DeliverySlotID: req.DeliverySlotID,
+		PromoCode:      req.PromoCode,
 	})
 	if err != nil {
-		switch {
-		case errors.Is(err, pkgerrors.ErrInvalidInput):
-			h.log.Warnw("invalid data for order creation", "userID", userID, "error", err)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user or product"})
-		case errors.Is(err, pkgerrors.ErrInsufficientStock):
-			h.log.Warnw("insufficient stock for order", "userID", userID, "error", err)
-			c.JSON(http.StatusConflict, gin.H{"error": "insufficient stock"})
-		default:
-			h.log.Errorw("internal error during order creation", "userID", userID, "error", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
-		}
+		_ = c.Error(err)
 		return
 	}
 
@@ -88,37 +87,68 @@ func (h *Handler) Create(c *gin.Context) {
 // @Security BearerAuth
 // @Param id path int true "Order ID"
 // @Success 200 {object} order.Order
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 404 {object} errors.Problem "order_not_found"
+// @Failure 500 {object} errors.Problem "internal_error"
 // @Router /api/v1/orders/{id} [get]
 func (h *Handler) GetByID(c *gin.Context) {
-	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	id, err := parseID(c)
 	if err != nil {
-		h.log.Warnw("invalid order id", "param", c.Param("id"), "error", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order id"})
+		_ = c.Error(err)
 		return
 	}
 
 	userIDRaw, ok1 := c.Get("userID")
 	roleRaw, ok2 := c.Get("role")
 	if !ok1 || !ok2 {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		_ = c.Error(pkgerrors.ErrUnauthorized)
 		return
 	}
 	userID := userIDRaw.(int64)
 	role := roleRaw.(string)
 
 	order, err := h.service.GetOrderByID(c.Request.Context(), id, userID, role)
-	switch {
-	case errors.Is(err, pkgerrors.ErrNotFound):
-		c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
-	case err != nil:
-		h.log.Errorw("get order by id failed", "id", id, "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
-	default:
-		c.JSON(http.StatusOK, order)
+	if err != nil {
+		_ = c.Error(err)
+		return
 	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+// @Summary Get order status history (user/admin)
+// @Description Возвращает историю изменения статусов заказа
+// @Tags orders
+// @Security BearerAuth
+// @Param id path int true "Order ID"
+// @Success 200 {array} order.StatusChange
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 404 {object} errors.Problem "order_not_found"
+// @Failure 500 {object} errors.Problem "internal_error"
+// @Router /api/v1/orders/{id}/history [get]
+func (h *Handler) GetHistory(c *gin.Context) {
+	id, err := parseID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	userIDRaw, ok1 := c.Get("userID")
+	roleRaw, ok2 := c.Get("role")
+	if !ok1 || !ok2 {
+		_ = c.Error(pkgerrors.ErrUnauthorized)
+		return
+	}
+	userID := userIDRaw.(int64)
+	role := roleRaw.(string)
+
+	history, err := h.service.GetOrderHistory(c.Request.Context(), id, userID, role)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
 }
 
 // @Summary Get all user orders (user/admin)
@@ -126,21 +156,20 @@ func (h *Handler) GetByID(c *gin.Context) {
 // @Tags orders
 // @Security BearerAuth
 // @Success 200 {array} order.Order
-// @Failure 401 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 401 {object} errors.Problem "unauthorized"
+// @Failure 500 {object} errors.Problem "internal_error"
 // @Router /api/v1/orders [get]
 func (h *Handler) GetAll(c *gin.Context) {
 	userIDRaw, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		_ = c.Error(pkgerrors.ErrUnauthorized)
 		return
 	}
 	userID := userIDRaw.(int64)
 
 	orders, err := h.service.GetUserOrders(c.Request.Context(), userID)
 	if err != nil {
-		h.log.Errorw("get user orders failed", "userID", userID, "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		_ = c.Error(err)
 		return
 	}
 
@@ -149,6 +178,7 @@ func (h *Handler) GetAll(c *gin.Context) {
 
 type UpdateStatusRequest struct {
 	Status string `json:"status" binding:"required" example:"processing"`
+	Reason string `json:"reason,omitempty" example:"handed over to courier"`
 }
 
 // @Summary Update order status (admin)
@@ -157,45 +187,80 @@ type UpdateStatusRequest struct {
 // @Security BearerAuth
 // @Param id path int true "Order ID"
 // @Param input body order.UpdateStatusRequest true "New status"
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор запроса с тем же ключом вернёт сохранённый ответ"
 // @Success 200 {object} map[string]string
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 404 {object} errors.Problem "order_not_found"
+// @Failure 409 {object} errors.Problem "invalid_status_transition, order_completed, refund_status_not_allowed, cancel_requires_refund"
+// @Failure 500 {object} errors.Problem "internal_error"
 // @Router /api/v1/orders/{id} [put]
 func (h *Handler) Update(c *gin.Context) {
-	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	id, err := parseID(c)
 	if err != nil {
-		h.log.Warnw("invalid order id", "param", c.Param("id"), "error", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order id"})
+		_ = c.Error(err)
 		return
 	}
 
 	var req UpdateStatusRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.log.Warnw("invalid input for update", "error", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
+		_ = c.Error(pkgerrors.Validation(err))
 		return
 	}
 
 	status := enums.OrderStatus(req.Status)
 	if !status.IsValid() {
-		h.log.Warnw("invalid status value", "status", req.Status)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
+		_ = c.Error(pkgerrors.InvalidField("status", "oneof", orderStatuses))
+		return
+	}
+
+	userIDRaw, ok1 := c.Get("userID")
+	roleRaw, ok2 := c.Get("role")
+	if !ok1 || !ok2 {
+		_ = c.Error(pkgerrors.ErrUnauthorized)
 		return
 	}
+	actor := order.Actor{UserID: userIDRaw.(int64), Role: roleRaw.(string)}
 
-	err = h.service.AdminUpdateOrderStatus(c.Request.Context(), id, req.Status)
-	switch {
-	case errors.Is(err, pkgerrors.ErrNotFound):
-		c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
-	case errors.Is(err, pkgerrors.ErrInvalidInput):
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
-	case err != nil:
-		h.log.Errorw("update order failed", "id", id, "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
-	default:
-		c.JSON(http.StatusOK, gin.H{"message": "status updated"})
+	if err := h.service.AdminUpdateOrderStatus(c.Request.Context(), id, status, actor, req.Reason); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "status updated"})
+}
+
+type NextStatusesResponse struct {
+	Status       enums.OrderStatus   `json:"status" example:"paid"`
+	NextStatuses []enums.OrderStatus `json:"next_statuses" example:"processing,partially_refunded,refunded"`
+}
+
+// @Summary Get allowed next statuses (admin)
+// @Description Возвращает текущий статус заказа и статусы, в которые его можно перевести
+// @Tags orders
+// @Security BearerAuth
+// @Param id path int true "Order ID"
+// @Success 200 {object} order.NextStatusesResponse
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 404 {object} errors.Problem "order_not_found"
+// @Failure 500 {object} errors.Problem "internal_error"
+// @Router /api/v1/orders/{id}/transitions [get]
+func (h *Handler) GetTransitions(c *gin.Context) {
+	id, err := parseID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	status, next, err := h.service.GetNextStatuses(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	if next == nil {
+		next = []enums.OrderStatus{}
 	}
+	c.JSON(http.StatusOK, NextStatusesResponse{Status: status, NextStatuses: next})
 }
 
 // @Summary Delete order by ID (admin)
@@ -204,28 +269,23 @@ func (h *Handler) Update(c *gin.Context) {
 // @Security BearerAuth
 // @Param id path int true "Order ID"
 // @Success 204
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 404 {object} errors.Problem "order_not_found"
+// @Failure 500 {object} errors.Problem "internal_error"
 // @Router /api/v1/orders/{id} [delete]
 func (h *Handler) Delete(c *gin.Context) {
-	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	id, err := parseID(c)
 	if err != nil {
-		h.log.Warnw("invalid order id", "param", c.Param("id"), "error", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order id"})
+		_ = c.Error(err)
 		return
 	}
 
-	err = h.service.DeleteOrder(c.Request.Context(), id)
-	switch {
-	case errors.Is(err, pkgerrors.ErrNotFound):
-		c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
-	case err != nil:
-		h.log.Errorw("delete order failed", "id", id, "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
-	default:
-		c.Status(http.StatusNoContent)
+	if err := h.service.DeleteOrder(c.Request.Context(), id); err != nil {
+		_ = c.Error(err)
+		return
 	}
+
+	c.Status(http.StatusNoContent)
 }
 
 // @Summary Cancel order (user/admin)
@@ -233,41 +293,36 @@ func (h *Handler) Delete(c *gin.Context) {
 // @Tags orders
 // @Security BearerAuth
 // @Param id path int true "Order ID"
+// @Param reason query string false "Cancellation reason"
 // @Success 200 {object} map[string]string
-// @Failure 400 {object} map[string]string
-// @Failure 403 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 403 {object} errors.Problem "forbidden"
+// @Failure 404 {object} errors.Problem "order_not_found"
+// @Failure 409 {object} errors.Problem "cancel_not_allowed"
+// @Failure 500 {object} errors.Problem "internal_error"
 // @Router /api/v1/orders/{id}/cancel [get]
 func (h *Handler) Cancel(c *gin.Context) {
-	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	id, err := parseID(c)
 	if err != nil {
-		h.log.Warnw("invalid order id", "param", c.Param("id"), "error", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order id"})
+		_ = c.Error(err)
 		return
 	}
 
-	userIDRaw, exists := c.Get("userID")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+	userIDRaw, ok1 := c.Get("userID")
+	roleRaw, ok2 := c.Get("role")
+	if !ok1 || !ok2 {
+		_ = c.Error(pkgerrors.ErrUnauthorized)
 		return
 	}
 	userID := userIDRaw.(int64)
+	actor := order.Actor{UserID: userID, Role: roleRaw.(string)}
 
-	err = h.service.CancelOrder(c.Request.Context(), id, userID)
-	switch {
-	case errors.Is(err, pkgerrors.ErrNotFound):
-		c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
-	case errors.Is(err, pkgerrors.ErrUnauthorized):
-		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
-	case errors.Is(err, pkgerrors.ErrInvalidInput):
-		c.JSON(http.StatusBadRequest, gin.H{"error": "cancel not allowed"})
-	case err != nil:
-		h.log.Errorw("cancel order failed", "id", id, "userID", userID, "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
-	default:
-		c.JSON(http.StatusOK, gin.H{"message": "order cancelled"})
+	if err := h.service.CancelOrder(c.Request.Context(), id, actor, c.Query("reason")); err != nil {
+		_ = c.Error(err)
+		return
 	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "order cancelled"})
 }
 
 // @Summary Get order stats (admin)
@@ -277,8 +332,8 @@ func (h *Handler) Cancel(c *gin.Context) {
 // @Param from query string false "Start date (YYYY-MM-DD)"
 // @Param to query string false "End date (YYYY-MM-DD)"
 // @Success 200 {object} map[string]interface{}
-// @Failure 400 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 500 {object} errors.Problem "internal_error"
 // @Router /api/v1/orders/stats [get]
 func (h *Handler) GetStats(c *gin.Context) {
 	fromStr := c.Query("from")
@@ -290,8 +345,7 @@ func (h *Handler) GetStats(c *gin.Context) {
 	if fromStr != "" {
 		t, err := time.Parse(layout, fromStr)
 		if err != nil {
-			h.log.Warnw("invalid from date", "from", fromStr, "error", err)
-			c.JSON(http.StatusBadRequest, gin.H{"status": "BAD_REQUEST", "message": "Invalid 'from' date format. Use YYYY-MM-DD."})
+			_ = c.Error(pkgerrors.InvalidField("from", "date", "YYYY-MM-DD"))
 			return
 		}
 		from = &t
@@ -300,8 +354,7 @@ func (h *Handler) GetStats(c *gin.Context) {
 	if toStr != "" {
 		t, err := time.Parse(layout, toStr)
 		if err != nil {
-			h.log.Warnw("invalid to date", "to", toStr, "error", err)
-			c.JSON(http.StatusBadRequest, gin.H{"status": "BAD_REQUEST", "message": "Invalid 'to' date format. Use YYYY-MM-DD."})
+			_ = c.Error(pkgerrors.InvalidField("to", "date", "YYYY-MM-DD"))
 			return
 		}
 		to = &t
@@ -309,14 +362,7 @@ func (h *Handler) GetStats(c *gin.Context) {
 
 	stats, err := h.service.GetStats(c.Request.Context(), from, to)
 	if err != nil {
-		h.log.Errorw("failed to get order stats", "from", fromStr, "to", toStr, "error", err)
-
-		switch err {
-		case pkgerrors.ErrInvalidInput:
-			c.JSON(http.StatusBadRequest, gin.H{"status": "BAD_REQUEST", "message": "Invalid input provided"})
-		default:
-			c.JSON(http.StatusInternalServerError, gin.H{"status": "INTERNAL_ERROR", "message": "Failed to get statistics"})
-		}
+		_ = c.Error(err)
 		return
 	}
 
@@ -334,19 +380,19 @@ func (h *Handler) GetStats(c *gin.Context) {
 // @Param limit query int false "Limit"
 // @Param offset query int false "Offset"
 // @Success 200 {object} map[string]interface{}
-// @Failure 400 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 500 {object} errors.Problem "internal_error"
 // @Router /api/v1/orders/export [get]
 func (h *Handler) Export(c *gin.Context) {
-	filter, ok := h.parseExportFilter(c)
-	if !ok {
+	filter, err := parseExportFilter(c)
+	if err != nil {
+		_ = c.Error(err)
 		return
 	}
 
 	orders, err := h.service.ExportOrders(c.Request.Context(), filter)
 	if err != nil {
-		h.log.Errorw("export orders failed", "filter", filter, "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		_ = c.Error(err)
 		return
 	}
 
@@ -364,19 +410,21 @@ func (h *Handler) Export(c *gin.Context) {
 // @Param limit query int false "Limit"
 // @Param offset query int false "Offset"
 // @Success 200 {string} string "CSV file"
-// @Failure 400 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 500 {object} errors.Problem "internal_error"
 // @Router /api/v1/orders/export/csv [get]
 func (h *Handler) ExportCSV(c *gin.Context) {
-	filter, ok := h.parseExportFilter(c)
-	if !ok {
+	log := logger.FromContext(c.Request.Context(), h.log)
+
+	filter, err := parseExportFilter(c)
+	if err != nil {
+		_ = c.Error(err)
 		return
 	}
 
 	orders, err := h.service.ExportOrders(c.Request.Context(), filter)
 	if err != nil {
-		h.log.Errorw("export csv failed", "filter", filter, "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		_ = c.Error(err)
 		return
 	}
 
@@ -386,8 +434,8 @@ func (h *Handler) ExportCSV(c *gin.Context) {
 	writer := csv.NewWriter(c.Writer)
 	defer writer.Flush()
 
-	if err := writer.Write([]string{"ID", "UserID", "Status", "DeliveryDate", "PickupPoint", "OrderDate", "TotalAmount", "ReceiptURL", "CreatedAt", "UpdatedAt"}); err != nil {
-		h.log.Errorw("write csv header failed", "error", err)
+	if err := writer.Write([]string{"ID", "UserID", "Status", "DeliveryDate", "PickupPoint", "OrderDate", "SubtotalAmount", "DiscountAmount", "NetAmount", "TaxAmount", "TotalAmount", "RefundedAmount", "ReceiptURL", "CreatedAt", "UpdatedAt"}); err != nil {
+		log.Errorw("write csv header failed", "error", err)
 		return
 	}
 
@@ -404,29 +452,41 @@ func (h *Handler) ExportCSV(c *gin.Context) {
 			nullTimeToString(o.DeliveryDate),
 			o.PickupPoint,
 			o.OrderDate.Format("2006-01-02 15:04:05"),
+			strconv.FormatInt(o.SubtotalAmount, 10),
+			strconv.FormatInt(o.DiscountAmount, 10),
+			strconv.FormatInt(o.NetAmount, 10),
+			strconv.FormatInt(o.TaxAmount, 10),
 			strconv.FormatInt(o.TotalAmount, 10),
+			strconv.FormatInt(o.RefundedAmount, 10),
 			receipt,
 			o.CreatedAt.Format("2006-01-02 15:04:05"),
 			o.UpdatedAt.Format("2006-01-02 15:04:05"),
 		}); err != nil {
-			h.log.Errorw("write csv row failed", "order_id", o.ID, "error", err)
+			log.Errorw("write csv row failed", "order_id", o.ID, "error", err)
 			return
 		}
 	}
 
 	if err := writer.Error(); err != nil {
-		h.log.Errorw("flush csv writer failed", "error", err)
+		log.Errorw("flush csv writer failed", "error", err)
+	}
+}
+
+func parseID(c *gin.Context) (int64, error) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, pkgerrors.InvalidField("id", "integer", "")
 	}
+	return id, nil
 }
 
-func (h *Handler) parseExportFilter(c *gin.Context) (order.ExportFilter, bool) {
+func parseExportFilter(c *gin.Context) (order.ExportFilter, error) {
 	var filter order.ExportFilter
 
 	if uidStr := c.Query("user_id"); uidStr != "" {
 		uid, err := strconv.ParseInt(uidStr, 10, 64)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
-			return filter, false
+			return filter, pkgerrors.InvalidField("user_id", "integer", "")
 		}
 		filter.UserID = &uid
 	}
@@ -434,8 +494,7 @@ func (h *Handler) parseExportFilter(c *gin.Context) (order.ExportFilter, bool) {
 	if statusStr := c.Query("status"); statusStr != "" {
 		status := enums.OrderStatus(statusStr)
 		if !status.IsValid() {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
-			return filter, false
+			return filter, pkgerrors.InvalidField("status", "oneof", orderStatuses)
 		}
 		filter.Status = &status
 	}
@@ -443,8 +502,7 @@ func (h *Handler) parseExportFilter(c *gin.Context) (order.ExportFilter, bool) {
 	if minStr := c.Query("min_amount"); minStr != "" {
 		min, err := strconv.ParseInt(minStr, 10, 64)
 		if err != nil || min < 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid min_amount"})
-			return filter, false
+			return filter, pkgerrors.InvalidField("min_amount", "gte", "0")
 		}
 		filter.MinAmount = &min
 	}
@@ -452,22 +510,19 @@ func (h *Handler) parseExportFilter(c *gin.Context) (order.ExportFilter, bool) {
 	if maxStr := c.Query("max_amount"); maxStr != "" {
 		max, err := strconv.ParseInt(maxStr, 10, 64)
 		if err != nil || max < 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid max_amount"})
-			return filter, false
+			return filter, pkgerrors.InvalidField("max_amount", "gte", "0")
 		}
 		filter.MaxAmount = &max
 	}
 
 	if filter.MinAmount != nil && filter.MaxAmount != nil && *filter.MinAmount > *filter.MaxAmount {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "min_amount must be <= max_amount"})
-		return filter, false
+		return filter, pkgerrors.InvalidField("min_amount", "ltefield", "max_amount")
 	}
 
 	if limitStr := c.Query("limit"); limitStr != "" {
 		limit, err := strconv.Atoi(limitStr)
 		if err != nil || limit <= 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
-			return filter, false
+			return filter, pkgerrors.InvalidField("limit", "gt", "0")
 		}
 		filter.Limit = limit
 	}
@@ -475,13 +530,12 @@ func (h *Handler) parseExportFilter(c *gin.Context) (order.ExportFilter, bool) {
 	if offsetStr := c.Query("offset"); offsetStr != "" {
 		offset, err := strconv.Atoi(offsetStr)
 		if err != nil || offset < 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset"})
-			return filter, false
+			return filter, pkgerrors.InvalidField("offset", "gte", "0")
 		}
 		filter.Offset = offset
 	}
 
-	return filter, true
+	return filter, nil
 }
 
 func nullTimeToString(t *time.Time) string {
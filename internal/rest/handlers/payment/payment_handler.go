@@ -0,0 +1,174 @@
+package payment
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/Cora23tt/order_service/internal/usecase/order"
+	"github.com/Cora23tt/order_service/internal/usecase/payment"
+	pkgerrors "github.com/Cora23tt/order_service/pkg/errors"
+)
+
+// maxCallbackSize limits the body of provider callbacks.
+const maxCallbackSize = 1 << 20
+
+type Handler struct {
+	service *payment.Service
+	log     *zap.SugaredLogger
+}
+
+func NewHandler(service *payment.Service, log *zap.SugaredLogger) *Handler {
+	return &Handler{service: service, log: log}
+}
+
+// Pay godoc
+// @Summary Оплата заказа
+// @Description Выставляет счёт на оплату заказа в платёжной системе и возвращает ссылку на оплату. Повторный запрос возвращает уже выставленный неоплаченный счёт
+// @Tags payments
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "ID заказа"
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор запроса с тем же ключом вернёт сохранённый ответ"
+// @Success 201 {object} payment.Payment
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 401 {object} errors.Problem "unauthorized"
+// @Failure 404 {object} errors.Problem "order_not_found"
+// @Failure 409 {object} errors.Problem "order_not_payable"
+// @Failure 502 {object} errors.Problem "payment_provider_error"
+// @Failure 500 {object} errors.Problem "internal_error"
+// @Router /api/v1/orders/{id}/payment [post]
+func (h *Handler) Pay(c *gin.Context) {
+	id, err := parseID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	p, err := h.service.CreatePayment(c.Request.Context(), id, c.GetInt64("userID"), c.GetString("role"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, p)
+}
+
+// List godoc
+// @Summary Платежи заказа
+// @Description Возвращает все счета заказа с их статусами
+// @Tags payments
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "ID заказа"
+// @Success 200 {array} payment.Payment
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 401 {object} errors.Problem "unauthorized"
+// @Failure 404 {object} errors.Problem "order_not_found"
+// @Failure 500 {object} errors.Problem "internal_error"
+// @Router /api/v1/orders/{id}/payments [get]
+func (h *Handler) List(c *gin.Context) {
+	id, err := parseID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	payments, err := h.service.ListPayments(c.Request.Context(), id, c.GetInt64("userID"), c.GetString("role"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, payments)
+}
+
+// RefundRequest selects the order lines and quantities to refund. Without
+// items the whole order is refunded.
+type RefundRequest struct {
+	Items  []order.RefundItemInput `json:"items" binding:"dive"`
+	Reason string                  `json:"reason" binding:"required,max=500" example:"товар повреждён"`
+}
+
+// Refund godoc
+// @Summary Возврат средств (admin)
+// @Description Возвращает деньги за весь заказ или за выбранные количества позиций через платёжную систему и возвращает товары на склад. Заказ переходит в статус refunded, если возвращены все позиции, иначе в partially_refunded. Сумма возвратов не может превысить оплаченную
+// @Tags payments
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "ID заказа"
+// @Param request body RefundRequest true "Возврат"
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор запроса с тем же ключом вернёт сохранённый ответ"
+// @Success 201 {object} order.Refund
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 401 {object} errors.Problem "unauthorized"
+// @Failure 403 {object} errors.Problem "forbidden"
+// @Failure 404 {object} errors.Problem "order_not_found, order_item_not_found"
+// @Failure 409 {object} errors.Problem "order_not_refundable"
+// @Failure 422 {object} errors.Problem "refund_quantity_exceeded, refund_exceeds_paid"
+// @Failure 502 {object} errors.Problem "payment_provider_error, refund_declined"
+// @Failure 500 {object} errors.Problem "internal_error"
+// @Router /api/v1/orders/{id}/refunds [post]
+func (h *Handler) Refund(c *gin.Context) {
+	id, err := parseID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req RefundRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(pkgerrors.Validation(err))
+		return
+	}
+
+	actor := order.Actor{UserID: c.GetInt64("userID"), Role: c.GetString("role")}
+	refund, err := h.service.Refund(c.Request.Context(), id, order.RefundInput{Items: req.Items, Reason: req.Reason}, actor)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, refund)
+}
+
+// Webhook godoc
+// @Summary Уведомление платёжной системы
+// @Description Принимает подписанное уведомление о статусе платежа. Подтверждённая оплата переводит заказ в статус paid; повторные уведомления не меняют состояние
+// @Tags payments
+// @Accept json
+// @Param provider path string true "Платёжная система" example(mock)
+// @Param X-Mock-Signature header string false "HMAC-SHA256 тела запроса (mock)"
+// @Success 200 "OK"
+// @Failure 400 {object} errors.Problem "invalid_callback"
+// @Failure 401 {object} errors.Problem "invalid_signature"
+// @Failure 404 {object} errors.Problem "payment_provider_not_found, payment_not_found"
+// @Failure 422 {object} errors.Problem "payment_amount_mismatch"
+// @Failure 500 {object} errors.Problem "internal_error"
+// @Router /api/v1/payments/webhook/{provider} [post]
+func (h *Handler) Webhook(c *gin.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxCallbackSize))
+	if err != nil {
+		_ = c.Error(pkgerrors.ErrInvalidCallback)
+		return
+	}
+
+	if err := h.service.HandleCallback(c.Request.Context(), c.Param("provider"), c.Request.Header, body); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func parseID(c *gin.Context) (int64, error) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, pkgerrors.InvalidField("id", "integer", "")
+	}
+	return id, nil
+}
@@ -0,0 +1,285 @@
+package pickup
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	pickupRepo "github.com/Cora23tt/order_service/internal/repository/pickup"
+	"github.com/Cora23tt/order_service/internal/usecase/pickup"
+	pkgerrors "github.com/Cora23tt/order_service/pkg/errors"
+)
+
+type Handler struct {
+	service *pickup.Service
+	log     *zap.SugaredLogger
+}
+
+func NewHandler(service *pickup.Service, log *zap.SugaredLogger) *Handler {
+	return &Handler{service: service, log: log}
+}
+
+// PointRequest is the full state of a pickup point. Days without
+// opening_hours intervals are days off; several intervals on one day allow
+// for breaks.
+type PointRequest struct {
+	Name         string                    `json:"name" binding:"required,max=255" example:"Buyuk Ipak Yoli"`
+	Address      string                    `json:"address" binding:"required,max=255" example:"Mirzo Ulug'bek. Buyuk Ipak Yoli st. 109. 45"`
+	Latitude     *float64                  `json:"latitude" binding:"required,min=-90,max=90" example:"41.3262"`
+	Longitude    *float64                  `json:"longitude" binding:"required,min=-180,max=180" example:"69.3285"`
+	OpeningHours []pickupRepo.OpeningHours `json:"opening_hours" binding:"dive"`
+	Capacity     int64                     `json:"capacity" binding:"required,gt=0" example:"200"`
+	Active       *bool                     `json:"active" example:"true"`
+}
+
+func (r PointRequest) input() pickup.PointInput {
+	active := true
+	if r.Active != nil {
+		active = *r.Active
+	}
+	return pickup.PointInput{
+		Name:         r.Name,
+		Address:      r.Address,
+		Latitude:     *r.Latitude,
+		Longitude:    *r.Longitude,
+		OpeningHours: r.OpeningHours,
+		Capacity:     r.Capacity,
+		Active:       active,
+	}
+}
+
+// List godoc
+// @Summary Пункты выдачи
+// @Description Возвращает действующие пункты выдачи. Если переданы lat и lon, пункты отсортированы по расстоянию от этой точки, ближайшие первыми, и содержат distance_km
+// @Tags pickup-points
+// @Produce json
+// @Param lat query number false "Широта"
+// @Param lon query number false "Долгота"
+// @Success 200 {array} pickup.Point
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 500 {object} errors.Problem "internal_error"
+// @Router /api/v1/pickup-points [get]
+func (h *Handler) List(c *gin.Context) {
+	filter, err := parseLocation(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	filter.ActiveOnly = true
+
+	points, err := h.service.List(c.Request.Context(), filter)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, points)
+}
+
+// Get godoc
+// @Summary Пункт выдачи
+// @Description Возвращает пункт выдачи по ID
+// @Tags pickup-points
+// @Produce json
+// @Param id path int true "ID пункта выдачи"
+// @Success 200 {object} pickup.Point
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 404 {object} errors.Problem "pickup_point_not_found"
+// @Failure 500 {object} errors.Problem "internal_error"
+// @Router /api/v1/pickup-points/{id} [get]
+func (h *Handler) Get(c *gin.Context) {
+	id, err := parseID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	p, err := h.service.Get(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, p)
+}
+
+// AdminList godoc
+// @Summary Все пункты выдачи (admin)
+// @Description Возвращает все пункты выдачи, включая неактивные. Сортировка по lat и lon — как в публичном списке
+// @Tags pickup-points
+// @Security BearerAuth
+// @Produce json
+// @Param lat query number false "Широта"
+// @Param lon query number false "Долгота"
+// @Success 200 {array} pickup.Point
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 401 {object} errors.Problem "unauthorized"
+// @Failure 403 {object} errors.Problem "forbidden"
+// @Failure 500 {object} errors.Problem "internal_error"
+// @Router /api/v1/admin/pickup-points [get]
+func (h *Handler) AdminList(c *gin.Context) {
+	filter, err := parseLocation(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	points, err := h.service.List(c.Request.Context(), filter)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, points)
+}
+
+// Create godoc
+// @Summary Создание пункта выдачи (admin)
+// @Description Создаёт пункт выдачи. Часы работы задаются интервалами по дням недели (1 — понедельник) в часовом поясе PICKUP_TIMEZONE
+// @Tags pickup-points
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body PointRequest true "Пункт выдачи"
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор запроса с тем же ключом вернёт сохранённый ответ"
+// @Success 201 {object} pickup.Point
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 401 {object} errors.Problem "unauthorized"
+// @Failure 403 {object} errors.Problem "forbidden"
+// @Failure 500 {object} errors.Problem "internal_error"
+// @Router /api/v1/admin/pickup-points [post]
+func (h *Handler) Create(c *gin.Context) {
+	var req PointRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(pkgerrors.Validation(err))
+		return
+	}
+
+	p, err := h.service.Create(c.Request.Context(), req.input())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, p)
+}
+
+// Update godoc
+// @Summary Изменение пункта выдачи (admin)
+// @Description Полностью заменяет параметры пункта выдачи. Уже оформленные заказы сохраняют название и адрес, с которыми были оформлены
+// @Tags pickup-points
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "ID пункта выдачи"
+// @Param request body PointRequest true "Пункт выдачи"
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор запроса с тем же ключом вернёт сохранённый ответ"
+// @Success 200 {object} pickup.Point
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 401 {object} errors.Problem "unauthorized"
+// @Failure 403 {object} errors.Problem "forbidden"
+// @Failure 404 {object} errors.Problem "pickup_point_not_found"
+// @Failure 500 {object} errors.Problem "internal_error"
+// @Router /api/v1/admin/pickup-points/{id} [put]
+func (h *Handler) Update(c *gin.Context) {
+	id, err := parseID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req PointRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(pkgerrors.Validation(err))
+		return
+	}
+
+	p, err := h.service.Update(c.Request.Context(), id, req.input())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, p)
+}
+
+// Delete godoc
+// @Summary Удаление пункта выдачи (admin)
+// @Description Удаляет пункт выдачи вместе с его слотами доставки. Пункт со слотами, на которые оформлены заказы, удалить нельзя. Заказы сохраняют название и адрес пункта; чтобы временно не принимать заказы, пункт лучше сделать неактивным
+// @Tags pickup-points
+// @Security BearerAuth
+// @Param id path int true "ID пункта выдачи"
+// @Success 204 "No Content"
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 401 {object} errors.Problem "unauthorized"
+// @Failure 403 {object} errors.Problem "forbidden"
+// @Failure 404 {object} errors.Problem "pickup_point_not_found"
+// @Failure 409 {object} errors.Problem "delivery_slot_booked"
+// @Failure 500 {object} errors.Problem "internal_error"
+// @Router /api/v1/admin/pickup-points/{id} [delete]
+func (h *Handler) Delete(c *gin.Context) {
+	id, err := parseID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	if err := h.service.Delete(c.Request.Context(), id); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// parseLocation reads the optional lat and lon query parameters, which must
+// be given together.
+func parseLocation(c *gin.Context) (pickupRepo.ListFilter, error) {
+	var filter pickupRepo.ListFilter
+	latStr, lonStr := c.Query("lat"), c.Query("lon")
+	if latStr == "" && lonStr == "" {
+		return filter, nil
+	}
+	if latStr == "" {
+		return filter, pkgerrors.InvalidField("lat", "required", "")
+	}
+	if lonStr == "" {
+		return filter, pkgerrors.InvalidField("lon", "required", "")
+	}
+
+	lat, err := parseCoordinate("lat", latStr, 90)
+	if err != nil {
+		return filter, err
+	}
+	lon, err := parseCoordinate("lon", lonStr, 180)
+	if err != nil {
+		return filter, err
+	}
+	filter.Latitude, filter.Longitude = &lat, &lon
+	return filter, nil
+}
+
+func parseCoordinate(name, s string, limit float64) (float64, error) {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(v) {
+		return 0, pkgerrors.InvalidField(name, "type", "float64")
+	}
+	if v < -limit {
+		return 0, pkgerrors.InvalidField(name, "gte", strconv.FormatFloat(-limit, 'f', -1, 64))
+	}
+	if v > limit {
+		return 0, pkgerrors.InvalidField(name, "lte", strconv.FormatFloat(limit, 'f', -1, 64))
+	}
+	return v, nil
+}
+
+func parseID(c *gin.Context) (int64, error) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, pkgerrors.InvalidField("id", "integer", "")
+	}
+	return id, nil
+}
@@ -0,0 +1,211 @@
+package promotion
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/Cora23tt/order_service/internal/usecase/promotion"
+	"github.com/Cora23tt/order_service/pkg/enums"
+	pkgerrors "github.com/Cora23tt/order_service/pkg/errors"
+)
+
+type Handler struct {
+	service *promotion.Service
+	log     *zap.SugaredLogger
+}
+
+func NewHandler(service *promotion.Service, log *zap.SugaredLogger) *Handler {
+	return &Handler{service: service, log: log}
+}
+
+// PromotionRequest is the full state of a promotion. Omitted limits and
+// validity bounds mean no limit; empty product_ids means every product.
+type PromotionRequest struct {
+	Code           string     `json:"code" binding:"required,max=64" example:"SPRING10"`
+	Description    string     `json:"description" example:"Весенняя скидка"`
+	DiscountType   string     `json:"discount_type" binding:"required,oneof=percent fixed" example:"percent"`
+	DiscountValue  int64      `json:"discount_value" binding:"required,gt=0" example:"10"`
+	MinOrderAmount int64      `json:"min_order_amount" binding:"gte=0" example:"50000"`
+	StartsAt       *time.Time `json:"starts_at" example:"2026-03-01T00:00:00+05:00"`
+	EndsAt         *time.Time `json:"ends_at" example:"2026-06-01T00:00:00+05:00"`
+	UsageLimit     *int64     `json:"usage_limit" binding:"omitempty,gt=0" example:"1000"`
+	PerUserLimit   *int64     `json:"per_user_limit" binding:"omitempty,gt=0" example:"1"`
+	Active         *bool      `json:"active" example:"true"`
+	ProductIDs     []int64    `json:"product_ids" example:"4,7"`
+}
+
+func (r PromotionRequest) input() promotion.PromotionInput {
+	active := true
+	if r.Active != nil {
+		active = *r.Active
+	}
+	return promotion.PromotionInput{
+		Code:           r.Code,
+		Description:    r.Description,
+		DiscountType:   enums.DiscountType(r.DiscountType),
+		DiscountValue:  r.DiscountValue,
+		MinOrderAmount: r.MinOrderAmount,
+		StartsAt:       r.StartsAt,
+		EndsAt:         r.EndsAt,
+		UsageLimit:     r.UsageLimit,
+		PerUserLimit:   r.PerUserLimit,
+		Active:         active,
+		ProductIDs:     r.ProductIDs,
+	}
+}
+
+// List godoc
+// @Summary Список акций (admin)
+// @Description Возвращает все промокоды с числом использований
+// @Tags promotions
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} promotion.Promotion
+// @Failure 401 {object} errors.Problem "unauthorized"
+// @Failure 403 {object} errors.Problem "forbidden"
+// @Failure 500 {object} errors.Problem "internal_error"
+// @Router /api/v1/admin/promotions/ [get]
+func (h *Handler) List(c *gin.Context) {
+	promotions, err := h.service.List(c.Request.Context())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, promotions)
+}
+
+// Get godoc
+// @Summary Акция по ID (admin)
+// @Description Возвращает промокод по ID
+// @Tags promotions
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "ID акции"
+// @Success 200 {object} promotion.Promotion
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 404 {object} errors.Problem "promotion_not_found"
+// @Failure 500 {object} errors.Problem "internal_error"
+// @Router /api/v1/admin/promotions/{id} [get]
+func (h *Handler) Get(c *gin.Context) {
+	id, err := parseID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	p, err := h.service.Get(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, p)
+}
+
+// Create godoc
+// @Summary Создание акции (admin)
+// @Description Создаёт промокод с процентной или фиксированной скидкой. Код не зависит от регистра
+// @Tags promotions
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body PromotionRequest true "Акция"
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор запроса с тем же ключом вернёт сохранённый ответ"
+// @Success 201 {object} promotion.Promotion
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 404 {object} errors.Problem "product_not_found"
+// @Failure 409 {object} errors.Problem "promotion_exists"
+// @Failure 500 {object} errors.Problem "internal_error"
+// @Router /api/v1/admin/promotions/ [post]
+func (h *Handler) Create(c *gin.Context) {
+	var req PromotionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(pkgerrors.Validation(err))
+		return
+	}
+
+	p, err := h.service.Create(c.Request.Context(), req.input())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, p)
+}
+
+// Update godoc
+// @Summary Изменение акции (admin)
+// @Description Полностью заменяет параметры промокода. Уже оформленные заказы сохраняют свою скидку
+// @Tags promotions
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "ID акции"
+// @Param request body PromotionRequest true "Акция"
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор запроса с тем же ключом вернёт сохранённый ответ"
+// @Success 200 {object} promotion.Promotion
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 404 {object} errors.Problem "promotion_not_found, product_not_found"
+// @Failure 409 {object} errors.Problem "promotion_exists"
+// @Failure 500 {object} errors.Problem "internal_error"
+// @Router /api/v1/admin/promotions/{id} [put]
+func (h *Handler) Update(c *gin.Context) {
+	id, err := parseID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req PromotionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(pkgerrors.Validation(err))
+		return
+	}
+
+	p, err := h.service.Update(c.Request.Context(), id, req.input())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, p)
+}
+
+// Delete godoc
+// @Summary Удаление акции (admin)
+// @Description Удаляет промокод. Скидки в уже оформленных заказах сохраняются
+// @Tags promotions
+// @Security BearerAuth
+// @Param id path int true "ID акции"
+// @Success 204 "No Content"
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 404 {object} errors.Problem "promotion_not_found"
+// @Failure 500 {object} errors.Problem "internal_error"
+// @Router /api/v1/admin/promotions/{id} [delete]
+func (h *Handler) Delete(c *gin.Context) {
+	id, err := parseID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	if err := h.service.Delete(c.Request.Context(), id); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func parseID(c *gin.Context) (int64, error) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, pkgerrors.InvalidField("id", "integer", "")
+	}
+	return id, nil
+}
@@ -0,0 +1,53 @@
+package receipt
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/Cora23tt/order_service/internal/usecase/receipt"
+	pkgerrors "github.com/Cora23tt/order_service/pkg/errors"
+)
+
+type Handler struct {
+	service *receipt.Service
+	log     *zap.SugaredLogger
+}
+
+func NewHandler(service *receipt.Service, log *zap.SugaredLogger) *Handler {
+	return &Handler{service: service, log: log}
+}
+
+// Get godoc
+// @Summary Чек заказа
+// @Description Возвращает чек оплаченного заказа: PDF или, если PDF не удалось сформировать, HTML. В чеке перечислены позиции, цены, НДС, пункт выдачи и QR-код с номером заказа. Пользователь может скачать только чеки своих заказов
+// @Tags orders
+// @Security BearerAuth
+// @Produce application/pdf
+// @Produce text/html
+// @Param id path int true "ID заказа"
+// @Success 200 {file} file "Чек"
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 401 {object} errors.Problem "unauthorized"
+// @Failure 404 {object} errors.Problem "order_not_found, receipt_not_found"
+// @Failure 500 {object} errors.Problem "internal_error"
+// @Router /api/v1/orders/{id}/receipt [get]
+func (h *Handler) Get(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		_ = c.Error(pkgerrors.InvalidField("id", "integer", ""))
+		return
+	}
+
+	doc, err := h.service.Get(c.Request.Context(), id, c.GetInt64("userID"), c.GetString("role"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", doc.FileName))
+	c.Data(http.StatusOK, doc.ContentType, doc.Data)
+}
@@ -0,0 +1,303 @@
+package returns
+
+import (
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	returnRepo "github.com/Cora23tt/order_service/internal/repository/returns"
+	"github.com/Cora23tt/order_service/internal/usecase/order"
+	"github.com/Cora23tt/order_service/internal/usecase/returns"
+	"github.com/Cora23tt/order_service/pkg/config"
+	"github.com/Cora23tt/order_service/pkg/enums"
+	pkgerrors "github.com/Cora23tt/order_service/pkg/errors"
+	"github.com/Cora23tt/order_service/pkg/logger"
+)
+
+// returnStatuses lists the accepted status values in validator oneof format.
+const returnStatuses = "requested approved rejected received refunded replaced"
+
+// photoExts lists the accepted photo file extensions.
+var photoExts = []string{".jpg", ".jpeg", ".png", ".webp"}
+
+type Handler struct {
+	service      *returns.Service
+	log          *zap.SugaredLogger
+	maxPhotoSize int64
+}
+
+func NewHandler(service *returns.Service, log *zap.SugaredLogger, cfg *config.Config) *Handler {
+	return &Handler{service: service, log: log, maxPhotoSize: cfg.Upload.MaxReturnPhotoSize}
+}
+
+type CreateReturnRequest struct {
+	OrderID int64                   `json:"order_id" binding:"required" example:"15"`
+	Items   []order.RefundItemInput `json:"items" binding:"required,min=1,dive"`
+	Reason  string                  `json:"reason" binding:"required,max=1000" example:"товар повреждён"`
+}
+
+// Create godoc
+// @Summary Заявка на возврат
+// @Description Открывает заявку на возврат позиций доставленного заказа. Заявку можно подать в течение RETURN_WINDOW после доставки; фотографии прикладываются отдельным запросом
+// @Tags returns
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body CreateReturnRequest true "Заявка"
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор запроса с тем же ключом вернёт сохранённый ответ"
+// @Success 201 {object} returns.Return
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 401 {object} errors.Problem "unauthorized"
+// @Failure 404 {object} errors.Problem "order_not_found, order_item_not_found"
+// @Failure 409 {object} errors.Problem "return_not_allowed, return_window_expired"
+// @Failure 422 {object} errors.Problem "return_quantity_exceeded"
+// @Failure 500 {object} errors.Problem "internal_error"
+// @Router /api/v1/returns [post]
+func (h *Handler) Create(c *gin.Context) {
+	var req CreateReturnRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(pkgerrors.Validation(err))
+		return
+	}
+
+	ret, err := h.service.Create(c.Request.Context(), returns.CreateInput{
+		OrderID: req.OrderID,
+		Items:   req.Items,
+		Reason:  req.Reason,
+		Actor:   order.Actor{UserID: c.GetInt64("userID"), Role: c.GetString("role")},
+	})
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, ret)
+}
+
+// List godoc
+// @Summary Заявки на возврат
+// @Description Возвращает заявки на возврат, новые первыми. Пользователь видит только свои заявки, администратор — все
+// @Tags returns
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "Статус" Enums(requested, approved, rejected, received, refunded, replaced)
+// @Param order_id query int false "ID заказа"
+// @Success 200 {array} returns.Return
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 401 {object} errors.Problem "unauthorized"
+// @Failure 500 {object} errors.Problem "internal_error"
+// @Router /api/v1/returns [get]
+func (h *Handler) List(c *gin.Context) {
+	var filter returnRepo.ListFilter
+	if statusStr := c.Query("status"); statusStr != "" {
+		status := enums.ReturnStatus(statusStr)
+		if !status.IsValid() {
+			_ = c.Error(pkgerrors.InvalidField("status", "oneof", returnStatuses))
+			return
+		}
+		filter.Status = &status
+	}
+	if orderStr := c.Query("order_id"); orderStr != "" {
+		orderID, err := strconv.ParseInt(orderStr, 10, 64)
+		if err != nil {
+			_ = c.Error(pkgerrors.InvalidField("order_id", "integer", ""))
+			return
+		}
+		filter.OrderID = &orderID
+	}
+
+	list, err := h.service.List(c.Request.Context(), filter, c.GetInt64("userID"), c.GetString("role"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// Get godoc
+// @Summary Заявка на возврат
+// @Description Возвращает заявку с позициями, фотографиями и историей статусов
+// @Tags returns
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "ID заявки"
+// @Success 200 {object} returns.Return
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 401 {object} errors.Problem "unauthorized"
+// @Failure 404 {object} errors.Problem "return_not_found"
+// @Failure 500 {object} errors.Problem "internal_error"
+// @Router /api/v1/returns/{id} [get]
+func (h *Handler) Get(c *gin.Context) {
+	id, err := parseID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	ret, err := h.service.Get(c.Request.Context(), id, c.GetInt64("userID"), c.GetString("role"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ret)
+}
+
+// AddPhoto godoc
+// @Summary Фотография к заявке на возврат
+// @Description Прикладывает фотографию товара к своей заявке, пока она ожидает решения. Количество фотографий ограничено RETURN_MAX_PHOTOS
+// @Tags returns
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "ID заявки"
+// @Param photo formData file true "Фотография (jpg, png, webp)"
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор запроса с тем же ключом вернёт сохранённый ответ"
+// @Success 201 {object} returns.Photo
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 401 {object} errors.Problem "unauthorized"
+// @Failure 404 {object} errors.Problem "return_not_found"
+// @Failure 409 {object} errors.Problem "return_not_editable"
+// @Failure 413 {object} errors.Problem "file_too_large"
+// @Failure 422 {object} errors.Problem "return_photo_limit"
+// @Failure 500 {object} errors.Problem "internal_error"
+// @Router /api/v1/returns/{id}/photos [post]
+func (h *Handler) AddPhoto(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context(), h.log)
+
+	id, err := parseID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	file, err := c.FormFile("photo")
+	if err != nil {
+		_ = c.Error(pkgerrors.InvalidField("photo", "required", ""))
+		return
+	}
+	if file.Size > h.maxPhotoSize {
+		_ = c.Error(pkgerrors.ErrFileTooLarge)
+		return
+	}
+	ext := strings.ToLower(filepath.Ext(file.Filename))
+	if !slices.Contains(photoExts, ext) {
+		_ = c.Error(pkgerrors.InvalidField("photo", "oneof", strings.Join(photoExts, " ")))
+		return
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		log.Errorw("failed to open uploaded photo", "error", err)
+		_ = c.Error(pkgerrors.ErrInternal)
+		return
+	}
+	defer src.Close()
+
+	photo, err := h.service.AddPhoto(c.Request.Context(), id, c.GetInt64("userID"), ext, src)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, photo)
+}
+
+// GetPhoto godoc
+// @Summary Фотография заявки на возврат
+// @Description Возвращает файл фотографии. Пользователь может скачать только фотографии своих заявок
+// @Tags returns
+// @Security BearerAuth
+// @Produce image/jpeg
+// @Produce image/png
+// @Produce image/webp
+// @Param id path int true "ID заявки"
+// @Param photo_id path int true "ID фотографии"
+// @Success 200 {file} file "Фотография"
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 401 {object} errors.Problem "unauthorized"
+// @Failure 404 {object} errors.Problem "return_not_found, photo_not_found"
+// @Failure 500 {object} errors.Problem "internal_error"
+// @Router /api/v1/returns/{id}/photos/{photo_id} [get]
+func (h *Handler) GetPhoto(c *gin.Context) {
+	id, err := parseID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	photoID, err := parseID(c, "photo_id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	path, err := h.service.PhotoPath(c.Request.Context(), id, photoID, c.GetInt64("userID"), c.GetString("role"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.File(path)
+}
+
+type UpdateReturnStatusRequest struct {
+	Status string `json:"status" binding:"required,oneof=approved rejected received refunded replaced" example:"approved"`
+	Reason string `json:"reason,omitempty" binding:"max=500" example:"товар принят на склад"`
+}
+
+// UpdateStatus godoc
+// @Summary Обработка заявки на возврат (admin)
+// @Description Переводит заявку на следующий шаг: requested → approved или rejected, approved → received или rejected, received → refunded или replaced. Для отказа причина обязательна. refunded возвращает деньги за позиции заявки через платёжную систему и возвращает товары на склад, replaced создаёт бесплатный заказ на замену в статусе paid
+// @Tags returns
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "ID заявки"
+// @Param request body UpdateReturnStatusRequest true "Новый статус"
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор запроса с тем же ключом вернёт сохранённый ответ"
+// @Success 200 {object} returns.Return
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 401 {object} errors.Problem "unauthorized"
+// @Failure 403 {object} errors.Problem "forbidden"
+// @Failure 404 {object} errors.Problem "return_not_found"
+// @Failure 409 {object} errors.Problem "invalid_status_transition, order_not_refundable, insufficient_stock"
+// @Failure 422 {object} errors.Problem "refund_quantity_exceeded, refund_exceeds_paid, return_quantity_exceeded"
+// @Failure 502 {object} errors.Problem "payment_provider_error, refund_declined"
+// @Failure 500 {object} errors.Problem "internal_error"
+// @Router /api/v1/admin/returns/{id} [put]
+func (h *Handler) UpdateStatus(c *gin.Context) {
+	id, err := parseID(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req UpdateReturnStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(pkgerrors.Validation(err))
+		return
+	}
+
+	actor := order.Actor{UserID: c.GetInt64("userID"), Role: c.GetString("role")}
+	ret, err := h.service.UpdateStatus(c.Request.Context(), id, enums.ReturnStatus(req.Status), actor, req.Reason)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ret)
+}
+
+func parseID(c *gin.Context, name string) (int64, error) {
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil {
+		return 0, pkgerrors.InvalidField(name, "integer", "")
+	}
+	return id, nil
+}
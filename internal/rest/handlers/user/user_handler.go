@@ -1,25 +1,39 @@
 package user
 
 import (
-	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 
 	"github.com/Cora23tt/order_service/internal/usecase/user"
+	"github.com/Cora23tt/order_service/pkg/config"
 	pkgerrors "github.com/Cora23tt/order_service/pkg/errors"
+	"github.com/Cora23tt/order_service/pkg/i18n"
+	"github.com/Cora23tt/order_service/pkg/logger"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
 type Handler struct {
-	service *user.Service
-	log     *zap.SugaredLogger
+	service       *user.Service
+	log           *zap.SugaredLogger
+	avatarDir     string
+	maxAvatarSize int64
 }
 
-func NewHandler(service *user.Service, log *zap.SugaredLogger) *Handler {
-	return &Handler{service: service, log: log}
+func NewHandler(service *user.Service, log *zap.SugaredLogger, cfg *config.Config) (*Handler, error) {
+	avatarDir := filepath.Join(cfg.Upload.Dir, "avatars")
+	if err := os.MkdirAll(avatarDir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("create avatar dir: %w", err)
+	}
+	return &Handler{
+		service:       service,
+		log:           log,
+		avatarDir:     avatarDir,
+		maxAvatarSize: cfg.Upload.MaxAvatarSize,
+	}, nil
 }
 
 // GetProfile godoc
@@ -29,26 +43,21 @@ func NewHandler(service *user.Service, log *zap.SugaredLogger) *Handler {
 // @Security BearerAuth
 // @Produce json
 // @Success 200 {object} user.Profile "Профиль пользователя"
-// @Failure 401 {object} map[string]string "unauthorized"
-// @Failure 404 {object} map[string]string "user not found"
-// @Failure 500 {object} map[string]string "internal error"
+// @Failure 401 {object} errors.Problem "unauthorized"
+// @Failure 404 {object} errors.Problem "user_not_found"
+// @Failure 500 {object} errors.Problem "internal_error"
 // @Router /api/v1/me [get]
 func (h *Handler) GetProfile(c *gin.Context) {
 	userIDRaw, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		_ = c.Error(pkgerrors.ErrUnauthorized)
 		return
 	}
 	userID := userIDRaw.(int64)
 
 	profile, err := h.service.GetProfile(c.Request.Context(), userID)
 	if err != nil {
-		if errors.Is(err, pkgerrors.ErrNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
-			return
-		}
-		h.log.Errorw("get profile failed", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		_ = c.Error(err)
 		return
 	}
 
@@ -58,26 +67,32 @@ func (h *Handler) GetProfile(c *gin.Context) {
 type UpdateProfileRequest struct {
 	PINFL     *string `json:"pinfl,omitempty"`
 	AvatarURL *string `json:"avatar_url,omitempty"`
+	Language  *string `json:"language,omitempty"`
 }
 
 // UpdateProfile godoc
 // @Summary Обновление профиля пользователя
-// @Description Обновляет PINFL и аватар текущего пользователя
+// @Description Обновляет PINFL, аватар и язык сообщений текущего пользователя
 // @Tags User
 // @Security BearerAuth
 // @Accept multipart/form-data
 // @Produce json
 // @Param pinfl formData string false "ПИНФЛ пользователя"
 // @Param avatar formData file false "Аватар (изображение)"
+// @Param language formData string false "Язык сообщений API" Enums(ru, uz, en)
 // @Success 200 {object} map[string]string "profile updated"
-// @Failure 401 {object} map[string]string "unauthorized"
-// @Failure 404 {object} map[string]string "user not found"
-// @Failure 500 {object} map[string]string "internal error"
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 401 {object} errors.Problem "unauthorized"
+// @Failure 404 {object} errors.Problem "user_not_found"
+// @Failure 413 {object} errors.Problem "file_too_large"
+// @Failure 500 {object} errors.Problem "internal_error"
 // @Router /api/v1/me [patch]
 func (h *Handler) UpdateProfile(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context(), h.log)
+
 	userIDRaw, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		_ = c.Error(pkgerrors.ErrUnauthorized)
 		return
 	}
 	userID := userIDRaw.(int64)
@@ -87,15 +102,31 @@ func (h *Handler) UpdateProfile(c *gin.Context) {
 		pinfl = val
 	}
 
+	var language *string
+	if val := c.PostForm("language"); val != "" {
+		lang, ok := i18n.Parse(val)
+		if !ok {
+			_ = c.Error(pkgerrors.InvalidField("language", "oneof", i18n.Supported))
+			return
+		}
+		code := string(lang)
+		language = &code
+	}
+
 	file, err := c.FormFile("avatar")
 	var avatarPath *string
 	if err == nil {
+		if file.Size > h.maxAvatarSize {
+			_ = c.Error(pkgerrors.ErrFileTooLarge)
+			return
+		}
+
 		ext := filepath.Ext(file.Filename)
-		baseDir := filepath.Join(".", "web", "avatars", fmt.Sprintf("%d", userID))
+		baseDir := filepath.Join(h.avatarDir, fmt.Sprintf("%d", userID))
 
 		if err := os.MkdirAll(baseDir, os.ModePerm); err != nil {
-			h.log.Errorw("failed to create avatar dir", "error", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot create folder"})
+			log.Errorw("failed to create avatar dir", "error", err)
+			_ = c.Error(pkgerrors.ErrInternal)
 			return
 		}
 
@@ -103,8 +134,8 @@ func (h *Handler) UpdateProfile(c *gin.Context) {
 		fullPath := filepath.Join(baseDir, filename)
 
 		if err := c.SaveUploadedFile(file, fullPath); err != nil {
-			h.log.Errorw("failed to save avatar", "error", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot save file"})
+			log.Errorw("failed to save avatar", "error", err)
+			_ = c.Error(pkgerrors.ErrInternal)
 			return
 		}
 		url := fmt.Sprintf("/profile/%d/photo", userID)
@@ -116,13 +147,8 @@ func (h *Handler) UpdateProfile(c *gin.Context) {
 		pinflPtr = &pinfl
 	}
 
-	if err := h.service.UpdateProfile(c.Request.Context(), userID, pinflPtr, avatarPath); err != nil {
-		if errors.Is(err, pkgerrors.ErrNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
-			return
-		}
-		h.log.Errorw("update profile failed", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+	if err := h.service.UpdateProfile(c.Request.Context(), userID, pinflPtr, avatarPath, language); err != nil {
+		_ = c.Error(err)
 		return
 	}
 
@@ -136,20 +162,19 @@ func (h *Handler) UpdateProfile(c *gin.Context) {
 // @Security BearerAuth
 // @Produce json
 // @Success 200 {array} user.Profile
-// @Failure 403 {object} map[string]string "forbidden"
-// @Failure 500 {object} map[string]string "internal error"
+// @Failure 403 {object} errors.Problem "forbidden"
+// @Failure 500 {object} errors.Problem "internal_error"
 // @Router /api/v1/admin/users [get]
 func (h *Handler) ListUsers(c *gin.Context) {
 	role, exists := c.Get("role")
 	if !exists || role != "admin" {
-		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		_ = c.Error(pkgerrors.ErrForbidden)
 		return
 	}
 
 	users, err := h.service.ListAllUsers(c.Request.Context())
 	if err != nil {
-		h.log.Errorw("list users failed", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		_ = c.Error(err)
 		return
 	}
 
@@ -165,18 +190,24 @@ func (h *Handler) ListUsers(c *gin.Context) {
 // @Produce image/webp
 // @Param id path int true "ID пользователя"
 // @Success 200 {file} file "Изображение аватара"
-// @Failure 404 {object} map[string]string "photo not found"
+// @Failure 400 {object} errors.Problem "validation_failed"
+// @Failure 404 {object} errors.Problem "photo_not_found"
 // @Router /profile/{id}/photo [get]
 func (h *Handler) GetProfilePhoto(c *gin.Context) {
 	id := c.Param("id")
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		_ = c.Error(pkgerrors.InvalidField("id", "integer", ""))
+		return
+	}
+
 	exts := []string{".jpg", ".jpeg", ".png", ".webp"}
 	for _, ext := range exts {
-		path := fmt.Sprintf("web/avatars/%s/profile%s", id, ext)
+		path := filepath.Join(h.avatarDir, id, "profile"+ext)
 		if _, err := os.Stat(path); err == nil {
 			c.File(path)
 			return
 		}
 	}
 
-	c.JSON(http.StatusNotFound, gin.H{"error": "photo not found"})
+	_ = c.Error(pkgerrors.ErrPhotoNotFound)
 }
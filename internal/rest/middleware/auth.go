@@ -1,44 +1,58 @@
 package middleware
 
 import (
-	"net/http"
+	"context"
 	"strings"
 
 	"slices"
 
 	"github.com/gin-gonic/gin"
+
+	pkgerrors "github.com/Cora23tt/order_service/pkg/errors"
+	"github.com/Cora23tt/order_service/pkg/i18n"
+	"github.com/Cora23tt/order_service/pkg/logger"
 )
 
 type AuthValidator interface {
-	Validate(token string) (userID int64, role string, err error)
+	Validate(ctx context.Context, token string) (userID int64, role string, sessionID string, language string, err error)
 }
 
 func (m *Middleware) AuthWithRoles(allowedRoles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tokenString := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
 		if tokenString == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing token"})
+			_ = c.Error(pkgerrors.ErrUnauthorized)
 			c.Abort()
 			return
 		}
 
-		userID, role, err := m.validator.Validate(tokenString)
+		ctx := c.Request.Context()
+		log := logger.FromContext(ctx, m.logger)
+
+		userID, role, sessionID, language, err := m.validator.Validate(ctx, tokenString)
 		if err != nil {
-			m.logger.Errorw("token parse error", "err", err)
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			log.Errorw("token parse error", "err", err)
+			_ = c.Error(pkgerrors.ErrUnauthorized)
 			c.Abort()
 			return
 		}
 
 		if !slices.Contains(allowedRoles, role) {
-			m.logger.Warnw("forbidden access", "userID", userID, "role", role)
-			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+			log.Warnw("forbidden access", "userID", userID, "role", role)
+			_ = c.Error(pkgerrors.ErrForbidden)
 			c.Abort()
 			return
 		}
 
 		c.Set("userID", userID)
 		c.Set("role", role)
+		c.Set("sessionID", sessionID)
+
+		ctx = logger.WithContext(ctx, log.With("user_id", userID))
+		if lang, ok := i18n.Parse(language); ok {
+			ctx = i18n.WithLang(ctx, lang)
+		}
+		c.Request = c.Request.WithContext(ctx)
 		c.Next()
 	}
 }
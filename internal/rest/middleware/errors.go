@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+
+	pkgerrors "github.com/Cora23tt/order_service/pkg/errors"
+	"github.com/Cora23tt/order_service/pkg/i18n"
+	"github.com/Cora23tt/order_service/pkg/logger"
+)
+
+// ErrorHandler renders the last error attached with c.Error as an RFC 7807
+// problem+json response in the language of the request. Handlers only call
+// c.Error and return; errors that are not a *pkgerrors.Error are answered as
+// internal errors.
+func (m *Middleware) ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		err := c.Errors.Last().Err
+		apiErr := pkgerrors.From(err)
+
+		log := logger.FromContext(c.Request.Context(), m.logger)
+		if apiErr.Status >= 500 {
+			log.Errorw("request failed", "error", err)
+		} else {
+			log.Debugw("request rejected", "code", apiErr.Code, "error", err)
+		}
+
+		m.writeProblem(c, apiErr)
+	}
+}
+
+// Recovery answers panics with an internal error problem.
+func (m *Middleware) Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered any) {
+		logger.FromContext(c.Request.Context(), m.logger).Errorw("panic recovered", "panic", recovered)
+		m.writeProblem(c, pkgerrors.ErrInternal)
+	})
+}
+
+func (m *Middleware) writeProblem(c *gin.Context, apiErr *pkgerrors.Error) {
+	lang := i18n.FromContext(c.Request.Context())
+
+	problem := apiErr.Problem(c.Request.URL.Path, c.GetString("requestID"))
+	problem.Detail = i18n.Message(lang, apiErr.Code, problem.Detail)
+	if len(apiErr.Fields) > 0 {
+		problem.Errors = make([]pkgerrors.FieldError, len(apiErr.Fields))
+		for i, f := range apiErr.Fields {
+			f.Message = i18n.FieldMessage(lang, f.Field, f.Rule, f.Param)
+			problem.Errors[i] = f
+		}
+	}
+
+	c.Header("Content-Type", pkgerrors.ProblemContentType)
+	c.Header("Content-Language", string(lang))
+	c.AbortWithStatusJSON(apiErr.Status, problem)
+}
+
+// UseJSONFieldNames makes binding validation errors refer to fields by their
+// JSON names instead of the Go struct field names.
+func UseJSONFieldNames() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		switch name {
+		case "-":
+			return ""
+		case "":
+			return f.Name
+		}
+		return name
+	})
+}
@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Cora23tt/order_service/internal/usecase/idempotency"
+	pkgerrors "github.com/Cora23tt/order_service/pkg/errors"
+	"github.com/Cora23tt/order_service/pkg/logger"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// maxIdempotencyKeyLength is the size of the key column.
+const maxIdempotencyKeyLength = 255
+
+type IdempotencyStore interface {
+	Begin(ctx context.Context, userID int64, key, fingerprint string) (*idempotency.Response, string, error)
+	Complete(ctx context.Context, userID int64, key, token string, resp idempotency.Response) error
+	Release(ctx context.Context, userID int64, key, token string) error
+}
+
+// Idempotency makes POST and PUT requests sent with an Idempotency-Key header
+// safe to retry. The first successful response is stored for the user and
+// key and replayed for repeated requests; reusing the key for a request with
+// a different method, path or body is rejected. Failed requests are not
+// stored, so they can be retried with the same key. The body is read into
+// memory and limited to the configured size. It must run after
+// AuthWithRoles.
+func (m *Middleware) Idempotency() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		userID := c.GetInt64("userID")
+		if key == "" || userID == 0 || (c.Request.Method != http.MethodPost && c.Request.Method != http.MethodPut) {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			_ = c.Error(pkgerrors.InvalidField(IdempotencyKeyHeader, "max", strconv.Itoa(maxIdempotencyKeyLength)))
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, m.maxBodySize))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				_ = c.Error(pkgerrors.ErrBodyTooLarge)
+			} else {
+				_ = c.Error(pkgerrors.ErrInvalidBody)
+			}
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		resp, token, err := m.idempotency.Begin(ctx, userID, key, fingerprint(c.Request.Method, c.Request.URL.Path, body))
+		if err != nil {
+			_ = c.Error(err)
+			c.Abort()
+			return
+		}
+		if resp != nil {
+			c.Header(IdempotentReplayedHeader, "true")
+			c.Data(resp.StatusCode, resp.ContentType, resp.Body)
+			c.Abort()
+			return
+		}
+
+		// The outcome is stored even if the client has gone away meanwhile.
+		ctx = context.WithoutCancel(ctx)
+		log := logger.FromContext(ctx, m.logger)
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			if err := m.idempotency.Release(ctx, userID, key, token); err != nil {
+				log.Errorw("release idempotency key failed", "error", err)
+			}
+		}()
+
+		c.Next()
+
+		status := c.Writer.Status()
+		if len(c.Errors) > 0 || status < 200 || status >= 300 {
+			return
+		}
+		err = m.idempotency.Complete(ctx, userID, key, token, idempotency.Response{
+			StatusCode:  status,
+			ContentType: c.Writer.Header().Get("Content-Type"),
+			Body:        recorder.body.Bytes(),
+		})
+		if err != nil {
+			log.Errorw("store idempotent response failed", "error", err)
+			return
+		}
+		completed = true
+	}
+}
+
+// fingerprint identifies a request by its method, path and body.
+func fingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write([]byte(path))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder keeps a copy of the response body written through it.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
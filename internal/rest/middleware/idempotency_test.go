@@ -0,0 +1,246 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/Cora23tt/order_service/internal/usecase/idempotency"
+	pkgerrors "github.com/Cora23tt/order_service/pkg/errors"
+)
+
+// fakeIdempotencyStore keeps keys in memory the way the idempotency service
+// keeps them in the database.
+type fakeIdempotencyStore struct {
+	records  map[string]*fakeIdempotencyRecord
+	tokens   int
+	released int
+}
+
+type fakeIdempotencyRecord struct {
+	fingerprint string
+	token       string
+	resp        *idempotency.Response
+}
+
+func newFakeIdempotencyStore() *fakeIdempotencyStore {
+	return &fakeIdempotencyStore{records: map[string]*fakeIdempotencyRecord{}}
+}
+
+func (s *fakeIdempotencyStore) id(userID int64, key string) string {
+	return strconv.FormatInt(userID, 10) + "/" + key
+}
+
+func (s *fakeIdempotencyStore) Begin(_ context.Context, userID int64, key, fingerprint string) (*idempotency.Response, string, error) {
+	rec, ok := s.records[s.id(userID, key)]
+	if !ok {
+		s.tokens++
+		token := strconv.Itoa(s.tokens)
+		s.records[s.id(userID, key)] = &fakeIdempotencyRecord{fingerprint: fingerprint, token: token}
+		return nil, token, nil
+	}
+	if rec.fingerprint != fingerprint {
+		return nil, "", pkgerrors.ErrIdempotencyKeyReused
+	}
+	if rec.resp == nil {
+		return nil, "", pkgerrors.ErrIdempotencyInProgress
+	}
+	return rec.resp, "", nil
+}
+
+func (s *fakeIdempotencyStore) Complete(_ context.Context, userID int64, key, token string, resp idempotency.Response) error {
+	rec, ok := s.records[s.id(userID, key)]
+	if !ok || rec.token != token {
+		return pkgerrors.ErrNotFound
+	}
+	rec.resp = &resp
+	return nil
+}
+
+func (s *fakeIdempotencyStore) Release(_ context.Context, userID int64, key, token string) error {
+	rec, ok := s.records[s.id(userID, key)]
+	if !ok || rec.token != token {
+		return nil
+	}
+	delete(s.records, s.id(userID, key))
+	s.released++
+	return nil
+}
+
+// idempotencyRouter serves POST /orders and PUT /orders with a handler that
+// echoes the body, or fails with ErrInvalidInput if the body is "fail".
+func idempotencyRouter(store IdempotencyStore, calls *int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	m := &Middleware{logger: zap.NewNop().Sugar(), idempotency: store, maxBodySize: 16}
+
+	r := gin.New()
+	r.Use(m.ErrorHandler(), func(c *gin.Context) {
+		if id := c.GetHeader("X-User"); id != "" {
+			userID, _ := strconv.ParseInt(id, 10, 64)
+			c.Set("userID", userID)
+		}
+	}, m.Idempotency())
+	handler := func(c *gin.Context) {
+		*calls++
+		body, _ := io.ReadAll(c.Request.Body)
+		if string(body) == "fail" {
+			_ = c.Error(pkgerrors.ErrInvalidInput)
+			return
+		}
+		c.Data(http.StatusCreated, "text/plain", []byte("created "+string(body)+" #"+strconv.Itoa(*calls)))
+	}
+	r.POST("/orders", handler)
+	r.PUT("/orders", handler)
+	r.GET("/orders", handler)
+	return r
+}
+
+type idempotencyRequest struct {
+	method, path, key, user, body string
+}
+
+func (req idempotencyRequest) do(r http.Handler) *httptest.ResponseRecorder {
+	httpReq := httptest.NewRequest(req.method, req.path, strings.NewReader(req.body))
+	if req.key != "" {
+		httpReq.Header.Set(IdempotencyKeyHeader, req.key)
+	}
+	if req.user != "" {
+		httpReq.Header.Set("X-User", req.user)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httpReq)
+	return w
+}
+
+func TestIdempotency(t *testing.T) {
+	first := idempotencyRequest{http.MethodPost, "/orders", "k1", "7", "a"}
+
+	tests := []struct {
+		name       string
+		setup      []idempotencyRequest
+		req        idempotencyRequest
+		wantStatus int
+		wantBody   string
+		wantCalls  int
+		replayed   bool
+	}{
+		{
+			name:       "first request",
+			req:        first,
+			wantStatus: http.StatusCreated,
+			wantBody:   "created a #1",
+			wantCalls:  1,
+		},
+		{
+			name:       "repeated request is replayed",
+			setup:      []idempotencyRequest{first},
+			req:        first,
+			wantStatus: http.StatusCreated,
+			wantBody:   "created a #1",
+			wantCalls:  1,
+			replayed:   true,
+		},
+		{
+			name:       "different body",
+			setup:      []idempotencyRequest{first},
+			req:        idempotencyRequest{http.MethodPost, "/orders", "k1", "7", "b"},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCalls:  1,
+		},
+		{
+			name:       "different method",
+			setup:      []idempotencyRequest{first},
+			req:        idempotencyRequest{http.MethodPut, "/orders", "k1", "7", "a"},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCalls:  1,
+		},
+		{
+			name:       "same key of another user",
+			setup:      []idempotencyRequest{first},
+			req:        idempotencyRequest{http.MethodPost, "/orders", "k1", "8", "a"},
+			wantStatus: http.StatusCreated,
+			wantBody:   "created a #2",
+			wantCalls:  2,
+		},
+		{
+			name:       "failed request can be retried",
+			setup:      []idempotencyRequest{{http.MethodPost, "/orders", "k1", "7", "fail"}},
+			req:        idempotencyRequest{http.MethodPost, "/orders", "k1", "7", "fail"},
+			wantStatus: http.StatusBadRequest,
+			wantCalls:  2,
+		},
+		{
+			name:       "without key",
+			setup:      []idempotencyRequest{{http.MethodPost, "/orders", "", "7", "a"}},
+			req:        idempotencyRequest{http.MethodPost, "/orders", "", "7", "a"},
+			wantStatus: http.StatusCreated,
+			wantBody:   "created a #2",
+			wantCalls:  2,
+		},
+		{
+			name:       "GET is not stored",
+			setup:      []idempotencyRequest{{http.MethodGet, "/orders", "k1", "7", ""}},
+			req:        idempotencyRequest{http.MethodGet, "/orders", "k1", "7", ""},
+			wantStatus: http.StatusCreated,
+			wantBody:   "created  #2",
+			wantCalls:  2,
+		},
+		{
+			name:       "key too long",
+			req:        idempotencyRequest{http.MethodPost, "/orders", strings.Repeat("k", maxIdempotencyKeyLength+1), "7", "a"},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "body too large",
+			req:        idempotencyRequest{http.MethodPost, "/orders", "k1", "7", strings.Repeat("a", 17)},
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeIdempotencyStore()
+			calls := 0
+			r := idempotencyRouter(store, &calls)
+			for _, req := range tt.setup {
+				req.do(r)
+			}
+
+			w := tt.req.do(r)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("handler called %d times, want %d", calls, tt.wantCalls)
+			}
+			if got := w.Header().Get(IdempotentReplayedHeader) == "true"; got != tt.replayed {
+				t.Errorf("replayed = %v, want %v", got, tt.replayed)
+			}
+		})
+	}
+}
+
+func TestIdempotencyReleasesFailedRequests(t *testing.T) {
+	store := newFakeIdempotencyStore()
+	calls := 0
+	r := idempotencyRouter(store, &calls)
+
+	idempotencyRequest{http.MethodPost, "/orders", "k1", "7", "fail"}.do(r)
+	if store.released != 1 || len(store.records) != 0 {
+		t.Fatalf("released %d keys, %d left; want the failed key released", store.released, len(store.records))
+	}
+
+	idempotencyRequest{http.MethodPost, "/orders", "k2", "7", "a"}.do(r)
+	if store.released != 1 || store.records["7/k2"].resp == nil {
+		t.Fatalf("want the successful response stored and not released")
+	}
+}
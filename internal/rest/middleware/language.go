@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/Cora23tt/order_service/pkg/i18n"
+)
+
+// Language picks the response language from the Accept-Language header.
+// For signed-in users AuthWithRoles replaces it with the language chosen in
+// the user profile, if any.
+func (m *Middleware) Language() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if lang, ok := i18n.FromAcceptLanguage(c.GetHeader("Accept-Language")); ok {
+			c.Request = c.Request.WithContext(i18n.WithLang(c.Request.Context(), lang))
+		}
+		c.Next()
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+
+	"github.com/Cora23tt/order_service/pkg/logger"
 )
 
 func (m *Middleware) ZapLogger() gin.HandlerFunc {
@@ -11,6 +13,6 @@ func (m *Middleware) ZapLogger() gin.HandlerFunc {
 		start := time.Now()
 		c.Next()
 		duration := time.Since(start)
-		m.logger.Infof("%s %s %d  %s", c.Request.Method, c.Request.URL.Path, c.Writer.Status(), duration.Truncate(time.Millisecond).String())
+		logger.FromContext(c.Request.Context(), m.logger).Infof("%s %s %d  %s", c.Request.Method, c.Request.URL.Path, c.Writer.Status(), duration.Truncate(time.Millisecond).String())
 	}
 }
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics records request count and latency. Requests are labelled with the
+// route template (/api/v1/orders/:id) so that IDs do not create new series.
+func (m *Middleware) Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		m.metrics.ObserveHTTPRequest(route, c.Request.Method, c.Writer.Status(), time.Since(start))
+	}
+}
@@ -2,16 +2,27 @@ package middleware
 
 import (
 	"go.uber.org/zap"
+
+	"github.com/Cora23tt/order_service/pkg/config"
+	"github.com/Cora23tt/order_service/pkg/metrics"
 )
 
 type Middleware struct {
-	logger    *zap.SugaredLogger
-	validator AuthValidator
+	logger         *zap.SugaredLogger
+	validator      AuthValidator
+	allowedOrigins []string
+	metrics        *metrics.Metrics
+	idempotency    IdempotencyStore
+	maxBodySize    int64
 }
 
-func New(logger *zap.SugaredLogger, validator AuthValidator) *Middleware {
+func New(logger *zap.SugaredLogger, validator AuthValidator, cfg *config.Config, metrics *metrics.Metrics, idempotency IdempotencyStore) *Middleware {
 	return &Middleware{
-		logger:    logger,
-		validator: validator,
+		logger:         logger,
+		validator:      validator,
+		allowedOrigins: cfg.CORS.AllowedOrigins,
+		metrics:        metrics,
+		idempotency:    idempotency,
+		maxBodySize:    cfg.Idempotency.MaxBodySize,
 	}
 }
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/Cora23tt/order_service/pkg/logger"
+	"github.com/Cora23tt/order_service/pkg/utils"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength limits the IDs accepted from clients so that they can
+// not blow up the logs.
+const maxRequestIDLength = 128
+
+// RequestID takes the request ID from the X-Request-ID header or generates a
+// new one, returns it in the response and stores a logger that adds it to
+// every line in the request context.
+func (m *Middleware) RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = utils.NewUUID()
+		}
+		c.Set("requestID", requestID)
+		c.Header(RequestIDHeader, requestID)
+
+		ctx := c.Request.Context()
+		log := logger.WithTrace(ctx, m.logger).With("request_id", requestID, "route", c.FullPath())
+		c.Request = c.Request.WithContext(logger.WithContext(ctx, log))
+		c.Next()
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -1,9 +1,11 @@
 package rest
 
 import (
+	"context"
+	"errors"
 	"net"
 	"net/http"
-	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
@@ -11,10 +13,23 @@ import (
 
 	_ "github.com/Cora23tt/order_service/docs"
 	"github.com/Cora23tt/order_service/internal/rest/handlers/auth"
+	"github.com/Cora23tt/order_service/internal/rest/handlers/cart"
+	"github.com/Cora23tt/order_service/internal/rest/handlers/delivery"
+	"github.com/Cora23tt/order_service/internal/rest/handlers/health"
 	"github.com/Cora23tt/order_service/internal/rest/handlers/order"
+	"github.com/Cora23tt/order_service/internal/rest/handlers/payment"
+	"github.com/Cora23tt/order_service/internal/rest/handlers/pickup"
 	"github.com/Cora23tt/order_service/internal/rest/handlers/product"
+	"github.com/Cora23tt/order_service/internal/rest/handlers/promotion"
+	"github.com/Cora23tt/order_service/internal/rest/handlers/receipt"
+	"github.com/Cora23tt/order_service/internal/rest/handlers/returns"
 	"github.com/Cora23tt/order_service/internal/rest/handlers/user"
 	"github.com/Cora23tt/order_service/internal/rest/middleware"
+	"github.com/Cora23tt/order_service/pkg/config"
+	pkgerrors "github.com/Cora23tt/order_service/pkg/errors"
+	pkghealth "github.com/Cora23tt/order_service/pkg/health"
+	"github.com/Cora23tt/order_service/pkg/lifecycle"
+	"github.com/Cora23tt/order_service/pkg/metrics"
 )
 
 type Server struct {
@@ -23,7 +38,16 @@ type Server struct {
 	order      *order.Handler
 	product    *product.Handler
 	user       *user.Handler
+	cart       *cart.Handler
+	promotion  *promotion.Handler
+	payment    *payment.Handler
+	returns    *returns.Handler
+	receipt    *receipt.Handler
+	pickup     *pickup.Handler
+	delivery   *delivery.Handler
+	health     *health.Handler
 	middleware *middleware.Middleware
+	metrics    *metrics.Metrics
 }
 
 func NewRESTServer(
@@ -33,6 +57,15 @@ func NewRESTServer(
 	mdlwr *middleware.Middleware,
 	product *product.Handler,
 	user *user.Handler,
+	cart *cart.Handler,
+	promotion *promotion.Handler,
+	payment *payment.Handler,
+	returns *returns.Handler,
+	receipt *receipt.Handler,
+	pickup *pickup.Handler,
+	delivery *delivery.Handler,
+	health *health.Handler,
+	metrics *metrics.Metrics,
 ) *Server {
 	return &Server{
 		mux:        mux,
@@ -40,24 +73,79 @@ func NewRESTServer(
 		order:      order,
 		product:    product,
 		user:       user,
+		cart:       cart,
+		promotion:  promotion,
+		payment:    payment,
+		returns:    returns,
+		receipt:    receipt,
+		pickup:     pickup,
+		delivery:   delivery,
+		health:     health,
 		middleware: mdlwr,
+		metrics:    metrics,
 	}
 }
 
-func NewHTTPServer(s *Server) *http.Server {
-	return &http.Server{
-		Addr:    net.JoinHostPort(os.Getenv("HOST"), os.Getenv("PORT")),
-		Handler: s.mux,
+// NewHTTPServer registers the server with the lifecycle. On shutdown the
+// readiness probe starts failing first and connections are only closed
+// after the configured drain delay, so that load balancers have time to
+// stop routing requests to this instance.
+func NewHTTPServer(s *Server, cfg *config.Config, lc *lifecycle.Lifecycle, checker *pkghealth.Checker) *http.Server {
+	server := &http.Server{
+		Addr:              net.JoinHostPort(cfg.HTTP.Host, cfg.HTTP.Port),
+		Handler:           s.mux,
+		ReadTimeout:       cfg.HTTP.ReadTimeout,
+		ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+		WriteTimeout:      cfg.HTTP.WriteTimeout,
+		IdleTimeout:       cfg.HTTP.IdleTimeout,
 	}
+
+	lc.Append(lifecycle.Hook{
+		Name: "http server",
+		OnStart: func(context.Context) error {
+			ln, err := net.Listen("tcp", server.Addr)
+			if err != nil {
+				return err
+			}
+			lc.Go("http server", func() error {
+				if err := server.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+					return err
+				}
+				return nil
+			})
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			checker.SetDraining()
+			select {
+			case <-time.After(cfg.HTTP.DrainDelay):
+			case <-ctx.Done():
+			}
+			return server.Shutdown(ctx)
+		},
+	})
+
+	return server
 }
 
 func (s *Server) SetupRoutes() {
 	const baseUrl = "/api/v1"
 
-	s.mux.Use(gin.Recovery())
+	middleware.UseJSONFieldNames()
+
+	s.mux.Use(s.middleware.Recovery())
+	s.mux.Use(s.middleware.Tracing())
+	s.mux.Use(s.middleware.RequestID())
+	s.mux.Use(s.middleware.Metrics())
 	s.mux.Use(s.middleware.ZapLogger())
 	s.mux.Use(s.middleware.CORSMiddleware())
+	s.mux.Use(s.middleware.Language())
+	s.mux.Use(s.middleware.ErrorHandler())
+	s.mux.NoRoute(func(c *gin.Context) { _ = c.Error(pkgerrors.ErrRouteNotFound) })
 
+	s.mux.GET("/healthz", s.health.Liveness)
+	s.mux.GET("/readyz", s.health.Readiness)
+	s.mux.GET("/metrics", gin.WrapH(s.metrics.Handler()))
 	s.mux.GET("/profile/:id/photo", s.user.GetProfilePhoto)
 	s.mux.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
@@ -65,40 +153,105 @@ func (s *Server) SetupRoutes() {
 	{
 		authGroup.POST("/signin", s.auth.SignIn)
 		authGroup.POST("/signup", s.auth.SignUp)
+		authGroup.POST("/refresh", s.auth.Refresh)
+		authGroup.POST("/logout", s.middleware.AuthWithRoles("user", "admin"), s.auth.Logout)
+		authGroup.POST("/logout-all", s.middleware.AuthWithRoles("user", "admin"), s.auth.LogoutAll)
 	}
 
 	userGroup := s.mux.Group(baseUrl+"/me", s.middleware.AuthWithRoles("user", "admin"))
 	{
 		userGroup.GET("/", s.user.GetProfile)
 		userGroup.PATCH("/", s.user.UpdateProfile)
+		userGroup.GET("/sessions", s.auth.ListSessions)
+		userGroup.DELETE("/sessions/:id", s.auth.RevokeSession)
 	}
 	adminUserGroup := s.mux.Group(baseUrl+"/admin/users", s.middleware.AuthWithRoles("admin"))
 	{
 		adminUserGroup.GET("/", s.user.ListUsers)
 	}
 
-	ordersGroup := s.mux.Group(baseUrl+"/orders", s.middleware.AuthWithRoles("user", "admin"))
+	ordersGroup := s.mux.Group(baseUrl+"/orders", s.middleware.AuthWithRoles("user", "admin"), s.middleware.Idempotency())
 	{
 		ordersGroup.POST("/", s.order.Create)
 		ordersGroup.GET("/", s.order.GetAll)
 		ordersGroup.GET("/:id", s.order.GetByID)
 		ordersGroup.GET("/:id/cancel", s.order.Cancel)
+		ordersGroup.GET("/:id/history", s.order.GetHistory)
+		ordersGroup.GET("/:id/receipt", s.receipt.Get)
+		ordersGroup.POST("/:id/payment", s.payment.Pay)
+		ordersGroup.GET("/:id/payments", s.payment.List)
 	}
-	adminOrdersGroup := s.mux.Group(baseUrl+"/orders", s.middleware.AuthWithRoles("admin"))
+	adminOrdersGroup := s.mux.Group(baseUrl+"/orders", s.middleware.AuthWithRoles("admin"), s.middleware.Idempotency())
 	{
 		adminOrdersGroup.DELETE("/:id", s.order.Delete)
 		adminOrdersGroup.PUT("/:id", s.order.Update)
+		adminOrdersGroup.GET("/:id/transitions", s.order.GetTransitions)
+		adminOrdersGroup.POST("/:id/refunds", s.payment.Refund)
 		adminOrdersGroup.GET("/stats", s.order.GetStats)
 		adminOrdersGroup.GET("/export", s.order.Export)
 		adminOrdersGroup.GET("/export/csv", s.order.ExportCSV)
 	}
 
+	paymentGroup := s.mux.Group(baseUrl + "/payments")
+	{
+		paymentGroup.POST("/webhook/:provider", s.payment.Webhook)
+	}
+
+	returnsGroup := s.mux.Group(baseUrl+"/returns", s.middleware.AuthWithRoles("user", "admin"), s.middleware.Idempotency())
+	{
+		returnsGroup.POST("/", s.returns.Create)
+		returnsGroup.GET("/", s.returns.List)
+		returnsGroup.GET("/:id", s.returns.Get)
+		returnsGroup.POST("/:id/photos", s.returns.AddPhoto)
+		returnsGroup.GET("/:id/photos/:photo_id", s.returns.GetPhoto)
+	}
+	adminReturnsGroup := s.mux.Group(baseUrl+"/admin/returns", s.middleware.AuthWithRoles("admin"), s.middleware.Idempotency())
+	{
+		adminReturnsGroup.PUT("/:id", s.returns.UpdateStatus)
+	}
+
+	cartGroup := s.mux.Group(baseUrl+"/cart", s.middleware.AuthWithRoles("user", "admin"), s.middleware.Idempotency())
+	{
+		cartGroup.GET("/items", s.cart.GetItems)
+		cartGroup.PUT("/items", s.cart.SetItem)
+		cartGroup.DELETE("/items", s.cart.Clear)
+		cartGroup.DELETE("/items/:product_id", s.cart.RemoveItem)
+		cartGroup.POST("/checkout", s.cart.Checkout)
+	}
+
+	adminPromotionGroup := s.mux.Group(baseUrl+"/admin/promotions", s.middleware.AuthWithRoles("admin"), s.middleware.Idempotency())
+	{
+		adminPromotionGroup.GET("/", s.promotion.List)
+		adminPromotionGroup.POST("/", s.promotion.Create)
+		adminPromotionGroup.GET("/:id", s.promotion.Get)
+		adminPromotionGroup.PUT("/:id", s.promotion.Update)
+		adminPromotionGroup.DELETE("/:id", s.promotion.Delete)
+	}
+
+	publicPickupGroup := s.mux.Group(baseUrl + "/pickup-points")
+	{
+		publicPickupGroup.GET("/", s.pickup.List)
+		publicPickupGroup.GET("/:id", s.pickup.Get)
+		publicPickupGroup.GET("/:id/slots", s.delivery.ListAvailable)
+	}
+	adminPickupGroup := s.mux.Group(baseUrl+"/admin/pickup-points", s.middleware.AuthWithRoles("admin"), s.middleware.Idempotency())
+	{
+		adminPickupGroup.GET("/", s.pickup.AdminList)
+		adminPickupGroup.POST("/", s.pickup.Create)
+		adminPickupGroup.PUT("/:id", s.pickup.Update)
+		adminPickupGroup.DELETE("/:id", s.pickup.Delete)
+		adminPickupGroup.GET("/:id/slots", s.delivery.List)
+		adminPickupGroup.POST("/:id/slots", s.delivery.Create)
+		adminPickupGroup.PUT("/:id/slots/:slot_id", s.delivery.Update)
+		adminPickupGroup.DELETE("/:id/slots/:slot_id", s.delivery.Delete)
+	}
+
 	publicProductGroup := s.mux.Group(baseUrl + "/products")
 	{
 		publicProductGroup.GET("/", s.product.GetProducts)
 		publicProductGroup.GET("/:id", s.product.GetProduct)
 	}
-	adminProductGroup := s.mux.Group(baseUrl+"/products", s.middleware.AuthWithRoles("admin"))
+	adminProductGroup := s.mux.Group(baseUrl+"/products", s.middleware.AuthWithRoles("admin"), s.middleware.Idempotency())
 	{
 		adminProductGroup.POST("/", s.product.AddProduct)
 		adminProductGroup.PUT("/:id", s.product.UpdateProduct)
@@ -2,21 +2,38 @@ package auth
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/Cora23tt/order_service/internal/repository/auth"
+	"github.com/Cora23tt/order_service/internal/repository/uow"
+	"github.com/Cora23tt/order_service/pkg/config"
 	pkgerrors "github.com/Cora23tt/order_service/pkg/errors"
+	"github.com/Cora23tt/order_service/pkg/logger"
+	"github.com/Cora23tt/order_service/pkg/metrics"
+	"github.com/Cora23tt/order_service/pkg/utils"
 	"github.com/dgrijalva/jwt-go/v4"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 )
 
-const tokenTTL = time.Duration(12 * time.Hour)
+var tracer = otel.Tracer("github.com/Cora23tt/order_service/internal/usecase/auth")
 
 type Service struct {
-	repo *auth.Repo
+	repo            *auth.Repo
+	uow             uow.UnitOfWork
+	log             *zap.SugaredLogger
+	secret          []byte
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
+	metrics         *metrics.Metrics
 }
 
 type User struct {
@@ -26,17 +43,53 @@ type User struct {
 	ID             int
 }
 
-func NewService(r *auth.Repo) *Service {
-	return &Service{repo: r}
+// TokenPair is issued on sign-in and on every refresh. The refresh token is
+// single use: refreshing returns a new pair and invalidates the old token.
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    time.Time
+}
+
+// ClientInfo describes the device a session was opened from.
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
+}
+
+type ActiveSession struct {
+	ID         string    `json:"id" example:"5f0c7d2e-8a4b-4c1e-9d57-2f0e6b1c3a90"`
+	UserAgent  *string   `json:"user_agent,omitempty" example:"okhttp/4.12.0"`
+	IPAddress  *string   `json:"ip_address,omitempty" example:"192.168.1.10"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+func NewService(r *auth.Repo, uow uow.UnitOfWork, log *zap.SugaredLogger, cfg *config.Config, metrics *metrics.Metrics) *Service {
+	return &Service{
+		repo:            r,
+		uow:             uow,
+		log:             log,
+		secret:          []byte(cfg.Auth.JWTSecret),
+		accessTokenTTL:  cfg.Auth.AccessTokenTTL,
+		refreshTokenTTL: cfg.Auth.RefreshTokenTTL,
+		metrics:         metrics,
+	}
 }
 
 type tokenClaims struct {
-	UserID int64  `json:"user_id"`
-	Role   string `json:"role"`
+	UserID    int64  `json:"user_id"`
+	Role      string `json:"role"`
+	SessionID string `json:"sid"`
 	jwt.StandardClaims
 }
 
 func (s *Service) CreateUser(ctx context.Context, phoneNumber, password string) (int64, error) {
+	ctx, span := tracer.Start(ctx, "auth.Service.CreateUser")
+	defer span.End()
+
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return 0, fmt.Errorf("failed to hash password: %w", err)
@@ -49,66 +102,300 @@ func (s *Service) CreateUser(ctx context.Context, phoneNumber, password string)
 	userID, err := s.repo.Create(ctx, &user)
 
 	if errors.Is(err, pkgerrors.ErrAlreadyExists) {
-		return 0, pkgerrors.ErrAlreadyExists
+		return 0, pkgerrors.ErrUserExists
 	}
 	return userID, err
 }
 
-func (s *Service) Validate(token string) (int64, string, error) {
-	return s.ParseToken(token)
-}
+// Validate checks the access token and that its session was not revoked. It
+// also returns the language preferred by the user, which is empty if the
+// user has not chosen one.
+func (s *Service) Validate(ctx context.Context, token string) (int64, string, string, string, error) {
+	ctx, span := tracer.Start(ctx, "auth.Service.Validate")
+	defer span.End()
+
+	userID, role, sessionID, err := s.ParseToken(token)
+	if err != nil {
+		return 0, "", "", "", err
+	}
 
-func (s *Service) GetUserID(ctx context.Context, token string) (int64, error) {
-	return s.repo.GetUserID(ctx, token)
+	active, language, err := s.repo.IsSessionActive(ctx, sessionID)
+	if err != nil {
+		return 0, "", "", "", fmt.Errorf("check session: %w", err)
+	}
+	if !active {
+		return 0, "", "", "", pkgerrors.ErrUnauthorized
+	}
+	return userID, role, sessionID, language, nil
 }
 
-func (s *Service) GenerateJWT(ctx context.Context, phoneNumber, password string) (string, error) {
+// SignIn checks the credentials and opens a new session.
+func (s *Service) SignIn(ctx context.Context, phoneNumber, password string, client ClientInfo) (*TokenPair, error) {
+	ctx, span := tracer.Start(ctx, "auth.Service.SignIn")
+	defer span.End()
+
 	user, err := s.repo.GetUser(ctx, phoneNumber)
 	if err != nil {
 		if errors.Is(err, pkgerrors.ErrNotFound) {
-			return "", pkgerrors.ErrNotFound
+			s.metrics.SignInFailed("unknown_user")
+			return nil, pkgerrors.ErrInvalidCredentials
 		}
-		return "", pkgerrors.ErrInternal
+		return nil, pkgerrors.ErrInternal
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.HashedPassword), []byte(password)); err != nil {
-		return "", pkgerrors.ErrInvalidCredentials
+		s.metrics.SignInFailed("invalid_password")
+		return nil, pkgerrors.ErrInvalidCredentials
+	}
+
+	return s.issueTokens(ctx, s.repo, user, utils.NewUUID(), client)
+}
+
+// Refresh exchanges a refresh token for a new token pair. Presenting a token
+// that was already rotated means it leaked, so the whole session is revoked.
+func (s *Service) Refresh(ctx context.Context, refreshToken string, client ClientInfo) (*TokenPair, error) {
+	ctx, span := tracer.Start(ctx, "auth.Service.Refresh")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
+	tx, err := s.uow.Begin(ctx)
+	if err != nil {
+		log.Errorw("begin transaction failed", "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	repo := auth.NewWithTx(tx.GetTx())
+
+	session, err := repo.GetSessionByTokenHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, pkgerrors.ErrNotFound) {
+			return nil, pkgerrors.ErrUnauthorized
+		}
+		log.Errorw("get session failed", "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+
+	switch checkRefresh(session, time.Now()) {
+	case refreshReused:
+		log.Warnw("refresh token reuse detected, revoking session", "user_id", session.UserID, "session_id", session.FamilyID)
+		if _, err := repo.RevokeSessionFamily(ctx, session.UserID, session.FamilyID); err != nil {
+			log.Errorw("revoke session failed", "session_id", session.FamilyID, "error", err)
+			return nil, pkgerrors.ErrInternal
+		}
+		if err := tx.Commit(ctx); err != nil {
+			log.Errorw("commit transaction failed", "error", err)
+			return nil, pkgerrors.ErrInternal
+		}
+		committed = true
+		return nil, pkgerrors.ErrUnauthorized
+	case refreshRejected:
+		return nil, pkgerrors.ErrUnauthorized
+	}
+
+	if err := repo.MarkSessionRotated(ctx, session.ID); err != nil {
+		log.Errorw("rotate session failed", "session_id", session.FamilyID, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+
+	user, err := repo.GetUserByID(ctx, session.UserID)
+	if err != nil {
+		if errors.Is(err, pkgerrors.ErrNotFound) {
+			return nil, pkgerrors.ErrUnauthorized
+		}
+		log.Errorw("get user failed", "user_id", session.UserID, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+
+	pair, err := s.issueTokens(ctx, repo, user, session.FamilyID, client)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Errorw("commit transaction failed", "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	committed = true
+	return pair, nil
+}
+
+// Logout revokes one session of the user.
+func (s *Service) Logout(ctx context.Context, userID int64, sessionID string) error {
+	ctx, span := tracer.Start(ctx, "auth.Service.Logout")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
+	if err := uuid.Validate(sessionID); err != nil {
+		return pkgerrors.ErrSessionNotFound
+	}
+
+	revoked, err := s.repo.RevokeSessionFamily(ctx, userID, sessionID)
+	if err != nil {
+		log.Errorw("revoke session failed", "user_id", userID, "session_id", sessionID, "error", err)
+		return pkgerrors.ErrInternal
+	}
+	if revoked == 0 {
+		return pkgerrors.ErrSessionNotFound
+	}
+	return nil
+}
+
+// LogoutAll revokes every session of the user.
+func (s *Service) LogoutAll(ctx context.Context, userID int64) error {
+	ctx, span := tracer.Start(ctx, "auth.Service.LogoutAll")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
+	if err := s.repo.RevokeAllSessions(ctx, userID); err != nil {
+		log.Errorw("revoke all sessions failed", "user_id", userID, "error", err)
+		return pkgerrors.ErrInternal
+	}
+	return nil
+}
+
+// ListSessions returns the active sessions of the user, marking the one the
+// request was made from.
+func (s *Service) ListSessions(ctx context.Context, userID int64, currentSessionID string) ([]ActiveSession, error) {
+	ctx, span := tracer.Start(ctx, "auth.Service.ListSessions")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
+	rows, err := s.repo.ListActiveSessions(ctx, userID)
+	if err != nil {
+		log.Errorw("list sessions failed", "user_id", userID, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+
+	sessions := make([]ActiveSession, 0, len(rows))
+	for _, r := range rows {
+		sessions = append(sessions, ActiveSession{
+			ID:         r.FamilyID,
+			UserAgent:  r.UserAgent,
+			IPAddress:  r.IPAddress,
+			CreatedAt:  r.SignedInAt,
+			LastUsedAt: r.CreatedAt,
+			ExpiresAt:  r.ExpiresAt,
+			Current:    r.FamilyID == currentSessionID,
+		})
+	}
+	return sessions, nil
+}
+
+func (s *Service) issueTokens(ctx context.Context, repo *auth.Repo, user auth.User, familyID string, client ClientInfo) (*TokenPair, error) {
+	log := logger.FromContext(ctx, s.log)
+
+	refreshToken, err := newRefreshToken()
+	if err != nil {
+		log.Errorw("generate refresh token failed", "error", err)
+		return nil, pkgerrors.ErrInternal
 	}
 
+	session := &auth.Session{
+		FamilyID:  familyID,
+		UserID:    user.ID,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: time.Now().Add(s.refreshTokenTTL),
+	}
+	if client.UserAgent != "" {
+		session.UserAgent = &client.UserAgent
+	}
+	if client.IPAddress != "" {
+		session.IPAddress = &client.IPAddress
+	}
+	if _, err := repo.CreateSession(ctx, session); err != nil {
+		log.Errorw("create session failed", "user_id", user.ID, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	// Tokens that can no longer be used are dropped whenever the user gets a
+	// new one, so the sessions of a user do not pile up.
+	if _, err := repo.DeleteDeadSessions(ctx, user.ID); err != nil {
+		log.Errorw("delete dead sessions failed", "user_id", user.ID, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+
+	expiresAt := time.Now().Add(s.accessTokenTTL)
 	claims := tokenClaims{
-		UserID: user.ID,
-		Role:   user.Role,
+		UserID:    user.ID,
+		Role:      user.Role,
+		SessionID: familyID,
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: &jwt.Time{Time: time.Now().Add(tokenTTL)},
+			ExpiresAt: &jwt.Time{Time: expiresAt},
 			IssuedAt:  &jwt.Time{Time: time.Now()},
 		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		return "", fmt.Errorf("JWT_SECRET not set")
+	accessToken, err := token.SignedString(s.secret)
+	if err != nil {
+		return nil, fmt.Errorf("sign token: %w", err)
 	}
 
-	return token.SignedString([]byte(secret))
+	return &TokenPair{AccessToken: accessToken, RefreshToken: refreshToken, ExpiresAt: expiresAt}, nil
 }
 
-func (s *Service) ParseToken(tokenStr string) (int64, string, error) {
+func (s *Service) ParseToken(tokenStr string) (int64, string, string, error) {
 	claims := &tokenClaims{}
 
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		return 0, "", fmt.Errorf("JWT_SECRET not set")
-	}
-
 	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(secret), nil
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return s.secret, nil
 	})
 
 	if err != nil || !token.Valid {
-		return 0, "", fmt.Errorf("invalid token: %w", err)
+		return 0, "", "", fmt.Errorf("invalid token: %w", err)
+	}
+	if claims.SessionID == "" {
+		return 0, "", "", fmt.Errorf("invalid token: no session")
 	}
 
-	return claims.UserID, claims.Role, nil
+	return claims.UserID, claims.Role, claims.SessionID, nil
+}
+
+// refreshCheck is the outcome of presenting the refresh token of a session.
+type refreshCheck int
+
+const (
+	refreshAllowed refreshCheck = iota
+	// refreshReused means the token was already rotated, so it leaked and
+	// the session must be revoked.
+	refreshReused
+	// refreshRejected means the session was revoked or has expired.
+	refreshRejected
+)
+
+// checkRefresh decides whether the token of session can be exchanged at now.
+// Reuse is checked first, so replaying a rotated token revokes the session
+// even if it has expired since.
+func checkRefresh(session *auth.Session, now time.Time) refreshCheck {
+	if session.RotatedAt != nil {
+		return refreshReused
+	}
+	if session.RevokedAt != nil || !session.ExpiresAt.After(now) {
+		return refreshRejected
+	}
+	return refreshAllowed
+}
+
+func newRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the form refresh tokens are stored in, so a database
+// leak does not expose usable tokens.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
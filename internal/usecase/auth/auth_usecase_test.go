@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Cora23tt/order_service/internal/repository/auth"
+	"github.com/dgrijalva/jwt-go/v4"
+)
+
+func TestCheckRefresh(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	earlier := now.Add(-time.Minute)
+
+	tests := []struct {
+		name    string
+		session auth.Session
+		want    refreshCheck
+	}{
+		{"live", auth.Session{ExpiresAt: now.Add(time.Hour)}, refreshAllowed},
+		{"rotated", auth.Session{ExpiresAt: now.Add(time.Hour), RotatedAt: &earlier}, refreshReused},
+		{"rotated and expired", auth.Session{ExpiresAt: earlier, RotatedAt: &earlier}, refreshReused},
+		{"rotated and revoked", auth.Session{ExpiresAt: now.Add(time.Hour), RotatedAt: &earlier, RevokedAt: &earlier}, refreshReused},
+		{"revoked", auth.Session{ExpiresAt: now.Add(time.Hour), RevokedAt: &earlier}, refreshRejected},
+		{"expired", auth.Session{ExpiresAt: earlier}, refreshRejected},
+		{"expires now", auth.Session{ExpiresAt: now}, refreshRejected},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkRefresh(&tt.session, now); got != tt.want {
+				t.Errorf("checkRefresh() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseToken(t *testing.T) {
+	s := &Service{secret: []byte("0123456789abcdef0123456789abcdef")}
+	sign := func(secret string, claims tokenClaims) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	valid := jwt.StandardClaims{ExpiresAt: &jwt.Time{Time: time.Now().Add(time.Minute)}}
+	expired := jwt.StandardClaims{ExpiresAt: &jwt.Time{Time: time.Now().Add(-time.Minute)}}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"valid", sign(string(s.secret), tokenClaims{UserID: 7, Role: "admin", SessionID: "sid", StandardClaims: valid}), false},
+		{"no session", sign(string(s.secret), tokenClaims{UserID: 7, Role: "admin", StandardClaims: valid}), true},
+		{"expired", sign(string(s.secret), tokenClaims{UserID: 7, Role: "admin", SessionID: "sid", StandardClaims: expired}), true},
+		{"other secret", sign("another secret of the same length", tokenClaims{UserID: 7, SessionID: "sid", StandardClaims: valid}), true},
+		{"garbage", "not a token", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID, role, sessionID, err := s.ParseToken(tt.token)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("ParseToken() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseToken() failed: %v", err)
+			}
+			if userID != 7 || role != "admin" || sessionID != "sid" {
+				t.Errorf("ParseToken() = %d, %q, %q, want 7, \"admin\", \"sid\"", userID, role, sessionID)
+			}
+		})
+	}
+}
+
+func TestHashToken(t *testing.T) {
+	a, err := newRefreshToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := newRefreshToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == b {
+		t.Fatal("newRefreshToken returned the same token twice")
+	}
+	if hashToken(a) != hashToken(a) || hashToken(a) == hashToken(b) {
+		t.Error("hashToken must be deterministic and differ between tokens")
+	}
+	if hashToken(a) == a {
+		t.Error("hashToken returned the token itself")
+	}
+}
@@ -0,0 +1,207 @@
+package cart
+
+import (
+	"context"
+
+	cartRepo "github.com/Cora23tt/order_service/internal/repository/cart"
+	"github.com/Cora23tt/order_service/internal/usecase/order"
+	pkgerrors "github.com/Cora23tt/order_service/pkg/errors"
+	"github.com/Cora23tt/order_service/pkg/logger"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
+)
+
+var tracer = otel.Tracer("github.com/Cora23tt/order_service/internal/usecase/cart")
+
+// Stock warnings of a cart line.
+const (
+	StockWarningOutOfStock   = "out_of_stock"
+	StockWarningInsufficient = "insufficient_stock"
+)
+
+type Service struct {
+	repo   *cartRepo.Repo
+	orders *order.Service
+	log    *zap.SugaredLogger
+}
+
+func NewService(repo *cartRepo.Repo, orders *order.Service, log *zap.SugaredLogger) *Service {
+	return &Service{repo: repo, orders: orders, log: log}
+}
+
+type Cart struct {
+	Items []Item `json:"items"`
+	Total int64  `json:"total" example:"48000"`
+}
+
+// Item is a cart line priced with the current product price. Stock is only
+// checked at checkout, so a line may ask for more than is available; such
+// lines carry a StockWarning.
+type Item struct {
+	ProductID    int64  `json:"product_id" example:"4"`
+	Name         string `json:"name" example:"Green tea"`
+	ImageURL     string `json:"image_url,omitempty" example:"/images/tea.png"`
+	Price        int64  `json:"price" example:"12000"`
+	Quantity     int64  `json:"quantity" example:"4"`
+	LineTotal    int64  `json:"line_total" example:"48000"`
+	Available    int64  `json:"available" example:"10"`
+	StockWarning string `json:"stock_warning,omitempty" enums:"out_of_stock,insufficient_stock" example:"insufficient_stock"`
+}
+
+type CheckoutInput struct {
+	UserID         int64
+	Role           string
+	PickupPointID  int64
+	DeliverySlotID *int64
+	PromoCode      string
+}
+
+func (s *Service) GetCart(ctx context.Context, userID int64) (*Cart, error) {
+	ctx, span := tracer.Start(ctx, "cart.Service.GetCart")
+	defer span.End()
+
+	items, err := s.repo.ListItems(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return newCart(items), nil
+}
+
+// SetItem puts the product into the cart or replaces its quantity and
+// returns the updated cart.
+func (s *Service) SetItem(ctx context.Context, userID, productID, quantity int64) (*Cart, error) {
+	ctx, span := tracer.Start(ctx, "cart.Service.SetItem")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
+	err := s.repo.SetItem(ctx, userID, productID, quantity)
+	switch err {
+	case nil:
+		log.Infow("cart item set", "product_id", productID, "quantity", quantity)
+	case pkgerrors.ErrNotFound:
+		log.Warnw("product not found for cart", "product_id", productID)
+		return nil, pkgerrors.ErrProductNotFound
+	default:
+		return nil, err
+	}
+
+	return s.GetCart(ctx, userID)
+}
+
+// RemoveItem removes the product from the cart and returns the updated cart.
+func (s *Service) RemoveItem(ctx context.Context, userID, productID int64) (*Cart, error) {
+	ctx, span := tracer.Start(ctx, "cart.Service.RemoveItem")
+	defer span.End()
+
+	err := s.repo.DeleteItem(ctx, userID, productID)
+	switch err {
+	case nil:
+	case pkgerrors.ErrNotFound:
+		return nil, pkgerrors.ErrCartItemNotFound
+	default:
+		return nil, err
+	}
+
+	return s.GetCart(ctx, userID)
+}
+
+func (s *Service) Clear(ctx context.Context, userID int64) error {
+	ctx, span := tracer.Start(ctx, "cart.Service.Clear")
+	defer span.End()
+
+	return s.repo.Clear(ctx, userID)
+}
+
+// Checkout turns the cart into an order. The cart is emptied in the order
+// transaction, so either the order is created and the cart is cleared or
+// neither happens. If the cart is changed concurrently the checkout fails
+// with ErrCartChanged.
+func (s *Service) Checkout(ctx context.Context, input CheckoutInput) (int64, error) {
+	ctx, span := tracer.Start(ctx, "cart.Service.Checkout")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
+	items, err := s.repo.ListItems(ctx, input.UserID)
+	if err != nil {
+		return 0, err
+	}
+	if len(items) == 0 {
+		return 0, pkgerrors.ErrCartEmpty
+	}
+
+	orderItems := make([]order.OrderItemInput, 0, len(items))
+	for _, it := range items {
+		orderItems = append(orderItems, order.OrderItemInput{ProductID: it.ProductID, Quantity: it.Quantity})
+	}
+
+	orderID, err := s.orders.CreateOrder(ctx, order.CreateOrderInput{
+		UserID:         input.UserID,
+		Role:           input.Role,
+		Items:          orderItems,
+		PickupPointID:  input.PickupPointID,
+		DeliverySlotID: input.DeliverySlotID,
+		PromoCode:      input.PromoCode,
+		BeforeCommit: func(ctx context.Context, tx pgx.Tx, orderID int64) error {
+			repo := cartRepo.NewWithTx(tx, s.log)
+
+			lines, err := repo.LockLines(ctx, input.UserID)
+			if err != nil {
+				return err
+			}
+			if !sameLines(lines, items) {
+				log.Warnw("cart changed during checkout", "order_id", orderID)
+				return pkgerrors.ErrCartChanged
+			}
+			return repo.Clear(ctx, input.UserID)
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	log.Infow("cart checked out", "order_id", orderID, "lines", len(items))
+	return orderID, nil
+}
+
+func newCart(items []cartRepo.Item) *Cart {
+	cart := &Cart{Items: make([]Item, 0, len(items))}
+	for _, it := range items {
+		item := Item{
+			ProductID: it.ProductID,
+			Name:      it.Name,
+			ImageURL:  it.ImageURL,
+			Price:     it.Price,
+			Quantity:  it.Quantity,
+			LineTotal: it.Price * it.Quantity,
+			Available: it.StockQuantity,
+		}
+		switch {
+		case it.StockQuantity == 0:
+			item.StockWarning = StockWarningOutOfStock
+		case it.StockQuantity < it.Quantity:
+			item.StockWarning = StockWarningInsufficient
+		}
+		cart.Total += item.LineTotal
+		cart.Items = append(cart.Items, item)
+	}
+	return cart
+}
+
+// sameLines reports whether the locked cart lines are the ones the order was
+// built from. Both are ordered differently, so they are compared as sets.
+func sameLines(lines []cartRepo.Line, items []cartRepo.Item) bool {
+	if len(lines) != len(items) {
+		return false
+	}
+	quantities := make(map[int64]int64, len(items))
+	for _, it := range items {
+		quantities[it.ProductID] = it.Quantity
+	}
+	for _, l := range lines {
+		if q, ok := quantities[l.ProductID]; !ok || q != l.Quantity {
+			return false
+		}
+	}
+	return true
+}
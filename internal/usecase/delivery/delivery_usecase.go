@@ -0,0 +1,305 @@
+package delivery
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	deliveryRepo "github.com/Cora23tt/order_service/internal/repository/delivery"
+	"github.com/Cora23tt/order_service/internal/repository/uow"
+	"github.com/Cora23tt/order_service/internal/usecase/pickup"
+	"github.com/Cora23tt/order_service/pkg/config"
+	pkgerrors "github.com/Cora23tt/order_service/pkg/errors"
+	"github.com/Cora23tt/order_service/pkg/logger"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
+)
+
+var tracer = otel.Tracer("github.com/Cora23tt/order_service/internal/usecase/delivery")
+
+type Service struct {
+	repo     *deliveryRepo.Repo
+	pickups  *pickup.Service
+	uow      uow.UnitOfWork
+	location *time.Location
+	horizon  time.Duration
+	log      *zap.SugaredLogger
+}
+
+func NewService(repo *deliveryRepo.Repo, pickups *pickup.Service, uow uow.UnitOfWork, cfg *config.Config, log *zap.SugaredLogger) (*Service, error) {
+	location, err := time.LoadLocation(cfg.Pickup.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("load pickup timezone: %w", err)
+	}
+	return &Service{
+		repo:     repo,
+		pickups:  pickups,
+		uow:      uow,
+		location: location,
+		horizon:  cfg.Delivery.BookingHorizon,
+		log:      log,
+	}, nil
+}
+
+// SlotInput is the full state of a delivery slot set by an admin.
+type SlotInput struct {
+	StartsAt time.Time
+	EndsAt   time.Time
+	Capacity int64
+}
+
+// ListAvailable returns the slots of the pickup point a customer can book
+// now: not started, within the booking horizon, with places left and while
+// the point is open. An inactive point has no available slots.
+func (s *Service) ListAvailable(ctx context.Context, pointID int64) ([]*deliveryRepo.Slot, error) {
+	ctx, span := tracer.Start(ctx, "delivery.Service.ListAvailable")
+	defer span.End()
+
+	point, err := s.pickups.Get(ctx, pointID)
+	if err != nil {
+		return nil, err
+	}
+	if !point.Active {
+		return []*deliveryRepo.Slot{}, nil
+	}
+
+	until := time.Now().Add(s.horizon)
+	slots, err := s.repo.List(ctx, deliveryRepo.ListFilter{PickupPointID: pointID, Until: &until, AvailableOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	available := slots[:0]
+	for _, slot := range slots {
+		if s.pickups.OpenAt(point, slot.StartsAt) {
+			available = append(available, s.localize(slot))
+		}
+	}
+	return available, nil
+}
+
+// List returns all slots of the pickup point that have not ended yet.
+func (s *Service) List(ctx context.Context, pointID int64) ([]*deliveryRepo.Slot, error) {
+	ctx, span := tracer.Start(ctx, "delivery.Service.List")
+	defer span.End()
+
+	if _, err := s.pickups.Get(ctx, pointID); err != nil {
+		return nil, err
+	}
+	slots, err := s.repo.List(ctx, deliveryRepo.ListFilter{PickupPointID: pointID})
+	if err != nil {
+		return nil, err
+	}
+	for _, slot := range slots {
+		s.localize(slot)
+	}
+	return slots, nil
+}
+
+func (s *Service) Create(ctx context.Context, pointID int64, input SlotInput) (*deliveryRepo.Slot, error) {
+	ctx, span := tracer.Start(ctx, "delivery.Service.Create")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
+	if !input.EndsAt.After(input.StartsAt) {
+		return nil, pkgerrors.InvalidField("ends_at", "gtfield", "starts_at")
+	}
+
+	slot := &deliveryRepo.Slot{
+		PickupPointID: pointID,
+		StartsAt:      input.StartsAt,
+		EndsAt:        input.EndsAt,
+		Capacity:      input.Capacity,
+		Available:     input.Capacity,
+	}
+	switch err := s.repo.Create(ctx, slot); err {
+	case nil:
+	case pkgerrors.ErrInvalidInput:
+		// The only foreign key written is the pickup point.
+		return nil, pkgerrors.ErrPickupPointNotFound
+	case pkgerrors.ErrAlreadyExists:
+		return nil, pkgerrors.ErrDeliverySlotExists
+	default:
+		return nil, err
+	}
+
+	log.Infow("delivery slot created", "slot_id", slot.ID, "pickup_point_id", pointID)
+	return s.localize(slot), nil
+}
+
+// Update replaces the slot. The capacity cannot drop below the places already
+// booked, and a slot with bookings cannot be moved in time.
+func (s *Service) Update(ctx context.Context, pointID, slotID int64, input SlotInput) (*deliveryRepo.Slot, error) {
+	ctx, span := tracer.Start(ctx, "delivery.Service.Update")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
+	if !input.EndsAt.After(input.StartsAt) {
+		return nil, pkgerrors.InvalidField("ends_at", "gtfield", "starts_at")
+	}
+
+	var slot *deliveryRepo.Slot
+	err := s.inTx(ctx, func(repo *deliveryRepo.Repo) error {
+		var err error
+		slot, err = s.lock(ctx, repo, pointID, slotID)
+		if err != nil {
+			return err
+		}
+		moved := !input.StartsAt.Equal(slot.StartsAt) || !input.EndsAt.Equal(slot.EndsAt)
+		if slot.Booked > 0 && moved {
+			log.Warnw("booked delivery slot moved", "slot_id", slotID, "booked", slot.Booked)
+			return pkgerrors.ErrDeliverySlotBooked
+		}
+		if input.Capacity < slot.Booked {
+			return pkgerrors.InvalidField("capacity", "gte", strconv.FormatInt(slot.Booked, 10))
+		}
+
+		slot.StartsAt, slot.EndsAt, slot.Capacity = input.StartsAt, input.EndsAt, input.Capacity
+		slot.Available = slot.Capacity - slot.Booked
+		return repo.Update(ctx, slot)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infow("delivery slot updated", "slot_id", slotID)
+	return s.localize(slot), nil
+}
+
+// Delete removes a slot that has no bookings.
+func (s *Service) Delete(ctx context.Context, pointID, slotID int64) error {
+	ctx, span := tracer.Start(ctx, "delivery.Service.Delete")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
+	err := s.inTx(ctx, func(repo *deliveryRepo.Repo) error {
+		slot, err := s.lock(ctx, repo, pointID, slotID)
+		if err != nil {
+			return err
+		}
+		if slot.Booked > 0 {
+			log.Warnw("booked delivery slot deleted", "slot_id", slotID, "booked", slot.Booked)
+			return pkgerrors.ErrDeliverySlotBooked
+		}
+		return repo.Delete(ctx, slotID)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Infow("delivery slot deleted", "slot_id", slotID)
+	return nil
+}
+
+// Book takes a place in the slot for an order being created in tx at the
+// pickup point. The place is held by the order once it is stored; the slot
+// stays locked until tx ends, so concurrent bookings cannot overfill it.
+func (s *Service) Book(ctx context.Context, tx pgx.Tx, slotID, pointID int64) (*deliveryRepo.Slot, error) {
+	ctx, span := tracer.Start(ctx, "delivery.Service.Book")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
+	repo := deliveryRepo.NewWithTx(tx, s.log)
+	slot, err := repo.GetByIDForUpdate(ctx, slotID)
+	switch err {
+	case nil:
+	case pkgerrors.ErrNotFound:
+		log.Warnw("unknown delivery slot", "slot_id", slotID)
+		return nil, pkgerrors.ErrDeliverySlotNotFound
+	default:
+		return nil, err
+	}
+
+	if slot.PickupPointID != pointID {
+		log.Warnw("delivery slot of another pickup point", "slot_id", slotID, "pickup_point_id", pointID)
+		return nil, pkgerrors.ErrDeliverySlotMismatch
+	}
+	now := time.Now()
+	if !slot.StartsAt.After(now) {
+		log.Warnw("past delivery slot", "slot_id", slotID, "starts_at", slot.StartsAt)
+		return nil, pkgerrors.ErrDeliverySlotPast
+	}
+	if slot.StartsAt.After(now.Add(s.horizon)) {
+		log.Warnw("delivery slot beyond booking horizon", "slot_id", slotID, "starts_at", slot.StartsAt)
+		return nil, pkgerrors.ErrDeliverySlotBeyondHorizon
+	}
+
+	slot.Booked, err = repo.CountBooked(ctx, slotID)
+	if err != nil {
+		return nil, err
+	}
+	if slot.Booked >= slot.Capacity {
+		log.Warnw("delivery slot full", "slot_id", slotID, "capacity", slot.Capacity)
+		return nil, pkgerrors.ErrDeliverySlotFull
+	}
+	slot.Available = slot.Capacity - slot.Booked
+	return s.localize(slot), nil
+}
+
+// Date returns the day of the slot in the pickup point timezone, as stored in
+// orders.delivery_date.
+func (s *Service) Date(slot *deliveryRepo.Slot) time.Time {
+	y, m, d := slot.StartsAt.In(s.location).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// lock loads and locks the slot of the pickup point with its bookings
+// counted.
+func (s *Service) lock(ctx context.Context, repo *deliveryRepo.Repo, pointID, slotID int64) (*deliveryRepo.Slot, error) {
+	slot, err := repo.GetByIDForUpdate(ctx, slotID)
+	if err != nil {
+		return nil, err
+	}
+	if slot.PickupPointID != pointID {
+		return nil, pkgerrors.ErrNotFound
+	}
+	slot.Booked, err = repo.CountBooked(ctx, slotID)
+	if err != nil {
+		return nil, err
+	}
+	return slot, nil
+}
+
+// localize shows the slot times in the pickup point timezone.
+func (s *Service) localize(slot *deliveryRepo.Slot) *deliveryRepo.Slot {
+	slot.StartsAt = slot.StartsAt.In(s.location)
+	slot.EndsAt = slot.EndsAt.In(s.location)
+	return slot
+}
+
+// inTx runs fn with a repo bound to a new transaction and maps the repo errors
+// of slot writes.
+func (s *Service) inTx(ctx context.Context, fn func(repo *deliveryRepo.Repo) error) error {
+	log := logger.FromContext(ctx, s.log)
+
+	tx, err := s.uow.Begin(ctx)
+	if err != nil {
+		log.Errorw("begin transaction failed", "error", err)
+		return pkgerrors.ErrInternal
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	err = fn(deliveryRepo.NewWithTx(tx.GetTx(), s.log))
+	switch err {
+	case nil:
+	case pkgerrors.ErrNotFound:
+		return pkgerrors.ErrDeliverySlotNotFound
+	case pkgerrors.ErrAlreadyExists:
+		return pkgerrors.ErrDeliverySlotExists
+	default:
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Errorw("commit transaction failed", "error", err)
+		return pkgerrors.ErrInternal
+	}
+	committed = true
+	return nil
+}
@@ -0,0 +1,98 @@
+package idempotency
+
+import (
+	"context"
+	"time"
+
+	idempotencyRepo "github.com/Cora23tt/order_service/internal/repository/idempotency"
+	"github.com/Cora23tt/order_service/pkg/config"
+	pkgerrors "github.com/Cora23tt/order_service/pkg/errors"
+	"github.com/Cora23tt/order_service/pkg/logger"
+	"github.com/Cora23tt/order_service/pkg/utils"
+	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
+)
+
+var tracer = otel.Tracer("github.com/Cora23tt/order_service/internal/usecase/idempotency")
+
+type Service struct {
+	repo *idempotencyRepo.Repo
+	ttl  time.Duration
+	lock time.Duration
+	log  *zap.SugaredLogger
+}
+
+func NewService(repo *idempotencyRepo.Repo, cfg *config.Config, log *zap.SugaredLogger) *Service {
+	return &Service{repo: repo, ttl: cfg.Idempotency.TTL, lock: cfg.Idempotency.LockTimeout, log: log}
+}
+
+// Response is a stored response that is replayed for a repeated request.
+type Response struct {
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+// Begin reserves the key of a user for the request with the given
+// fingerprint. If the key was already used for the same request, the stored
+// response is returned and the request must not be processed again; a nil
+// response means the caller owns the key and must Complete or Release it with
+// the returned token within the lock timeout, after which a retry takes the
+// key over.
+func (s *Service) Begin(ctx context.Context, userID int64, key, fingerprint string) (*Response, string, error) {
+	ctx, span := tracer.Start(ctx, "idempotency.Service.Begin")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
+	if err := s.repo.DeleteExpired(ctx, userID); err != nil {
+		return nil, "", err
+	}
+
+	token := utils.NewUUID()
+	reserved, err := s.repo.Reserve(ctx, userID, key, fingerprint, token, s.ttl, s.lock)
+	if err != nil {
+		return nil, "", err
+	}
+	if reserved {
+		return nil, token, nil
+	}
+
+	rec, err := s.repo.Get(ctx, userID, key)
+	switch err {
+	case nil:
+	case pkgerrors.ErrNotFound:
+		// The key was released or expired after Reserve; the client can
+		// simply retry.
+		return nil, "", pkgerrors.ErrIdempotencyInProgress
+	default:
+		return nil, "", err
+	}
+
+	if rec.Fingerprint != fingerprint {
+		log.Warnw("idempotency key reused for a different request", "key", key)
+		return nil, "", pkgerrors.ErrIdempotencyKeyReused
+	}
+	if rec.StatusCode == nil {
+		return nil, "", pkgerrors.ErrIdempotencyInProgress
+	}
+
+	log.Infow("replaying idempotent response", "key", key, "status", *rec.StatusCode)
+	return &Response{StatusCode: *rec.StatusCode, ContentType: rec.ContentType, Body: rec.Body}, "", nil
+}
+
+// Complete stores the response of a request started with Begin.
+func (s *Service) Complete(ctx context.Context, userID int64, key, token string, resp Response) error {
+	ctx, span := tracer.Start(ctx, "idempotency.Service.Complete")
+	defer span.End()
+
+	return s.repo.Complete(ctx, userID, key, token, resp.StatusCode, resp.ContentType, resp.Body)
+}
+
+// Release frees the key of a request that failed, so that it can be retried
+// with the same key.
+func (s *Service) Release(ctx context.Context, userID int64, key, token string) error {
+	ctx, span := tracer.Start(ctx, "idempotency.Service.Release")
+	defer span.End()
+
+	return s.repo.Delete(ctx, userID, key, token)
+}
@@ -0,0 +1,246 @@
+package order_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	deliveryRepo "github.com/Cora23tt/order_service/internal/repository/delivery"
+	orderRepo "github.com/Cora23tt/order_service/internal/repository/order"
+	pickupRepo "github.com/Cora23tt/order_service/internal/repository/pickup"
+	productRepo "github.com/Cora23tt/order_service/internal/repository/product"
+	promotionRepo "github.com/Cora23tt/order_service/internal/repository/promotion"
+	"github.com/Cora23tt/order_service/internal/repository/uow"
+	"github.com/Cora23tt/order_service/internal/usecase/delivery"
+	"github.com/Cora23tt/order_service/internal/usecase/order"
+	"github.com/Cora23tt/order_service/internal/usecase/pickup"
+	"github.com/Cora23tt/order_service/internal/usecase/promotion"
+	receiptService "github.com/Cora23tt/order_service/internal/usecase/receipt"
+	"github.com/Cora23tt/order_service/pkg/config"
+	"github.com/Cora23tt/order_service/pkg/db"
+	pkgerrors "github.com/Cora23tt/order_service/pkg/errors"
+	"github.com/Cora23tt/order_service/pkg/metrics"
+	"github.com/Cora23tt/order_service/pkg/receipt"
+	"github.com/Cora23tt/order_service/pkg/storage"
+	"github.com/Cora23tt/order_service/pkg/tax"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// These tests run against the Postgres database in TEST_DATABASE_URL and are
+// skipped without it. The database is migrated to the latest version; every
+// test creates its own user, product and pickup point and removes them
+// afterwards.
+
+type fixture struct {
+	pool      *pgxpool.Pool
+	service   *order.Service
+	userID    int64
+	productID int64
+	pointID   int64
+}
+
+func setup(t *testing.T, stock int64) *fixture {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	ctx := context.Background()
+	log := zap.NewNop().Sugar()
+
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	migrator, err := db.NewMigrator(pool, log)
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	cfg := &config.Config{
+		Tax:      config.TaxConfig{PricesIncludeTax: true, Rounding: "half_up"},
+		Receipts: config.ReceiptsConfig{Format: "html"},
+		Pickup:   config.PickupConfig{Timezone: "Asia/Tashkent"},
+		Delivery: config.DeliveryConfig{BookingHorizon: 24 * time.Hour},
+	}
+	pickups, err := pickup.NewService(pickupRepo.NewRepo(pool, log), uow.New(pool), cfg, log)
+	if err != nil {
+		t.Fatalf("pickup service: %v", err)
+	}
+	slots, err := delivery.NewService(deliveryRepo.NewRepo(pool, log), pickups, uow.New(pool), cfg, log)
+	if err != nil {
+		t.Fatalf("delivery service: %v", err)
+	}
+	orders := orderRepo.NewRepo(pool, log)
+	receipts := receiptService.NewService(orders, productRepo.NewRepo(pool, log), storage.NewLocal(t.TempDir()), receipt.New(cfg, log), log)
+	promotions := promotion.NewService(promotionRepo.NewRepo(pool, log), uow.New(pool), log)
+
+	f := &fixture{
+		pool:    pool,
+		service: order.NewService(orders, log, uow.New(pool), metrics.New(pool), promotions, tax.New(cfg), receipts, pickups, slots),
+	}
+
+	phone := fmt.Sprintf("t%d", time.Now().UnixNano()%1e15)
+	if err := pool.QueryRow(ctx, `
+		INSERT INTO users (phone_number, password_hash) VALUES ($1, 'x') RETURNING id
+	`, phone).Scan(&f.userID); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	if err := pool.QueryRow(ctx, `
+		INSERT INTO products (name, price, stock_quantity) VALUES ('concurrency test', 1000, $1) RETURNING id
+	`, stock).Scan(&f.productID); err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+	hours := make([]pickupRepo.OpeningHours, 0, 7)
+	for weekday := 1; weekday <= 7; weekday++ {
+		hours = append(hours, pickupRepo.OpeningHours{Weekday: weekday, Opens: "00:00", Closes: "24:00"})
+	}
+	point, err := pickups.Create(ctx, pickup.PointInput{
+		Name:         "concurrency test",
+		Address:      "test",
+		OpeningHours: hours,
+		Capacity:     1000,
+		Active:       true,
+	})
+	if err != nil {
+		t.Fatalf("create pickup point: %v", err)
+	}
+	f.pointID = point.ID
+
+	t.Cleanup(func() {
+		ctx := context.Background()
+		for _, q := range []string{
+			`DELETE FROM orders WHERE user_id = $1`,
+			`DELETE FROM users WHERE id = $1`,
+		} {
+			if _, err := pool.Exec(ctx, q, f.userID); err != nil {
+				t.Errorf("cleanup: %v", err)
+			}
+		}
+		if _, err := pool.Exec(ctx, `DELETE FROM products WHERE id = $1`, f.productID); err != nil {
+			t.Errorf("cleanup: %v", err)
+		}
+		if _, err := pool.Exec(ctx, `DELETE FROM pickup_points WHERE id = $1`, f.pointID); err != nil {
+			t.Errorf("cleanup: %v", err)
+		}
+	})
+	return f
+}
+
+func (f *fixture) order(ctx context.Context, quantity int64) (int64, error) {
+	return f.service.CreateOrder(ctx, order.CreateOrderInput{
+		UserID:        f.userID,
+		Role:          "user",
+		Items:         []order.OrderItemInput{{ProductID: f.productID, Quantity: quantity}},
+		PickupPointID: f.pointID,
+	})
+}
+
+func (f *fixture) stock(t *testing.T) int64 {
+	t.Helper()
+	var stock int64
+	if err := f.pool.QueryRow(context.Background(), `SELECT stock_quantity FROM products WHERE id = $1`, f.productID).Scan(&stock); err != nil {
+		t.Fatalf("read stock: %v", err)
+	}
+	return stock
+}
+
+func TestCreateOrderConcurrentStock(t *testing.T) {
+	const (
+		stock  = 3
+		orders = 10
+	)
+	f := setup(t, stock)
+	ctx := context.Background()
+
+	var (
+		wg    sync.WaitGroup
+		start = make(chan struct{})
+		errs  = make([]error, orders)
+	)
+	for i := range orders {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, errs[i] = f.order(ctx, 1)
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	succeeded := 0
+	for i, err := range errs {
+		switch err {
+		case nil:
+			succeeded++
+		case pkgerrors.ErrInsufficientStock:
+		default:
+			t.Errorf("order %d: unexpected error %v", i, err)
+		}
+	}
+	if succeeded != stock {
+		t.Errorf("%d orders succeeded, want %d", succeeded, stock)
+	}
+	if got := f.stock(t); got != 0 {
+		t.Errorf("stock after orders = %d, want 0", got)
+	}
+}
+
+func TestCancelOrderReleasesStock(t *testing.T) {
+	f := setup(t, 5)
+	ctx := context.Background()
+
+	id, err := f.order(ctx, 2)
+	if err != nil {
+		t.Fatalf("create order: %v", err)
+	}
+	if got := f.stock(t); got != 3 {
+		t.Fatalf("stock after order = %d, want 3", got)
+	}
+
+	if err := f.service.CancelOrder(ctx, id, order.Actor{UserID: f.userID, Role: "user"}, ""); err != nil {
+		t.Fatalf("cancel order: %v", err)
+	}
+	if got := f.stock(t); got != 5 {
+		t.Errorf("stock after cancel = %d, want 5", got)
+	}
+}
+
+func TestDeleteOrderReleasesStock(t *testing.T) {
+	f := setup(t, 5)
+	ctx := context.Background()
+
+	id, err := f.order(ctx, 2)
+	if err != nil {
+		t.Fatalf("create order: %v", err)
+	}
+
+	if err := f.service.DeleteOrder(ctx, id); err != nil {
+		t.Fatalf("delete order: %v", err)
+	}
+	if got := f.stock(t); got != 5 {
+		t.Errorf("stock after delete = %d, want 5", got)
+	}
+}
+
+func TestCreateOrderRejectsNonPositiveQuantity(t *testing.T) {
+	f := setup(t, 5)
+
+	if _, err := f.order(context.Background(), -5); err != pkgerrors.ErrInvalidInput {
+		t.Fatalf("create order with negative quantity: got %v, want %v", err, pkgerrors.ErrInvalidInput)
+	}
+	if got := f.stock(t); got != 5 {
+		t.Errorf("stock = %d, want 5", got)
+	}
+}
@@ -1,44 +1,103 @@
 package order
 
 import (
+	"cmp"
 	"context"
+	"slices"
 	"time"
 
 	repo "github.com/Cora23tt/order_service/internal/repository/order"
 	"github.com/Cora23tt/order_service/internal/repository/product"
 	"github.com/Cora23tt/order_service/internal/repository/uow"
+	"github.com/Cora23tt/order_service/internal/usecase/delivery"
+	"github.com/Cora23tt/order_service/internal/usecase/pickup"
+	"github.com/Cora23tt/order_service/internal/usecase/promotion"
+	"github.com/Cora23tt/order_service/internal/usecase/receipt"
 	"github.com/Cora23tt/order_service/pkg/enums"
 	"github.com/Cora23tt/order_service/pkg/errors"
+	"github.com/Cora23tt/order_service/pkg/logger"
+	"github.com/Cora23tt/order_service/pkg/metrics"
+	"github.com/Cora23tt/order_service/pkg/tax"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
 	"go.uber.org/zap"
 )
 
+var tracer = otel.Tracer("github.com/Cora23tt/order_service/internal/usecase/order")
+
 type Service struct {
-	repo *repo.Repo
-	log  *zap.SugaredLogger
-	uow  uow.UnitOfWork
+	repo       *repo.Repo
+	log        *zap.SugaredLogger
+	uow        uow.UnitOfWork
+	metrics    *metrics.Metrics
+	promotions *promotion.Service
+	tax        *tax.Calculator
+	receipts   *receipt.Service
+	pickups    *pickup.Service
+	slots      *delivery.Service
+}
+
+func NewService(r *repo.Repo, log *zap.SugaredLogger, uow uow.UnitOfWork, metrics *metrics.Metrics, promotions *promotion.Service, tax *tax.Calculator, receipts *receipt.Service, pickups *pickup.Service, slots *delivery.Service) *Service {
+	return &Service{repo: r, log: log, uow: uow, metrics: metrics, promotions: promotions, tax: tax, receipts: receipts, pickups: pickups, slots: slots}
 }
 
-func NewService(r *repo.Repo, log *zap.SugaredLogger, uow uow.UnitOfWork) *Service {
-	return &Service{repo: r, log: log, uow: uow}
+// Actor identifies who changes an order. UserID is zero for changes made by
+// the system itself.
+type Actor struct {
+	UserID int64
+	Role   string
+}
+
+// SystemActor makes the changes the service does on its own, such as
+// confirming payments and cancelling unpaid orders.
+var SystemActor = Actor{Role: "system"}
+
+func (a Actor) userID() *int64 {
+	if a.UserID == 0 {
+		return nil
+	}
+	return &a.UserID
 }
 
+// CreateOrderInput describes a new order. DeliverySlotID, if set, books a
+// place in a delivery slot of the pickup point.
 type CreateOrderInput struct {
-	UserID       int64
-	Items        []OrderItemInput
-	PickupPoint  string
-	DeliveryDate *time.Time
+	UserID         int64
+	Role           string
+	Items          []OrderItemInput
+	PickupPointID  int64
+	DeliverySlotID *int64
+	PromoCode      string
+
+	// BeforeCommit, if set, runs in the order transaction once the order is
+	// stored. Returning an error rolls the order back and CreateOrder returns
+	// that error.
+	BeforeCommit func(ctx context.Context, tx pgx.Tx, orderID int64) error
 }
 
+// OrderItemInput describes a requested order line. The price is always taken
+// from the product; ExpectedPrice only lets the client detect that it changed.
 type OrderItemInput struct {
-	ProductID int64 `json:"product_id" binding:"required" example:"4"`
-	Quantity  int64 `json:"quantity" binding:"required" example:"4"`
-	Price     int64 `json:"price" binding:"required" example:"12000"`
+	ProductID     int64  `json:"product_id" binding:"required" example:"4"`
+	Quantity      int64  `json:"quantity" binding:"required,gt=0" example:"4"`
+	ExpectedPrice *int64 `json:"expected_price,omitempty" example:"12000"`
 }
 
 func (s *Service) CreateOrder(ctx context.Context, input CreateOrderInput) (int64, error) {
+	ctx, span := tracer.Start(ctx, "order.Service.CreateOrder")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
+	for _, item := range input.Items {
+		if item.Quantity <= 0 {
+			log.Warnw("non-positive order quantity", "product_id", item.ProductID, "quantity", item.Quantity)
+			return 0, errors.ErrInvalidInput
+		}
+	}
+
 	tx, err := s.uow.Begin(ctx)
 	if err != nil {
-		s.log.Errorw("begin transaction failed", "error", err)
+		log.Errorw("begin transaction failed", "error", err)
 		return 0, errors.ErrInternal
 	}
 	committed := false
@@ -51,39 +110,114 @@ func (s *Service) CreateOrder(ctx context.Context, input CreateOrderInput) (int6
 	orderRepo := repo.NewWithTx(tx.GetTx(), s.log)
 	productRepo := product.NewWithTx(tx.GetTx(), s.log)
 
-	var total int64
+	// An order with a delivery slot is delivered when the slot starts, so the
+	// point must be open then. One without can be placed at any time.
+	var deliveryDate, pickupAt *time.Time
+	if input.DeliverySlotID != nil {
+		slot, err := s.slots.Book(ctx, tx.GetTx(), *input.DeliverySlotID, input.PickupPointID)
+		if err != nil {
+			return 0, err
+		}
+		date := s.slots.Date(slot)
+		deliveryDate, pickupAt = &date, &slot.StartsAt
+	}
+	point, err := s.pickups.Select(ctx, tx.GetTx(), input.PickupPointID, pickupAt)
+	if err != nil {
+		return 0, err
+	}
+
+	// Lock products in a stable order so concurrent orders touching the same
+	// products cannot deadlock each other.
+	items := slices.Clone(input.Items)
+	slices.SortFunc(items, func(a, b OrderItemInput) int {
+		return cmp.Compare(a.ProductID, b.ProductID)
+	})
+
+	var subtotal int64
 	order := &repo.Order{
-		UserID:       input.UserID,
-		Status:       enums.StatusPendingPayment,
-		DeliveryDate: input.DeliveryDate,
-		PickupPoint:  input.PickupPoint,
-		TotalAmount:  0,
-		Items:        make([]repo.OrderItem, 0, len(input.Items)),
+		UserID:         input.UserID,
+		Status:         enums.StatusPendingPayment,
+		DeliveryDate:   deliveryDate,
+		DeliverySlotID: input.DeliverySlotID,
+		PickupPointID:  &point.ID,
+		PickupPoint:    point.Name + ", " + point.Address,
+		Items:          make([]repo.OrderItem, 0, len(input.Items)),
 	}
+	lines := make([]promotion.Line, 0, len(items))
 
-	for _, item := range input.Items {
-		product, err := productRepo.GetProductByID(ctx, item.ProductID)
+	for _, item := range items {
+		product, err := productRepo.GetProductByIDForUpdate(ctx, item.ProductID)
 		if err != nil {
-			s.log.Errorw("product not found", "product_id", item.ProductID, "error", err)
-			return 0, errors.ErrInvalidInput
+			log.Errorw("product not found", "product_id", item.ProductID, "error", err)
+			if err == errors.ErrNotFound {
+				return 0, errors.ErrUnknownProduct
+			}
+			return 0, errors.ErrInternal
+		}
+		if item.ExpectedPrice != nil && *item.ExpectedPrice != product.Price {
+			log.Warnw("price changed", "product_id", item.ProductID, "expected", *item.ExpectedPrice, "actual", product.Price)
+			return 0, errors.ErrPriceChanged
 		}
 		if product.StockQuantity < item.Quantity {
-			s.log.Warnw("insufficient stock", "product_id", item.ProductID, "available", product.StockQuantity, "requested", item.Quantity)
+			log.Warnw("insufficient stock", "product_id", item.ProductID, "available", product.StockQuantity, "requested", item.Quantity)
+			s.metrics.StockOutRejected()
 			return 0, errors.ErrInsufficientStock
 		}
+		if err := productRepo.DecreaseStock(ctx, item.ProductID, item.Quantity); err != nil {
+			log.Errorw("decrease stock failed", "product_id", item.ProductID, "requested", item.Quantity, "error", err)
+			switch err {
+			case errors.ErrInsufficientStock:
+				s.metrics.StockOutRejected()
+				return 0, err
+			case errors.ErrInvalidInput:
+				return 0, err
+			default:
+				return 0, errors.ErrInternal
+			}
+		}
 
-		total += item.Price * item.Quantity
+		subtotal += product.Price * item.Quantity
 		order.Items = append(order.Items, repo.OrderItem{
-			ProductID: item.ProductID,
-			Quantity:  item.Quantity,
-			Price:     item.Price,
+			ProductID:  item.ProductID,
+			Quantity:   item.Quantity,
+			Price:      product.Price,
+			TotalPrice: product.Price * item.Quantity,
+			VATRate:    product.VATRate,
 		})
+		lines = append(lines, promotion.Line{ProductID: item.ProductID, Amount: product.Price * item.Quantity})
+	}
+	order.SubtotalAmount = subtotal
+
+	if input.PromoCode != "" {
+		discount, err := s.promotions.Apply(ctx, tx.GetTx(), input.UserID, input.PromoCode, lines)
+		if err != nil {
+			return 0, err
+		}
+		order.DiscountAmount = discount.Amount
+		order.Discounts = append(order.Discounts, repo.OrderDiscount{
+			PromotionID: &discount.PromotionID,
+			Code:        discount.Code,
+			Amount:      discount.Amount,
+		})
+		for i := range order.Items {
+			order.Items[i].DiscountAmount = discount.LineAmounts[i]
+		}
+	}
+
+	// VAT is computed per line on the discounted amount, so the order totals
+	// are the sums of the rounded line amounts.
+	for i := range order.Items {
+		item := &order.Items[i]
+		amounts := s.tax.Line(item.TotalPrice-item.DiscountAmount, item.VATRate)
+		item.NetAmount, item.TaxAmount, item.GrossAmount = amounts.Net, amounts.Tax, amounts.Gross
+		order.NetAmount += amounts.Net
+		order.TaxAmount += amounts.Tax
+		order.TotalAmount += amounts.Gross
 	}
-	order.TotalAmount = total
 
 	orderID, err := orderRepo.Create(ctx, order)
 	if err != nil {
-		s.log.Errorw("create order failed", "user_id", input.UserID, "error", err)
+		log.Errorw("create order failed", "user_id", input.UserID, "error", err)
 		switch err {
 		case errors.ErrInvalidInput, errors.ErrAlreadyExists:
 			return 0, err
@@ -92,86 +226,707 @@ func (s *Service) CreateOrder(ctx context.Context, input CreateOrderInput) (int6
 		}
 	}
 
+	err = orderRepo.AddStatusChange(ctx, &repo.StatusChange{
+		OrderID:     orderID,
+		ToStatus:    order.Status,
+		ActorUserID: &input.UserID,
+		ActorRole:   input.Role,
+	})
+	if err != nil {
+		log.Errorw("record initial order status failed", "order_id", orderID, "error", err)
+		return 0, errors.ErrInternal
+	}
+
+	if input.BeforeCommit != nil {
+		if err := input.BeforeCommit(ctx, tx.GetTx(), orderID); err != nil {
+			return 0, err
+		}
+	}
+
 	if err := tx.Commit(ctx); err != nil {
-		s.log.Errorw("commit transaction failed", "order_id", orderID, "error", err)
+		log.Errorw("commit transaction failed", "order_id", orderID, "error", err)
 		return 0, errors.ErrInternal
 	}
 	committed = true
+	s.metrics.OrderCreated()
 
-	s.log.Infow("order created", "order_id", orderID, "user_id", input.UserID)
+	log.Infow("order created", "order_id", orderID, "user_id", input.UserID, "discount", order.DiscountAmount)
 	return orderID, nil
 }
 
 func (s *Service) GetOrderByID(ctx context.Context, orderID, userID int64, role string) (*repo.Order, error) {
+	ctx, span := tracer.Start(ctx, "order.Service.GetOrderByID")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
 	order, err := s.repo.GetByID(ctx, orderID)
 	if err != nil {
-		s.log.Errorw("get order by id failed", "order_id", orderID, "error", err)
+		log.Errorw("get order by id failed", "order_id", orderID, "error", err)
 		switch err {
 		case errors.ErrNotFound:
-			return nil, err
+			return nil, errors.ErrOrderNotFound
 		default:
 			return nil, errors.ErrInternal
 		}
 	}
 
 	if role != "admin" && order.UserID != userID {
-		s.log.Warnw("unauthorized access to order", "order_id", orderID, "requester_id", userID)
-		return nil, errors.ErrNotFound
+		log.Warnw("unauthorized access to order", "order_id", orderID, "requester_id", userID)
+		return nil, errors.ErrOrderNotFound
+	}
+
+	order.History, err = s.repo.GetStatusHistory(ctx, orderID)
+	if err != nil {
+		log.Errorw("get order history failed", "order_id", orderID, "error", err)
+		return nil, errors.ErrInternal
 	}
 
 	return order, nil
 }
 
+// GetOrderHistory returns the status timeline of the order. Users only see
+// the history of their own orders.
+func (s *Service) GetOrderHistory(ctx context.Context, orderID, userID int64, role string) ([]repo.StatusChange, error) {
+	ctx, span := tracer.Start(ctx, "order.Service.GetOrderHistory")
+	defer span.End()
+
+	order, err := s.GetOrderByID(ctx, orderID, userID, role)
+	if err != nil {
+		return nil, err
+	}
+	return order.History, nil
+}
+
 func (s *Service) GetUserOrders(ctx context.Context, userID int64) ([]*repo.Order, error) {
+	ctx, span := tracer.Start(ctx, "order.Service.GetUserOrders")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
 	orders, err := s.repo.GetAllByUser(ctx, userID)
 	if err != nil {
-		s.log.Errorw("get user orders failed", "user_id", userID, "error", err)
+		log.Errorw("get user orders failed", "user_id", userID, "error", err)
 		return nil, errors.ErrInternal
 	}
 	return orders, nil
 }
 
-func (s *Service) CancelOrder(ctx context.Context, orderID, userID int64) error {
-	order, err := s.repo.GetByID(ctx, orderID)
+func (s *Service) CancelOrder(ctx context.Context, orderID int64, actor Actor, reason string) error {
+	ctx, span := tracer.Start(ctx, "order.Service.CancelOrder")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
+	tx, err := s.uow.Begin(ctx)
+	if err != nil {
+		log.Errorw("begin transaction failed", "error", err)
+		return errors.ErrInternal
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	orderRepo := repo.NewWithTx(tx.GetTx(), s.log)
+	productRepo := product.NewWithTx(tx.GetTx(), s.log)
+
+	order, err := orderRepo.GetByIDForUpdate(ctx, orderID)
 	if err != nil {
-		s.log.Errorw("cancel order: get by id failed", "order_id", orderID, "error", err)
+		log.Errorw("cancel order: get by id failed", "order_id", orderID, "error", err)
 		switch err {
 		case errors.ErrNotFound:
-			return err
+			return errors.ErrOrderNotFound
 		default:
 			return errors.ErrInternal
 		}
 	}
 
-	if order.UserID != userID {
-		s.log.Warnw("cancel order: forbidden", "order_id", orderID, "request_user_id", userID, "owner_user_id", order.UserID)
-		return errors.ErrUnauthorized
+	if order.UserID != actor.UserID {
+		log.Warnw("cancel order: forbidden", "order_id", orderID, "request_user_id", actor.UserID, "owner_user_id", order.UserID)
+		return errors.ErrForbidden
 	}
 
 	if order.Status != enums.StatusPendingPayment {
-		s.log.Warnw("cancel order: invalid status", "order_id", orderID, "status", order.Status)
-		return errors.ErrInvalidInput
+		log.Warnw("cancel order: invalid status", "order_id", orderID, "status", order.Status)
+		return errors.ErrCancelNotAllowed
+	}
+
+	if err := s.transition(ctx, orderRepo, productRepo, order, enums.StatusCancelled, actor, reason); err != nil {
+		return err
 	}
 
-	err = s.repo.UpdateStatus(ctx, orderID, "cancelled")
+	if err := tx.Commit(ctx); err != nil {
+		log.Errorw("commit transaction failed", "order_id", orderID, "error", err)
+		return errors.ErrInternal
+	}
+	committed = true
+	s.recordTransition(ctx, enums.StatusPendingPayment, order)
+
+	log.Infow("order cancelled", "order_id", orderID)
+	return nil
+}
+
+func (s *Service) AdminUpdateOrderStatus(ctx context.Context, orderID int64, status enums.OrderStatus, actor Actor, reason string) error {
+	ctx, span := tracer.Start(ctx, "order.Service.AdminUpdateOrderStatus")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
+	if status.IsRefund() {
+		return errors.ErrRefundStatusNotAllowed
+	}
+
+	tx, err := s.uow.Begin(ctx)
 	if err != nil {
-		s.log.Errorw("cancel order: update status failed", "order_id", orderID, "error", err)
+		log.Errorw("begin transaction failed", "error", err)
+		return errors.ErrInternal
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	orderRepo := repo.NewWithTx(tx.GetTx(), s.log)
+	productRepo := product.NewWithTx(tx.GetTx(), s.log)
+
+	order, err := orderRepo.GetByIDForUpdate(ctx, orderID)
+	if err != nil {
+		log.Errorw("admin update order status: get by id failed", "order_id", orderID, "error", err)
 		switch err {
-		case errors.ErrNotFound, errors.ErrInvalidInput:
-			return err
+		case errors.ErrNotFound:
+			return errors.ErrOrderNotFound
 		default:
 			return errors.ErrInternal
 		}
 	}
 
-	s.log.Infow("order cancelled", "order_id", orderID)
+	// Only unpaid orders can be cancelled; cancelling a paid one would keep
+	// the customer's money, so it is refunded in full instead.
+	if status == enums.StatusCancelled && order.Status != enums.StatusPendingPayment && !order.Status.IsFinal() {
+		log.Warnw("cancel of a paid order", "order_id", orderID, "status", order.Status)
+		return errors.ErrCancelRequiresRefund
+	}
+
+	from := order.Status
+	if err := s.transition(ctx, orderRepo, productRepo, order, status, actor, reason); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Errorw("commit transaction failed", "order_id", orderID, "error", err)
+		return errors.ErrInternal
+	}
+	committed = true
+	s.recordTransition(ctx, from, order)
+
+	log.Infow("admin updated order status", "order_id", orderID, "status", status)
 	return nil
 }
 
-func (s *Service) AdminUpdateOrderStatus(ctx context.Context, orderID int64, status string) error {
-	err := s.repo.UpdateStatus(ctx, orderID, status)
+// MarkPaid moves a pending order to paid on behalf of the system, e.g. when
+// the payment provider confirms the payment. beforeCommit, if set, runs in the
+// same transaction after the order is locked and moved; returning an error
+// rolls the change back and MarkPaid returns that error.
+func (s *Service) MarkPaid(ctx context.Context, orderID int64, reason string, beforeCommit func(ctx context.Context, tx pgx.Tx) error) error {
+	ctx, span := tracer.Start(ctx, "order.Service.MarkPaid")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
+	tx, err := s.uow.Begin(ctx)
+	if err != nil {
+		log.Errorw("begin transaction failed", "error", err)
+		return errors.ErrInternal
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	orderRepo := repo.NewWithTx(tx.GetTx(), s.log)
+	productRepo := product.NewWithTx(tx.GetTx(), s.log)
+
+	order, err := orderRepo.GetByIDForUpdate(ctx, orderID)
+	if err != nil {
+		log.Errorw("mark paid: get by id failed", "order_id", orderID, "error", err)
+		switch err {
+		case errors.ErrNotFound:
+			return errors.ErrOrderNotFound
+		default:
+			return errors.ErrInternal
+		}
+	}
+
+	from := order.Status
+	if err := s.transition(ctx, orderRepo, productRepo, order, enums.StatusPaid, SystemActor, reason); err != nil {
+		return err
+	}
+
+	if beforeCommit != nil {
+		if err := beforeCommit(ctx, tx.GetTx()); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Errorw("commit transaction failed", "order_id", orderID, "error", err)
+		return errors.ErrInternal
+	}
+	committed = true
+	s.recordTransition(ctx, from, order)
+
+	log.Infow("order paid", "order_id", orderID)
+	return nil
+}
+
+// CancelExpired cancels up to limit orders that have been pending payment for
+// longer than timeout on behalf of the system and returns their stock. It
+// returns how many orders were cancelled; fewer than limit means no expired
+// orders are left, except those being cancelled by another instance.
+func (s *Service) CancelExpired(ctx context.Context, timeout time.Duration, limit int) (int, error) {
+	ctx, span := tracer.Start(ctx, "order.Service.CancelExpired")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
+	tx, err := s.uow.Begin(ctx)
+	if err != nil {
+		log.Errorw("begin transaction failed", "error", err)
+		return 0, errors.ErrInternal
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	orderRepo := repo.NewWithTx(tx.GetTx(), s.log)
+	productRepo := product.NewWithTx(tx.GetTx(), s.log)
+
+	ids, err := orderRepo.LockExpired(ctx, timeout, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	orders := make([]*repo.Order, 0, len(ids))
+	for _, id := range ids {
+		order, err := orderRepo.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			log.Errorw("cancel expired: get by id failed", "order_id", id, "error", err)
+			return 0, errors.ErrInternal
+		}
+		if err := s.transition(ctx, orderRepo, productRepo, order, enums.StatusCancelled, SystemActor, "payment timeout"); err != nil {
+			return 0, err
+		}
+		orders = append(orders, order)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Errorw("commit transaction failed", "error", err)
+		return 0, errors.ErrInternal
+	}
+	committed = true
+	for _, order := range orders {
+		s.recordTransition(ctx, enums.StatusPendingPayment, order)
+		log.Infow("expired order cancelled", "order_id", order.ID, "created_at", order.CreatedAt)
+	}
+	s.metrics.OrdersExpired(len(orders))
+
+	return len(orders), nil
+}
+
+// RefundInput selects what to refund. Without items everything not refunded
+// yet is refunded.
+type RefundInput struct {
+	Items  []RefundItemInput
+	Reason string
+
+	// BeforeCommit, if set, runs in the refund transaction once the refund
+	// is stored and before the money is returned by the provider. Returning
+	// an error rolls the refund back.
+	BeforeCommit func(ctx context.Context, tx pgx.Tx, refund *repo.Refund) error
+}
+
+// RefundItemInput is a quantity of an order line to refund.
+type RefundItemInput struct {
+	ItemID   int64 `json:"item_id" binding:"required" example:"12"`
+	Quantity int64 `json:"quantity" binding:"required,gt=0" example:"1"`
+}
+
+// RefundPayment returns amount of the order's payment to the customer and
+// returns the reference of the refund at the payment provider. It runs in the
+// refund transaction with the order locked, after everything else is stored
+// and right before the commit, so an error leaves the order unchanged.
+type RefundPayment func(ctx context.Context, tx pgx.Tx, order *repo.Order, amount int64) (string, error)
+
+// RefundOrder refunds items of a paid order: the money is returned through
+// pay, the items go back to stock and the order becomes refunded once every
+// item is refunded, partially_refunded otherwise. A line's refund is its
+// share of the gross amount the customer paid for it, rounded so that
+// refunding all of its items returns exactly that amount.
+func (s *Service) RefundOrder(ctx context.Context, orderID int64, input RefundInput, actor Actor, pay RefundPayment) (*repo.Refund, error) {
+	ctx, span := tracer.Start(ctx, "order.Service.RefundOrder")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
+	tx, err := s.uow.Begin(ctx)
 	if err != nil {
-		s.log.Errorw("admin update order status failed", "order_id", orderID, "status", status, "error", err)
+		log.Errorw("begin transaction failed", "error", err)
+		return nil, errors.ErrInternal
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	orderRepo := repo.NewWithTx(tx.GetTx(), s.log)
+	productRepo := product.NewWithTx(tx.GetTx(), s.log)
+
+	order, err := orderRepo.GetByIDForUpdate(ctx, orderID)
+	if err != nil {
+		log.Errorw("refund order: get by id failed", "order_id", orderID, "error", err)
+		switch err {
+		case errors.ErrNotFound:
+			return nil, errors.ErrOrderNotFound
+		default:
+			return nil, errors.ErrInternal
+		}
+	}
+	if !order.Status.CanTransitionTo(enums.StatusRefunded) {
+		log.Warnw("refund order: invalid status", "order_id", orderID, "status", order.Status)
+		return nil, errors.ErrOrderNotRefundable
+	}
+
+	quantities := make(map[int64]int64, len(input.Items))
+	for _, item := range input.Items {
+		quantities[item.ItemID] += item.Quantity
+	}
+	if len(quantities) == 0 {
+		for _, item := range order.Items {
+			quantities[item.ID] = item.Quantity - item.RefundedQuantity
+		}
+	}
+
+	refund := &repo.Refund{
+		OrderID:     order.ID,
+		Reason:      input.Reason,
+		ActorUserID: actor.userID(),
+	}
+	fully := true
+	for _, item := range order.Items {
+		quantity, ok := quantities[item.ID]
+		delete(quantities, item.ID)
+		if item.RefundedQuantity+quantity > item.Quantity {
+			log.Warnw("refund quantity exceeded", "order_id", orderID, "item_id", item.ID, "quantity", quantity, "refunded", item.RefundedQuantity)
+			return nil, errors.ErrRefundQuantityExceeded
+		}
+		if item.RefundedQuantity+quantity < item.Quantity {
+			fully = false
+		}
+		if !ok || quantity == 0 {
+			continue
+		}
+
+		// Every refund returns the difference of the cumulative shares, so
+		// the refunds of a line add up to its gross amount.
+		before := item.GrossAmount * item.RefundedQuantity / item.Quantity
+		after := item.GrossAmount * (item.RefundedQuantity + quantity) / item.Quantity
+		refund.Items = append(refund.Items, repo.RefundItem{
+			OrderItemID: item.ID,
+			ProductID:   item.ProductID,
+			Quantity:    quantity,
+			Amount:      after - before,
+		})
+		refund.Amount += after - before
+	}
+	for itemID := range quantities {
+		log.Warnw("refund of unknown order item", "order_id", orderID, "item_id", itemID)
+		return nil, errors.ErrOrderItemNotFound
+	}
+	if len(refund.Items) == 0 {
+		return nil, errors.ErrOrderNotRefundable
+	}
+	if order.RefundedAmount+refund.Amount > order.TotalAmount {
+		log.Errorw("refund exceeds order total", "order_id", orderID, "amount", refund.Amount, "refunded", order.RefundedAmount, "total", order.TotalAmount)
+		return nil, errors.ErrRefundExceedsPaid
+	}
+
+	// Products are locked in id order, the same way CreateOrder does.
+	returned := slices.Clone(refund.Items)
+	slices.SortFunc(returned, func(a, b repo.RefundItem) int {
+		return cmp.Compare(a.ProductID, b.ProductID)
+	})
+	for _, item := range returned {
+		if err := productRepo.IncreaseStock(ctx, item.ProductID, item.Quantity); err != nil {
+			log.Errorw("restock refunded item failed", "order_id", orderID, "product_id", item.ProductID, "quantity", item.Quantity, "error", err)
+			return nil, errors.ErrInternal
+		}
+	}
+
+	if err := orderRepo.AddRefund(ctx, refund); err != nil {
+		log.Errorw("add refund failed", "order_id", orderID, "error", err)
+		return nil, errors.ErrInternal
+	}
+
+	to := enums.StatusPartiallyRefunded
+	if fully {
+		to = enums.StatusRefunded
+	}
+	from := order.Status
+	if err := s.transition(ctx, orderRepo, productRepo, order, to, actor, input.Reason); err != nil {
+		return nil, err
+	}
+
+	if input.BeforeCommit != nil {
+		if err := input.BeforeCommit(ctx, tx.GetTx(), refund); err != nil {
+			return nil, err
+		}
+	}
+
+	// The money goes out last, so nothing but the commit can fail after it.
+	if refund.Amount > 0 {
+		refund.ProviderRef, err = pay(ctx, tx.GetTx(), order, refund.Amount)
+		if err != nil {
+			return nil, err
+		}
+		if err := orderRepo.SetRefundProviderRef(ctx, refund.ID, refund.ProviderRef); err != nil {
+			log.Errorw("refunded by the provider but not recorded, manual reconciliation required", "order_id", orderID, "amount", refund.Amount, "provider_ref", refund.ProviderRef, "error", err)
+			return nil, errors.ErrInternal
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Errorw("refunded by the provider but not recorded, manual reconciliation required", "order_id", orderID, "amount", refund.Amount, "provider_ref", refund.ProviderRef, "error", err)
+		return nil, errors.ErrInternal
+	}
+	committed = true
+	s.recordTransition(ctx, from, order)
+	s.metrics.RefundIssued(refund.Amount)
+
+	log.Infow("order refunded", "order_id", orderID, "refund_id", refund.ID, "amount", refund.Amount, "status", to)
+	return refund, nil
+}
+
+// CreateReplacement creates a free order that replaces the given items of
+// the delivered order; each item may be listed once. The replacement starts
+// out paid, so it is fulfilled like any
+// other order, and its items are taken from stock. The replaced goods are not
+// put back to stock. beforeCommit, if set, runs in the same transaction.
+func (s *Service) CreateReplacement(ctx context.Context, orderID int64, items []RefundItemInput, actor Actor, reason string, beforeCommit func(ctx context.Context, tx pgx.Tx, replacementID int64) error) (int64, error) {
+	ctx, span := tracer.Start(ctx, "order.Service.CreateReplacement")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
+	tx, err := s.uow.Begin(ctx)
+	if err != nil {
+		log.Errorw("begin transaction failed", "error", err)
+		return 0, errors.ErrInternal
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	orderRepo := repo.NewWithTx(tx.GetTx(), s.log)
+	productRepo := product.NewWithTx(tx.GetTx(), s.log)
+
+	original, err := orderRepo.GetByIDForUpdate(ctx, orderID)
+	if err != nil {
+		log.Errorw("create replacement: get by id failed", "order_id", orderID, "error", err)
+		switch err {
+		case errors.ErrNotFound:
+			return 0, errors.ErrOrderNotFound
+		default:
+			return 0, errors.ErrInternal
+		}
+	}
+
+	stage, err := s.fulfilmentStage(ctx, orderRepo, original)
+	if err != nil {
+		return 0, err
+	}
+	if stage != enums.StatusDelivered {
+		log.Warnw("replacement for undelivered order", "order_id", orderID, "status", original.Status)
+		return 0, errors.ErrReturnNotAllowed
+	}
+
+	replacement := &repo.Order{
+		UserID:        original.UserID,
+		Status:        enums.StatusPaid,
+		PickupPointID: original.PickupPointID,
+		PickupPoint:   original.PickupPoint,
+	}
+	seen := make(map[int64]bool, len(items))
+	for _, input := range items {
+		if seen[input.ItemID] {
+			log.Warnw("order item replaced twice", "order_id", orderID, "item_id", input.ItemID)
+			return 0, errors.ErrInvalidInput
+		}
+		seen[input.ItemID] = true
+		i := slices.IndexFunc(original.Items, func(item repo.OrderItem) bool { return item.ID == input.ItemID })
+		if i < 0 {
+			log.Warnw("replacement of unknown order item", "order_id", orderID, "item_id", input.ItemID)
+			return 0, errors.ErrOrderItemNotFound
+		}
+		item := original.Items[i]
+		if input.Quantity <= 0 {
+			log.Warnw("non-positive replacement quantity", "order_id", orderID, "item_id", item.ID, "quantity", input.Quantity)
+			return 0, errors.ErrInvalidInput
+		}
+		if input.Quantity > item.Quantity-item.RefundedQuantity {
+			log.Warnw("replacement quantity exceeded", "order_id", orderID, "item_id", item.ID, "quantity", input.Quantity, "refunded", item.RefundedQuantity)
+			return 0, errors.ErrReturnQuantityExceeded
+		}
+		replacement.Items = append(replacement.Items, repo.OrderItem{
+			ProductID: item.ProductID,
+			Quantity:  input.Quantity,
+			VATRate:   item.VATRate,
+		})
+	}
+
+	// Products are locked in id order, the same way CreateOrder does.
+	reserved := slices.Clone(replacement.Items)
+	slices.SortFunc(reserved, func(a, b repo.OrderItem) int {
+		return cmp.Compare(a.ProductID, b.ProductID)
+	})
+	for _, item := range reserved {
+		if err := productRepo.DecreaseStock(ctx, item.ProductID, item.Quantity); err != nil {
+			log.Errorw("reserve replacement stock failed", "order_id", orderID, "product_id", item.ProductID, "quantity", item.Quantity, "error", err)
+			switch err {
+			case errors.ErrInsufficientStock:
+				s.metrics.StockOutRejected()
+				return 0, err
+			default:
+				return 0, errors.ErrInternal
+			}
+		}
+	}
+
+	replacementID, err := orderRepo.Create(ctx, replacement)
+	if err != nil {
+		log.Errorw("create replacement order failed", "order_id", orderID, "error", err)
+		return 0, errors.ErrInternal
+	}
+
+	change := &repo.StatusChange{
+		OrderID:     replacementID,
+		ToStatus:    replacement.Status,
+		ActorUserID: actor.userID(),
+		ActorRole:   actor.Role,
+	}
+	if reason != "" {
+		change.Reason = &reason
+	}
+	if err := orderRepo.AddStatusChange(ctx, change); err != nil {
+		log.Errorw("record initial order status failed", "order_id", replacementID, "error", err)
+		return 0, errors.ErrInternal
+	}
+
+	if beforeCommit != nil {
+		if err := beforeCommit(ctx, tx.GetTx(), replacementID); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Errorw("commit transaction failed", "order_id", replacementID, "error", err)
+		return 0, errors.ErrInternal
+	}
+	committed = true
+	s.metrics.OrderCreated()
+	s.issueReceipt(ctx, replacementID)
+
+	log.Infow("replacement order created", "order_id", replacementID, "original_order_id", orderID)
+	return replacementID, nil
+}
+
+// GetNextStatuses returns the current status of the order and the statuses it
+// may be moved to.
+func (s *Service) GetNextStatuses(ctx context.Context, orderID int64) (enums.OrderStatus, []enums.OrderStatus, error) {
+	ctx, span := tracer.Start(ctx, "order.Service.GetNextStatuses")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
+	order, err := s.repo.GetByID(ctx, orderID)
+	if err != nil {
+		log.Errorw("get next statuses failed", "order_id", orderID, "error", err)
+		switch err {
+		case errors.ErrNotFound:
+			return "", nil, errors.ErrOrderNotFound
+		default:
+			return "", nil, errors.ErrInternal
+		}
+	}
+	next, err := s.nextStatuses(ctx, s.repo, order)
+	if err != nil {
+		return "", nil, err
+	}
+	return order.Status, next, nil
+}
+
+// nextStatuses returns the statuses the order may be moved to. A partially
+// refunded order is still fulfilled from the stage it had reached, so it can
+// only move on from that stage.
+func (s *Service) nextStatuses(ctx context.Context, orderRepo *repo.Repo, order *repo.Order) ([]enums.OrderStatus, error) {
+	next := order.Status.NextStatuses()
+	if order.Status != enums.StatusPartiallyRefunded {
+		return next, nil
+	}
+	stage, err := s.fulfilmentStage(ctx, orderRepo, order)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(next, func(to enums.OrderStatus) bool {
+		return !to.IsRefund() && !stage.CanTransitionTo(to)
+	}), nil
+}
+
+// fulfilmentStage returns how far the order was fulfilled. The status says so
+// for every order but a partially refunded one, whose stage is the furthest
+// of processing, shipped and delivered it reached, or paid.
+func (s *Service) fulfilmentStage(ctx context.Context, orderRepo *repo.Repo, order *repo.Order) (enums.OrderStatus, error) {
+	if order.Status != enums.StatusPartiallyRefunded {
+		return order.Status, nil
+	}
+	for _, stage := range []enums.OrderStatus{enums.StatusDelivered, enums.StatusShipped, enums.StatusProcessing} {
+		at, err := orderRepo.StatusReachedAt(ctx, order.ID, stage)
+		if err != nil {
+			return "", errors.ErrInternal
+		}
+		if at != nil {
+			return stage, nil
+		}
+	}
+	return enums.StatusPaid, nil
+}
+
+// transition moves a locked order to the given status according to the
+// transition table in enums and records the change in the order history.
+// Cancelling returns the reserved stock.
+func (s *Service) transition(ctx context.Context, orderRepo *repo.Repo, productRepo *product.Repo, order *repo.Order, to enums.OrderStatus, actor Actor, reason string) error {
+	log := logger.FromContext(ctx, s.log)
+
+	if order.Status.IsFinal() {
+		log.Warnw("order already completed", "order_id", order.ID, "status", order.Status, "to", to)
+		return errors.ErrOrderCompleted
+	}
+	next, err := s.nextStatuses(ctx, orderRepo, order)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(next, to) {
+		log.Warnw("invalid status transition", "order_id", order.ID, "from", order.Status, "to", to)
+		return errors.ErrInvalidTransition
+	}
+
+	if err := orderRepo.UpdateStatus(ctx, order.ID, to); err != nil {
+		log.Errorw("update status failed", "order_id", order.ID, "status", to, "error", err)
 		switch err {
 		case errors.ErrNotFound, errors.ErrInvalidInput:
 			return err
@@ -179,26 +934,150 @@ func (s *Service) AdminUpdateOrderStatus(ctx context.Context, orderID int64, sta
 			return errors.ErrInternal
 		}
 	}
-	s.log.Infow("admin updated order status", "order_id", orderID, "status", status)
+
+	if to == enums.StatusCancelled {
+		if err := s.releaseHeldStock(ctx, orderRepo, productRepo, order); err != nil {
+			return err
+		}
+	}
+
+	from := order.Status
+	change := &repo.StatusChange{
+		OrderID:     order.ID,
+		FromStatus:  &from,
+		ToStatus:    to,
+		ActorUserID: actor.userID(),
+		ActorRole:   actor.Role,
+	}
+	if reason != "" {
+		change.Reason = &reason
+	}
+	if err := orderRepo.AddStatusChange(ctx, change); err != nil {
+		log.Errorw("record status change failed", "order_id", order.ID, "from", from, "to", to, "error", err)
+		return errors.ErrInternal
+	}
+
+	order.Status = to
 	return nil
 }
 
+// recordTransition updates the metrics after a status change was committed.
+// An order counts towards revenue and gets its receipt once it is paid.
+func (s *Service) recordTransition(ctx context.Context, from enums.OrderStatus, order *repo.Order) {
+	s.metrics.OrderStatusChanged(string(from), string(order.Status))
+	if order.Status == enums.StatusPaid {
+		s.metrics.RevenueReceived(order.TotalAmount)
+		s.issueReceipt(ctx, order.ID)
+	}
+}
+
+// issueReceipt generates the receipt of a paid order. The payment is already
+// committed, so a failure is only logged; the receipt is generated again when
+// it is first downloaded.
+func (s *Service) issueReceipt(ctx context.Context, orderID int64) {
+	if err := s.receipts.Generate(ctx, orderID); err != nil {
+		logger.FromContext(ctx, s.log).Errorw("issue receipt failed", "order_id", orderID, "error", err)
+	}
+}
+
 func (s *Service) DeleteOrder(ctx context.Context, orderID int64) error {
-	err := s.repo.Delete(ctx, orderID)
+	ctx, span := tracer.Start(ctx, "order.Service.DeleteOrder")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
+	tx, err := s.uow.Begin(ctx)
+	if err != nil {
+		log.Errorw("begin transaction failed", "error", err)
+		return errors.ErrInternal
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	orderRepo := repo.NewWithTx(tx.GetTx(), s.log)
+	productRepo := product.NewWithTx(tx.GetTx(), s.log)
+
+	order, err := orderRepo.GetByIDForUpdate(ctx, orderID)
 	if err != nil {
-		s.log.Errorw("delete order failed", "order_id", orderID, "error", err)
+		log.Errorw("delete order: get by id failed", "order_id", orderID, "error", err)
 		switch err {
 		case errors.ErrNotFound:
-			return err
+			return errors.ErrOrderNotFound
 		default:
 			return errors.ErrInternal
 		}
 	}
-	s.log.Infow("order deleted", "order_id", orderID)
+
+	if err := s.releaseHeldStock(ctx, orderRepo, productRepo, order); err != nil {
+		return err
+	}
+
+	err = orderRepo.Delete(ctx, orderID)
+	if err != nil {
+		log.Errorw("delete order failed", "order_id", orderID, "error", err)
+		switch err {
+		case errors.ErrNotFound:
+			return errors.ErrOrderNotFound
+		default:
+			return errors.ErrInternal
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Errorw("commit transaction failed", "order_id", orderID, "error", err)
+		return errors.ErrInternal
+	}
+	committed = true
+
+	log.Infow("order deleted", "order_id", orderID)
+	return nil
+}
+
+// releaseHeldStock returns the items of the order to stock if it still holds
+// them: a partially refunded order does until it reaches delivered.
+func (s *Service) releaseHeldStock(ctx context.Context, orderRepo *repo.Repo, productRepo *product.Repo, order *repo.Order) error {
+	stage, err := s.fulfilmentStage(ctx, orderRepo, order)
+	if err != nil {
+		return err
+	}
+	if !order.Status.HoldsStock() || !stage.HoldsStock() {
+		return nil
+	}
+	return s.releaseStock(ctx, productRepo, order)
+}
+
+// releaseStock puts the quantities reserved by the order back to the products.
+// Products are locked in id order, the same way CreateOrder does.
+func (s *Service) releaseStock(ctx context.Context, productRepo *product.Repo, order *repo.Order) error {
+	log := logger.FromContext(ctx, s.log)
+
+	items := slices.Clone(order.Items)
+	slices.SortFunc(items, func(a, b repo.OrderItem) int {
+		return cmp.Compare(a.ProductID, b.ProductID)
+	})
+
+	for _, item := range items {
+		// Refunded items were already put back by their refund.
+		quantity := item.Quantity - item.RefundedQuantity
+		if quantity == 0 {
+			continue
+		}
+		if err := productRepo.IncreaseStock(ctx, item.ProductID, quantity); err != nil {
+			log.Errorw("release stock failed", "order_id", order.ID, "product_id", item.ProductID, "quantity", quantity, "error", err)
+			return errors.ErrInternal
+		}
+	}
 	return nil
 }
 
 func (s *Service) GetStats(ctx context.Context, from, to *time.Time) ([]repo.OrderStats, error) {
+	ctx, span := tracer.Start(ctx, "order.Service.GetStats")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
 	orderRepo := s.repo
 
 	now := time.Now()
@@ -213,8 +1092,8 @@ func (s *Service) GetStats(ctx context.Context, from, to *time.Time) ([]repo.Ord
 	}
 
 	if !start.Before(end) && !start.Equal(end) {
-		s.log.Warnw("invalid date range", "from", start, "to", end)
-		return nil, errors.ErrInvalidInput
+		log.Warnw("invalid date range", "from", start, "to", end)
+		return nil, errors.ErrInvalidDateRange
 	}
 
 	stats, err := orderRepo.GetStats(ctx, start, end)
@@ -234,6 +1113,9 @@ type ExportFilter struct {
 }
 
 func (s *Service) ExportOrders(ctx context.Context, f ExportFilter) ([]*repo.Order, error) {
+	ctx, span := tracer.Start(ctx, "order.Service.ExportOrders")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
 
 	if f.Limit == 0 {
 		f.Limit = 20
@@ -251,7 +1133,7 @@ func (s *Service) ExportOrders(ctx context.Context, f ExportFilter) ([]*repo.Ord
 		Offset:    f.Offset,
 	})
 	if err != nil {
-		s.log.Errorw("export orders failed", "filter", f, "error", err)
+		log.Errorw("export orders failed", "filter", f, "error", err)
 		return nil, errors.ErrInternal
 	}
 	return orders, nil
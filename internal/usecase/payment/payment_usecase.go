@@ -0,0 +1,266 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	orderRepo "github.com/Cora23tt/order_service/internal/repository/order"
+	paymentRepo "github.com/Cora23tt/order_service/internal/repository/payment"
+	"github.com/Cora23tt/order_service/internal/usecase/order"
+	"github.com/Cora23tt/order_service/pkg/enums"
+	pkgerrors "github.com/Cora23tt/order_service/pkg/errors"
+	"github.com/Cora23tt/order_service/pkg/logger"
+	"github.com/Cora23tt/order_service/pkg/payment"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
+)
+
+var tracer = otel.Tracer("github.com/Cora23tt/order_service/internal/usecase/payment")
+
+// errAlreadyProcessed rolls back the order change when a concurrent callback
+// for the same payment got there first.
+var errAlreadyProcessed = errors.New("payment already processed")
+
+type Service struct {
+	repo     *paymentRepo.Repo
+	orders   *order.Service
+	provider payment.Provider
+	log      *zap.SugaredLogger
+}
+
+func NewService(repo *paymentRepo.Repo, orders *order.Service, provider payment.Provider, log *zap.SugaredLogger) *Service {
+	return &Service{repo: repo, orders: orders, provider: provider, log: log}
+}
+
+// CreatePayment issues an invoice for the order with the payment provider.
+// An order has at most one pending invoice; asking again returns it.
+func (s *Service) CreatePayment(ctx context.Context, orderID, userID int64, role string) (*paymentRepo.Payment, error) {
+	ctx, span := tracer.Start(ctx, "payment.Service.CreatePayment")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
+	o, err := s.orders.GetOrderByID(ctx, orderID, userID, role)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := s.repo.GetPendingByOrder(ctx, orderID)
+	switch err {
+	case nil:
+		return p, nil
+	case pkgerrors.ErrNotFound:
+	default:
+		return nil, err
+	}
+
+	if o.Status != enums.StatusPendingPayment {
+		log.Warnw("order is not payable", "order_id", orderID, "status", o.Status)
+		return nil, pkgerrors.ErrOrderNotPayable
+	}
+
+	invoice, err := s.provider.CreateInvoice(ctx, payment.Invoice{
+		OrderID:     o.ID,
+		Amount:      o.TotalAmount,
+		Description: fmt.Sprintf("Order #%d", o.ID),
+	})
+	if err != nil {
+		log.Errorw("create invoice failed", "order_id", orderID, "provider", s.provider.Name(), "error", err)
+		return nil, pkgerrors.ErrPaymentProvider
+	}
+
+	p = &paymentRepo.Payment{
+		OrderID:     o.ID,
+		Provider:    s.provider.Name(),
+		ProviderRef: invoice.ProviderRef,
+		Amount:      o.TotalAmount,
+		Status:      enums.PaymentPending,
+		PaymentURL:  &invoice.PaymentURL,
+	}
+	err = s.repo.Create(ctx, p)
+	switch err {
+	case nil:
+	case pkgerrors.ErrAlreadyExists:
+		// A concurrent request stored its invoice first; ours is never paid.
+		log.Warnw("order already has a pending payment", "order_id", orderID, "provider_ref", invoice.ProviderRef)
+		return s.repo.GetPendingByOrder(ctx, orderID)
+	case pkgerrors.ErrInvalidInput:
+		return nil, pkgerrors.ErrOrderNotFound
+	default:
+		return nil, err
+	}
+
+	log.Infow("payment created", "order_id", orderID, "payment_id", p.ID, "provider_ref", p.ProviderRef, "amount", p.Amount)
+	return p, nil
+}
+
+// ListPayments returns the payments of the order. Users only see the
+// payments of their own orders.
+func (s *Service) ListPayments(ctx context.Context, orderID, userID int64, role string) ([]*paymentRepo.Payment, error) {
+	ctx, span := tracer.Start(ctx, "payment.Service.ListPayments")
+	defer span.End()
+
+	if _, err := s.orders.GetOrderByID(ctx, orderID, userID, role); err != nil {
+		return nil, err
+	}
+	return s.repo.ListByOrder(ctx, orderID)
+}
+
+// Refund refunds the order or some of its items through the provider of its
+// payment. Refunds of an order never return more than its payment.
+func (s *Service) Refund(ctx context.Context, orderID int64, input order.RefundInput, actor order.Actor) (*orderRepo.Refund, error) {
+	ctx, span := tracer.Start(ctx, "payment.Service.Refund")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
+	return s.orders.RefundOrder(ctx, orderID, input, actor, func(ctx context.Context, tx pgx.Tx, o *orderRepo.Order, amount int64) (string, error) {
+		p, err := paymentRepo.NewWithTx(tx, s.log).GetPaidByOrder(ctx, o.ID)
+		switch err {
+		case nil:
+		case pkgerrors.ErrNotFound:
+			log.Warnw("refund of an order without a confirmed payment", "order_id", o.ID)
+			return "", pkgerrors.ErrOrderNotRefundable
+		default:
+			return "", err
+		}
+		if o.RefundedAmount+amount > p.Amount {
+			log.Warnw("refund exceeds the paid amount", "order_id", o.ID, "amount", amount, "refunded", o.RefundedAmount, "paid", p.Amount)
+			return "", pkgerrors.ErrRefundExceedsPaid
+		}
+		if p.Provider != s.provider.Name() {
+			log.Errorw("payment provider of the order is not configured", "order_id", o.ID, "provider", p.Provider)
+			return "", pkgerrors.ErrPaymentProvider
+		}
+
+		res, err := s.provider.Refund(ctx, payment.Refund{ProviderRef: p.ProviderRef, Amount: amount, Reason: input.Reason})
+		switch {
+		case err == nil:
+		case errors.Is(err, payment.ErrDeclined):
+			log.Warnw("refund declined", "order_id", o.ID, "payment_id", p.ID, "amount", amount)
+			return "", pkgerrors.ErrRefundDeclined
+		default:
+			log.Errorw("refund failed", "order_id", o.ID, "payment_id", p.ID, "amount", amount, "error", err)
+			return "", pkgerrors.ErrPaymentProvider
+		}
+
+		log.Infow("payment refunded", "order_id", o.ID, "payment_id", p.ID, "amount", amount, "provider_ref", res.ProviderRef)
+		return res.ProviderRef, nil
+	})
+}
+
+// HandleCallback applies a payment status update sent by the provider. A
+// confirmed payment moves its order to paid in the same transaction; if the
+// order cannot be paid any more, the payment is refunded instead.
+// Callbacks are delivered at least once, so repeated ones are acknowledged
+// without changing anything.
+func (s *Service) HandleCallback(ctx context.Context, provider string, header http.Header, body []byte) error {
+	ctx, span := tracer.Start(ctx, "payment.Service.HandleCallback")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
+	if provider != s.provider.Name() {
+		return pkgerrors.ErrPaymentProviderNotFound
+	}
+
+	cb, err := s.provider.ParseCallback(ctx, header, body)
+	switch {
+	case err == nil:
+	case errors.Is(err, payment.ErrInvalidSignature):
+		log.Warnw("payment callback with invalid signature", "provider", provider)
+		return pkgerrors.ErrInvalidSignature
+	default:
+		log.Warnw("payment callback cannot be parsed", "provider", provider, "error", err)
+		return pkgerrors.ErrInvalidCallback
+	}
+
+	p, err := s.repo.GetByProviderRef(ctx, provider, cb.ProviderRef)
+	switch err {
+	case nil:
+	case pkgerrors.ErrNotFound:
+		log.Warnw("payment callback for unknown invoice", "provider", provider, "provider_ref", cb.ProviderRef)
+		return pkgerrors.ErrPaymentNotFound
+	default:
+		return err
+	}
+
+	log = log.With("payment_id", p.ID, "order_id", p.OrderID, "status", cb.Status)
+	if p.Status != enums.PaymentPending {
+		if p.Status != cb.Status {
+			log.Warnw("payment callback ignored, payment already completed", "payment_status", p.Status)
+		} else {
+			log.Infow("duplicate payment callback")
+		}
+		return nil
+	}
+
+	switch cb.Status {
+	case enums.PaymentPending:
+		return nil
+	case enums.PaymentFailed:
+		if _, err := s.repo.UpdateStatus(ctx, p.ID, enums.PaymentPending, enums.PaymentFailed); err != nil {
+			return err
+		}
+		log.Infow("payment failed")
+		return nil
+	}
+
+	if cb.Amount != p.Amount {
+		log.Errorw("paid amount does not match the invoice", "amount", cb.Amount, "invoice_amount", p.Amount)
+		return pkgerrors.ErrPaymentAmountMismatch
+	}
+
+	err = s.orders.MarkPaid(ctx, p.OrderID, "payment "+p.ProviderRef, func(ctx context.Context, tx pgx.Tx) error {
+		updated, err := paymentRepo.NewWithTx(tx, s.log).UpdateStatus(ctx, p.ID, enums.PaymentPending, enums.PaymentPaid)
+		if err != nil {
+			return err
+		}
+		if !updated {
+			return errAlreadyProcessed
+		}
+		return nil
+	})
+	switch err {
+	case nil:
+		log.Infow("payment confirmed")
+		return nil
+	case errAlreadyProcessed:
+		log.Infow("duplicate payment callback")
+		return nil
+	case pkgerrors.ErrInvalidTransition, pkgerrors.ErrOrderCompleted:
+		// The money was taken although the order cannot be paid any more,
+		// e.g. because it was cancelled meanwhile or a concurrent callback
+		// paid it. The payment is recorded and given back.
+		updated, err := s.repo.UpdateStatus(ctx, p.ID, enums.PaymentPending, enums.PaymentPaid)
+		if err != nil {
+			return err
+		}
+		if !updated {
+			log.Infow("duplicate payment callback")
+			return nil
+		}
+		s.refundUnpayable(ctx, p)
+		return nil
+	default:
+		return err
+	}
+}
+
+// refundUnpayable returns in full a payment confirmed for an order that cannot
+// be paid any more. A refund that fails leaves the payment paid and is logged
+// for a manual refund.
+func (s *Service) refundUnpayable(ctx context.Context, p *paymentRepo.Payment) {
+	log := logger.FromContext(ctx, s.log).With("payment_id", p.ID, "order_id", p.OrderID)
+
+	res, err := s.provider.Refund(ctx, payment.Refund{ProviderRef: p.ProviderRef, Amount: p.Amount, Reason: "order cannot be paid"})
+	if err != nil {
+		log.Errorw("refund of a payment for an order that cannot be paid failed, manual refund required", "amount", p.Amount, "error", err)
+		return
+	}
+	if _, err := s.repo.UpdateStatus(ctx, p.ID, enums.PaymentPaid, enums.PaymentRefunded); err != nil {
+		log.Errorw("payment refunded but not marked as refunded", "refund_ref", res.ProviderRef, "error", err)
+		return
+	}
+	log.Warnw("payment for an order that cannot be paid refunded", "amount", p.Amount, "refund_ref", res.ProviderRef)
+}
@@ -0,0 +1,266 @@
+package pickup
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	pickupRepo "github.com/Cora23tt/order_service/internal/repository/pickup"
+	"github.com/Cora23tt/order_service/internal/repository/uow"
+	"github.com/Cora23tt/order_service/pkg/config"
+	pkgerrors "github.com/Cora23tt/order_service/pkg/errors"
+	"github.com/Cora23tt/order_service/pkg/logger"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
+)
+
+var tracer = otel.Tracer("github.com/Cora23tt/order_service/internal/usecase/pickup")
+
+type Service struct {
+	repo     *pickupRepo.Repo
+	uow      uow.UnitOfWork
+	location *time.Location
+	log      *zap.SugaredLogger
+}
+
+func NewService(repo *pickupRepo.Repo, uow uow.UnitOfWork, cfg *config.Config, log *zap.SugaredLogger) (*Service, error) {
+	location, err := time.LoadLocation(cfg.Pickup.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("load pickup timezone: %w", err)
+	}
+	return &Service{repo: repo, uow: uow, location: location, log: log}, nil
+}
+
+// PointInput is the full state of a pickup point set by an admin.
+type PointInput struct {
+	Name         string
+	Address      string
+	Latitude     float64
+	Longitude    float64
+	OpeningHours []pickupRepo.OpeningHours
+	Capacity     int64
+	Active       bool
+}
+
+// List returns the pickup points matching the filter, nearest first if the
+// filter has a location.
+func (s *Service) List(ctx context.Context, filter pickupRepo.ListFilter) ([]*pickupRepo.Point, error) {
+	ctx, span := tracer.Start(ctx, "pickup.Service.List")
+	defer span.End()
+
+	points, err := s.repo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, p := range points {
+		p.OpenNow = s.OpenAt(p, now)
+	}
+	return points, nil
+}
+
+func (s *Service) Get(ctx context.Context, id int64) (*pickupRepo.Point, error) {
+	ctx, span := tracer.Start(ctx, "pickup.Service.Get")
+	defer span.End()
+
+	p, err := s.repo.GetByID(ctx, id)
+	switch err {
+	case nil:
+		p.OpenNow = s.OpenAt(p, time.Now())
+		return p, nil
+	case pkgerrors.ErrNotFound:
+		return nil, pkgerrors.ErrPickupPointNotFound
+	default:
+		return nil, err
+	}
+}
+
+func (s *Service) Create(ctx context.Context, input PointInput) (*pickupRepo.Point, error) {
+	ctx, span := tracer.Start(ctx, "pickup.Service.Create")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
+	p, err := newPoint(input)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(ctx, p); err != nil {
+		return nil, err
+	}
+
+	log.Infow("pickup point created", "pickup_point_id", p.ID)
+	return s.Get(ctx, p.ID)
+}
+
+// Update replaces the pickup point. Orders already placed there keep the name
+// and address they were placed with.
+func (s *Service) Update(ctx context.Context, id int64, input PointInput) (*pickupRepo.Point, error) {
+	ctx, span := tracer.Start(ctx, "pickup.Service.Update")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
+	p, err := newPoint(input)
+	if err != nil {
+		return nil, err
+	}
+	p.ID = id
+
+	switch err := s.repo.Update(ctx, p); err {
+	case nil:
+	case pkgerrors.ErrNotFound:
+		return nil, pkgerrors.ErrPickupPointNotFound
+	default:
+		return nil, err
+	}
+
+	log.Infow("pickup point updated", "pickup_point_id", id)
+	return s.Get(ctx, id)
+}
+
+// Delete removes the pickup point together with its delivery slots. A point
+// with booked slots cannot be deleted, the same way a booked slot cannot.
+func (s *Service) Delete(ctx context.Context, id int64) error {
+	ctx, span := tracer.Start(ctx, "pickup.Service.Delete")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
+	tx, err := s.uow.Begin(ctx)
+	if err != nil {
+		log.Errorw("begin transaction failed", "error", err)
+		return pkgerrors.ErrInternal
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	repo := pickupRepo.NewWithTx(tx.GetTx(), s.log)
+	if err := repo.LockDeliverySlots(ctx, id); err != nil {
+		return err
+	}
+	booked, err := repo.CountBookedSlotOrders(ctx, id)
+	if err != nil {
+		return err
+	}
+	if booked > 0 {
+		log.Warnw("pickup point with booked delivery slots deleted", "pickup_point_id", id, "booked", booked)
+		return pkgerrors.ErrDeliverySlotBooked
+	}
+
+	switch err := repo.Delete(ctx, id); err {
+	case nil:
+	case pkgerrors.ErrNotFound:
+		return pkgerrors.ErrPickupPointNotFound
+	default:
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		log.Errorw("commit transaction failed", "error", err)
+		return pkgerrors.ErrInternal
+	}
+	committed = true
+
+	log.Infow("pickup point deleted", "pickup_point_id", id)
+	return nil
+}
+
+// Select checks that an order being created in tx can be placed at the point
+// and returns the point. The point must have room for one more order and, if
+// at is set, be open then. The point stays locked until tx ends.
+func (s *Service) Select(ctx context.Context, tx pgx.Tx, id int64, at *time.Time) (*pickupRepo.Point, error) {
+	ctx, span := tracer.Start(ctx, "pickup.Service.Select")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
+	repo := pickupRepo.NewWithTx(tx, s.log)
+	p, err := repo.GetByIDForUpdate(ctx, id)
+	switch err {
+	case nil:
+	case pkgerrors.ErrNotFound:
+		log.Warnw("unknown pickup point", "pickup_point_id", id)
+		return nil, pkgerrors.ErrPickupPointNotFound
+	default:
+		return nil, err
+	}
+
+	if !p.Active {
+		log.Warnw("order to inactive pickup point", "pickup_point_id", id)
+		return nil, pkgerrors.ErrPickupPointInactive
+	}
+	if at != nil && !s.OpenAt(p, *at) {
+		log.Warnw("order to closed pickup point", "pickup_point_id", id, "at", *at)
+		return nil, pkgerrors.ErrPickupPointClosed
+	}
+	held, err := repo.CountHeldOrders(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if held >= p.Capacity {
+		log.Warnw("order to full pickup point", "pickup_point_id", id, "capacity", p.Capacity)
+		return nil, pkgerrors.ErrPickupPointFull
+	}
+	return p, nil
+}
+
+// OpenAt reports whether the point is open at t according to its opening
+// hours in the pickup point timezone.
+func (s *Service) OpenAt(p *pickupRepo.Point, t time.Time) bool {
+	t = t.In(s.location)
+	weekday := int(t.Weekday())
+	if weekday == 0 {
+		weekday = 7
+	}
+	now := t.Format("15:04")
+	return slices.ContainsFunc(p.OpeningHours, func(h pickupRepo.OpeningHours) bool {
+		return h.Weekday == weekday && h.Opens <= now && now < h.Closes
+	})
+}
+
+// endOfDay closes an interval that lasts until midnight.
+const endOfDay = "24:00"
+
+func newPoint(input PointInput) (*pickupRepo.Point, error) {
+	hours := make([]pickupRepo.OpeningHours, 0, len(input.OpeningHours))
+	for i, h := range input.OpeningHours {
+		// Times are stored as zero-padded HH:MM, so that they can be
+		// compared as strings; "24:00" sorts after every time of the day.
+		opens, err := time.Parse("15:04", h.Opens)
+		if err != nil {
+			return nil, pkgerrors.InvalidField(fmt.Sprintf("opening_hours[%d].opens", i), "date", "15:04")
+		}
+		closes := endOfDay
+		if h.Closes != endOfDay {
+			t, err := time.Parse("15:04", h.Closes)
+			if err != nil {
+				return nil, pkgerrors.InvalidField(fmt.Sprintf("opening_hours[%d].closes", i), "date", "15:04")
+			}
+			closes = t.Format("15:04")
+		}
+		if closes <= opens.Format("15:04") {
+			return nil, pkgerrors.InvalidField(fmt.Sprintf("opening_hours[%d].closes", i), "gtfield", "opens")
+		}
+		hours = append(hours, pickupRepo.OpeningHours{
+			Weekday: h.Weekday,
+			Opens:   opens.Format("15:04"),
+			Closes:  closes,
+		})
+	}
+	slices.SortFunc(hours, func(a, b pickupRepo.OpeningHours) int {
+		return cmp.Or(cmp.Compare(a.Weekday, b.Weekday), cmp.Compare(a.Opens, b.Opens))
+	})
+
+	return &pickupRepo.Point{
+		Name:         input.Name,
+		Address:      input.Address,
+		Latitude:     input.Latitude,
+		Longitude:    input.Longitude,
+		OpeningHours: hours,
+		Capacity:     input.Capacity,
+		Active:       input.Active,
+	}, nil
+}
@@ -6,87 +6,122 @@ import (
 
 	productRepo "github.com/Cora23tt/order_service/internal/repository/product"
 	pkgerrors "github.com/Cora23tt/order_service/pkg/errors"
+	"github.com/Cora23tt/order_service/pkg/logger"
+	"github.com/Cora23tt/order_service/pkg/tax"
+	"go.opentelemetry.io/otel"
 	"go.uber.org/zap"
 )
 
+var tracer = otel.Tracer("github.com/Cora23tt/order_service/internal/usecase/product")
+
 type Service struct {
 	repo *productRepo.Repo
 	log  *zap.SugaredLogger
+	tax  *tax.Calculator
 }
 
-func NewService(repo *productRepo.Repo, log *zap.SugaredLogger) *Service {
-	return &Service{repo: repo, log: log}
+func NewService(repo *productRepo.Repo, log *zap.SugaredLogger, tax *tax.Calculator) *Service {
+	return &Service{repo: repo, log: log, tax: tax}
 }
 
-func (s *Service) AddProduct(ctx context.Context, price, quantity int64, name, description, imageURL string) error {
+// AddProduct stores a new product. A nil vatRate means the configured default
+// rate.
+func (s *Service) AddProduct(ctx context.Context, price, quantity int64, vatRate *int64, name, description, imageURL string) error {
+	ctx, span := tracer.Start(ctx, "product.Service.AddProduct")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
 	product := productRepo.Product{
 		Name:          name,
 		Price:         price,
 		Description:   description,
 		ImageUrl:      imageURL,
 		StockQuantity: quantity,
+		VATRate:       s.tax.DefaultRate,
+	}
+	if vatRate != nil {
+		product.VATRate = *vatRate
 	}
 	err := s.repo.CreateProduct(ctx, &product)
 	switch err {
 	case nil:
-		s.log.Infow("product added", "name", name, "price", price)
+		log.Infow("product added", "name", name, "price", price)
 		return nil
-	case pkgerrors.ErrInvalidInput, pkgerrors.ErrAlreadyExists:
-		s.log.Warnw("invalid input for product creation", "error", err)
+	case pkgerrors.ErrInvalidInput:
+		log.Warnw("invalid input for product creation", "error", err)
 		return err
+	case pkgerrors.ErrAlreadyExists:
+		log.Warnw("product already exists", "name", name)
+		return pkgerrors.ErrProductExists
 	default:
-		s.log.Errorw("failed to create product", "error", err)
+		log.Errorw("failed to create product", "error", err)
 		return pkgerrors.ErrInternal
 	}
 }
 
 func (s *Service) DeleteProduct(ctx context.Context, id int64) error {
+	ctx, span := tracer.Start(ctx, "product.Service.DeleteProduct")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
 	err := s.repo.DeleteProduct(ctx, id)
 	switch err {
 	case nil:
-		s.log.Infow("product deleted", "id", id)
+		log.Infow("product deleted", "id", id)
 		return nil
 	case pkgerrors.ErrNotFound:
-		s.log.Warnw("product not found for delete", "id", id)
-		return err
+		log.Warnw("product not found for delete", "id", id)
+		return pkgerrors.ErrProductNotFound
 	default:
-		s.log.Errorw("failed to delete product", "id", id, "error", err)
+		log.Errorw("failed to delete product", "id", id, "error", err)
 		return pkgerrors.ErrInternal
 	}
 }
 
 func (s *Service) GetProductByID(ctx context.Context, id int64) (*productRepo.Product, error) {
+	ctx, span := tracer.Start(ctx, "product.Service.GetProductByID")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
 	product, err := s.repo.GetProductByID(ctx, id)
 	switch err {
 	case nil:
 		return product, nil
 	case pkgerrors.ErrNotFound:
-		s.log.Warnw("product not found", "id", id)
-		return nil, err
+		log.Warnw("product not found", "id", id)
+		return nil, pkgerrors.ErrProductNotFound
 	default:
-		s.log.Errorw("failed to get product", "id", id, "error", err)
+		log.Errorw("failed to get product", "id", id, "error", err)
 		return nil, pkgerrors.ErrInternal
 	}
 }
 
 func (s *Service) GetProducts(ctx context.Context) ([]*productRepo.Product, error) {
+	ctx, span := tracer.Start(ctx, "product.Service.GetProducts")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
 	products, err := s.repo.GetProducts(ctx)
 	if err != nil {
-		s.log.Errorw("failed to get products", "error", err)
+		log.Errorw("failed to get products", "error", err)
 		return nil, pkgerrors.ErrInternal
 	}
 	return products, nil
 }
 
-func (s *Service) UpdateProduct(ctx context.Context, id, quantity, price int64, name, description, imageUrl string) error {
+func (s *Service) UpdateProduct(ctx context.Context, id, quantity, price int64, vatRate *int64, name, description, imageUrl string) error {
+	ctx, span := tracer.Start(ctx, "product.Service.UpdateProduct")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
 	product, err := s.repo.GetProductByID(ctx, id)
 	if err != nil {
 		switch err {
 		case pkgerrors.ErrNotFound:
-			s.log.Warnw("product not found for update", "id", id)
-			return err
+			log.Warnw("product not found for update", "id", id)
+			return pkgerrors.ErrProductNotFound
 		default:
-			s.log.Errorw("failed to get product for update", "id", id, "error", err)
+			log.Errorw("failed to get product for update", "id", id, "error", err)
 			return pkgerrors.ErrInternal
 		}
 	}
@@ -106,18 +141,27 @@ func (s *Service) UpdateProduct(ctx context.Context, id, quantity, price int64,
 	if quantity != 0 {
 		product.StockQuantity = quantity
 	}
+	if vatRate != nil {
+		product.VATRate = *vatRate
+	}
 	product.UpdatedAt = time.Now()
 
 	err = s.repo.UpdateProduct(ctx, product)
 	switch err {
 	case nil:
-		s.log.Infow("product updated", "id", id)
+		log.Infow("product updated", "id", id)
 		return nil
-	case pkgerrors.ErrNotFound, pkgerrors.ErrInvalidInput, pkgerrors.ErrAlreadyExists:
-		s.log.Warnw("product update issue", "id", id, "error", err)
+	case pkgerrors.ErrNotFound:
+		log.Warnw("product not found for update", "id", id)
+		return pkgerrors.ErrProductNotFound
+	case pkgerrors.ErrAlreadyExists:
+		log.Warnw("product update conflict", "id", id)
+		return pkgerrors.ErrProductExists
+	case pkgerrors.ErrInvalidInput:
+		log.Warnw("product update issue", "id", id, "error", err)
 		return err
 	default:
-		s.log.Errorw("failed to update product", "id", id, "error", err)
+		log.Errorw("failed to update product", "id", id, "error", err)
 		return pkgerrors.ErrInternal
 	}
 }
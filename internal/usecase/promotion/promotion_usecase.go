@@ -0,0 +1,317 @@
+package promotion
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	promotionRepo "github.com/Cora23tt/order_service/internal/repository/promotion"
+	"github.com/Cora23tt/order_service/internal/repository/uow"
+	"github.com/Cora23tt/order_service/pkg/enums"
+	pkgerrors "github.com/Cora23tt/order_service/pkg/errors"
+	"github.com/Cora23tt/order_service/pkg/logger"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
+)
+
+var tracer = otel.Tracer("github.com/Cora23tt/order_service/internal/usecase/promotion")
+
+type Service struct {
+	repo *promotionRepo.Repo
+	uow  uow.UnitOfWork
+	log  *zap.SugaredLogger
+}
+
+func NewService(repo *promotionRepo.Repo, uow uow.UnitOfWork, log *zap.SugaredLogger) *Service {
+	return &Service{repo: repo, uow: uow, log: log}
+}
+
+// PromotionInput is the full state of a promotion set by an admin.
+type PromotionInput struct {
+	Code           string
+	Description    string
+	DiscountType   enums.DiscountType
+	DiscountValue  int64
+	MinOrderAmount int64
+	StartsAt       *time.Time
+	EndsAt         *time.Time
+	UsageLimit     *int64
+	PerUserLimit   *int64
+	Active         bool
+	ProductIDs     []int64
+}
+
+// Line is an order line the discount is calculated from.
+type Line struct {
+	ProductID int64
+	Amount    int64
+}
+
+// Discount is a promo code applied to an order. LineAmounts splits Amount
+// between the order lines, in the order the lines were given.
+type Discount struct {
+	PromotionID int64
+	Code        string
+	Amount      int64
+	LineAmounts []int64
+}
+
+// NormalizeCode returns the form promo codes are stored and looked up in, so
+// that codes are case-insensitive.
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func (s *Service) List(ctx context.Context) ([]*promotionRepo.Promotion, error) {
+	ctx, span := tracer.Start(ctx, "promotion.Service.List")
+	defer span.End()
+
+	return s.repo.List(ctx)
+}
+
+func (s *Service) Get(ctx context.Context, id int64) (*promotionRepo.Promotion, error) {
+	ctx, span := tracer.Start(ctx, "promotion.Service.Get")
+	defer span.End()
+
+	p, err := s.repo.GetByID(ctx, id)
+	switch err {
+	case nil:
+		return p, nil
+	case pkgerrors.ErrNotFound:
+		return nil, pkgerrors.ErrPromotionNotFound
+	default:
+		return nil, err
+	}
+}
+
+func (s *Service) Create(ctx context.Context, input PromotionInput) (*promotionRepo.Promotion, error) {
+	ctx, span := tracer.Start(ctx, "promotion.Service.Create")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
+	p, err := newPromotion(input)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.inTx(ctx, func(repo *promotionRepo.Repo) error {
+		return repo.Create(ctx, p)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infow("promotion created", "promotion_id", p.ID, "code", p.Code)
+	return s.Get(ctx, p.ID)
+}
+
+func (s *Service) Update(ctx context.Context, id int64, input PromotionInput) (*promotionRepo.Promotion, error) {
+	ctx, span := tracer.Start(ctx, "promotion.Service.Update")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
+	p, err := newPromotion(input)
+	if err != nil {
+		return nil, err
+	}
+	p.ID = id
+
+	err = s.inTx(ctx, func(repo *promotionRepo.Repo) error {
+		return repo.Update(ctx, p)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infow("promotion updated", "promotion_id", id)
+	return s.Get(ctx, id)
+}
+
+// Delete removes the promotion. Orders it was applied to keep their discount.
+func (s *Service) Delete(ctx context.Context, id int64) error {
+	ctx, span := tracer.Start(ctx, "promotion.Service.Delete")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
+	err := s.repo.Delete(ctx, id)
+	switch err {
+	case nil:
+		log.Infow("promotion deleted", "promotion_id", id)
+		return nil
+	case pkgerrors.ErrNotFound:
+		return pkgerrors.ErrPromotionNotFound
+	default:
+		return err
+	}
+}
+
+// Apply checks the promo code against an order being created in tx and
+// returns the discount it gives. The promotion stays locked until tx ends and
+// the order's discount is what counts as a redemption, so usage limits hold
+// under concurrent orders.
+func (s *Service) Apply(ctx context.Context, tx pgx.Tx, userID int64, code string, lines []Line) (*Discount, error) {
+	ctx, span := tracer.Start(ctx, "promotion.Service.Apply")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
+	repo := promotionRepo.NewWithTx(tx, s.log)
+
+	p, err := repo.GetByCodeForUpdate(ctx, NormalizeCode(code))
+	switch err {
+	case nil:
+	case pkgerrors.ErrNotFound:
+		log.Warnw("unknown promo code", "code", code)
+		return nil, pkgerrors.ErrPromoCodeInvalid
+	default:
+		return nil, err
+	}
+
+	now := time.Now()
+	if !p.Active || (p.StartsAt != nil && now.Before(*p.StartsAt)) || (p.EndsAt != nil && !now.Before(*p.EndsAt)) {
+		log.Warnw("promo code not valid now", "code", p.Code)
+		return nil, pkgerrors.ErrPromoCodeInvalid
+	}
+
+	var subtotal, eligible int64
+	eligibleAmounts := make([]int64, len(lines))
+	for i, l := range lines {
+		subtotal += l.Amount
+		if len(p.ProductIDs) == 0 || slices.Contains(p.ProductIDs, l.ProductID) {
+			eligible += l.Amount
+			eligibleAmounts[i] = l.Amount
+		}
+	}
+	if subtotal < p.MinOrderAmount {
+		return nil, pkgerrors.ErrPromoMinAmount
+	}
+	if eligible == 0 {
+		return nil, pkgerrors.ErrPromoNotApplicable
+	}
+
+	total, byUser, err := repo.CountRedemptions(ctx, p.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if (p.UsageLimit != nil && total >= *p.UsageLimit) || (p.PerUserLimit != nil && byUser >= *p.PerUserLimit) {
+		log.Warnw("promo code usage limit reached", "code", p.Code, "used", total, "used_by_user", byUser)
+		return nil, pkgerrors.ErrPromoCodeExhausted
+	}
+
+	amount := discountAmount(p, eligible)
+	if amount == 0 {
+		return nil, pkgerrors.ErrPromoNotApplicable
+	}
+	return &Discount{
+		PromotionID: p.ID,
+		Code:        p.Code,
+		Amount:      amount,
+		LineAmounts: allocate(amount, eligibleAmounts, eligible),
+	}, nil
+}
+
+// discountAmount returns the discount on the eligible amount. Percentages are
+// rounded down.
+func discountAmount(p *promotionRepo.Promotion, eligible int64) int64 {
+	if p.DiscountType == enums.DiscountPercent {
+		return eligible * p.DiscountValue / 100
+	}
+	return min(p.DiscountValue, eligible)
+}
+
+// allocate splits amount between lines in proportion to their amounts, which
+// add up to total. Shares are rounded down and the units left over go to the
+// lines with the largest remainders, so the shares add up to amount exactly.
+func allocate(amount int64, lines []int64, total int64) []int64 {
+	shares := make([]int64, len(lines))
+	remainders := make([]int64, len(lines))
+	left := amount
+	for i, l := range lines {
+		shares[i] = amount * l / total
+		remainders[i] = amount * l % total
+		left -= shares[i]
+	}
+
+	order := make([]int, len(lines))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(remainders[b], remainders[a])
+	})
+	for _, i := range order[:left] {
+		shares[i]++
+	}
+	return shares
+}
+
+func newPromotion(input PromotionInput) (*promotionRepo.Promotion, error) {
+	if input.DiscountType == enums.DiscountPercent && input.DiscountValue > 100 {
+		return nil, pkgerrors.InvalidField("discount_value", "lte", "100")
+	}
+	if input.StartsAt != nil && input.EndsAt != nil && !input.EndsAt.After(*input.StartsAt) {
+		return nil, pkgerrors.InvalidField("ends_at", "gtfield", "starts_at")
+	}
+
+	productIDs := slices.Clone(input.ProductIDs)
+	slices.Sort(productIDs)
+	productIDs = slices.Compact(productIDs)
+	if productIDs == nil {
+		productIDs = []int64{}
+	}
+
+	return &promotionRepo.Promotion{
+		Code:           NormalizeCode(input.Code),
+		Description:    input.Description,
+		DiscountType:   input.DiscountType,
+		DiscountValue:  input.DiscountValue,
+		MinOrderAmount: input.MinOrderAmount,
+		StartsAt:       input.StartsAt,
+		EndsAt:         input.EndsAt,
+		UsageLimit:     input.UsageLimit,
+		PerUserLimit:   input.PerUserLimit,
+		Active:         input.Active,
+		ProductIDs:     productIDs,
+	}, nil
+}
+
+// inTx runs fn with a repo bound to a new transaction and maps the repo errors
+// of promotion writes.
+func (s *Service) inTx(ctx context.Context, fn func(repo *promotionRepo.Repo) error) error {
+	log := logger.FromContext(ctx, s.log)
+
+	tx, err := s.uow.Begin(ctx)
+	if err != nil {
+		log.Errorw("begin transaction failed", "error", err)
+		return pkgerrors.ErrInternal
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	err = fn(promotionRepo.NewWithTx(tx.GetTx(), s.log))
+	switch err {
+	case nil:
+	case pkgerrors.ErrNotFound:
+		return pkgerrors.ErrPromotionNotFound
+	case pkgerrors.ErrAlreadyExists:
+		return pkgerrors.ErrPromotionExists
+	case pkgerrors.ErrInvalidInput:
+		// The only foreign key written is the product of a restriction.
+		return pkgerrors.ErrProductNotFound
+	default:
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Errorw("commit transaction failed", "error", err)
+		return pkgerrors.ErrInternal
+	}
+	committed = true
+	return nil
+}
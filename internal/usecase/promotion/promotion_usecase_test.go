@@ -0,0 +1,74 @@
+package promotion
+
+import (
+	"slices"
+	"testing"
+
+	promotionRepo "github.com/Cora23tt/order_service/internal/repository/promotion"
+	"github.com/Cora23tt/order_service/pkg/enums"
+)
+
+func TestDiscountAmount(t *testing.T) {
+	tests := []struct {
+		name     string
+		typ      enums.DiscountType
+		value    int64
+		eligible int64
+		want     int64
+	}{
+		{"percent", enums.DiscountPercent, 10, 5000, 500},
+		{"percent rounds down", enums.DiscountPercent, 15, 999, 149},
+		{"percent full", enums.DiscountPercent, 100, 5000, 5000},
+		{"fixed", enums.DiscountFixed, 700, 5000, 700},
+		{"fixed capped at eligible", enums.DiscountFixed, 7000, 5000, 5000},
+		{"fixed nothing eligible", enums.DiscountFixed, 700, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &promotionRepo.Promotion{DiscountType: tt.typ, DiscountValue: tt.value}
+			if got := discountAmount(p, tt.eligible); got != tt.want {
+				t.Errorf("discountAmount(%s %d, %d) = %d, want %d", tt.typ, tt.value, tt.eligible, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount int64
+		lines  []int64
+		want   []int64
+	}{
+		{"single line", 500, []int64{5000}, []int64{500}},
+		{"exact shares", 300, []int64{1000, 2000}, []int64{100, 200}},
+		{"remainder to first of equal remainders", 100, []int64{100, 100, 100}, []int64{34, 33, 33}},
+		{"remainder to largest remainders", 10, []int64{10, 20, 40}, []int64{1, 3, 6}},
+		{"whole total", 7000, []int64{1000, 2500, 3500}, []int64{1000, 2500, 3500}},
+		{"zero amount", 0, []int64{1000, 2000}, []int64{0, 0}},
+		{"tiny amount", 1, []int64{300, 300, 300}, []int64{1, 0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var total int64
+			for _, l := range tt.lines {
+				total += l
+			}
+			got := allocate(tt.amount, tt.lines, total)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("allocate(%d, %v) = %v, want %v", tt.amount, tt.lines, got, tt.want)
+			}
+
+			var sum int64
+			for i, share := range got {
+				sum += share
+				if share > tt.lines[i] {
+					t.Errorf("line %d: share %d exceeds line total %d", i, share, tt.lines[i])
+				}
+			}
+			if sum != tt.amount {
+				t.Errorf("shares add up to %d, want %d", sum, tt.amount)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package receipt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	orderRepo "github.com/Cora23tt/order_service/internal/repository/order"
+	"github.com/Cora23tt/order_service/internal/repository/product"
+	"github.com/Cora23tt/order_service/pkg/enums"
+	pkgerrors "github.com/Cora23tt/order_service/pkg/errors"
+	"github.com/Cora23tt/order_service/pkg/logger"
+	"github.com/Cora23tt/order_service/pkg/receipt"
+	"github.com/Cora23tt/order_service/pkg/storage"
+	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
+)
+
+var tracer = otel.Tracer("github.com/Cora23tt/order_service/internal/usecase/receipt")
+
+type Service struct {
+	orders   *orderRepo.Repo
+	products *product.Repo
+	storage  storage.Storage
+	renderer *receipt.Renderer
+	log      *zap.SugaredLogger
+}
+
+func NewService(orders *orderRepo.Repo, products *product.Repo, storage storage.Storage, renderer *receipt.Renderer, log *zap.SugaredLogger) *Service {
+	return &Service{orders: orders, products: products, storage: storage, renderer: renderer, log: log}
+}
+
+// Document is a stored receipt ready to be downloaded.
+type Document struct {
+	Data        []byte
+	ContentType string
+	FileName    string
+}
+
+// Generate renders the receipt of a paid order, stores it and records its
+// download URL on the order. Generating it again replaces the stored one.
+func (s *Service) Generate(ctx context.Context, orderID int64) error {
+	ctx, span := tracer.Start(ctx, "receipt.Service.Generate")
+	defer span.End()
+
+	o, err := s.getOrder(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	_, err = s.generate(ctx, o)
+	return err
+}
+
+// Get returns the receipt of the order. Users can only download receipts of
+// their own orders. A receipt that is missing from the storage is generated
+// again if the order was paid.
+func (s *Service) Get(ctx context.Context, orderID, userID int64, role string) (*Document, error) {
+	ctx, span := tracer.Start(ctx, "receipt.Service.Get")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
+	o, err := s.getOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if role != "admin" && o.UserID != userID {
+		log.Warnw("unauthorized access to receipt", "order_id", orderID, "requester_id", userID)
+		return nil, pkgerrors.ErrOrderNotFound
+	}
+
+	var data []byte
+	rc, err := s.storage.Get(ctx, key(orderID))
+	switch err {
+	case nil:
+		data, err = io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			log.Errorw("read receipt failed", "order_id", orderID, "error", err)
+			return nil, pkgerrors.ErrInternal
+		}
+	case storage.ErrNotFound:
+		doc, err := s.generate(ctx, o)
+		if err != nil {
+			return nil, err
+		}
+		data = doc.Data
+	default:
+		log.Errorw("open receipt failed", "order_id", orderID, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+
+	contentType := http.DetectContentType(data)
+	ext := ".html"
+	if strings.HasPrefix(contentType, "application/pdf") {
+		ext = ".pdf"
+	}
+	return &Document{
+		Data:        data,
+		ContentType: contentType,
+		FileName:    fmt.Sprintf("receipt-%d%s", orderID, ext),
+	}, nil
+}
+
+func (s *Service) getOrder(ctx context.Context, orderID int64) (*orderRepo.Order, error) {
+	o, err := s.orders.GetByID(ctx, orderID)
+	switch err {
+	case nil:
+		return o, nil
+	case pkgerrors.ErrNotFound:
+		return nil, pkgerrors.ErrOrderNotFound
+	default:
+		return nil, err
+	}
+}
+
+func (s *Service) generate(ctx context.Context, o *orderRepo.Order) (*receipt.Document, error) {
+	log := logger.FromContext(ctx, s.log)
+
+	paidAt, err := s.orders.StatusReachedAt(ctx, o.ID, enums.StatusPaid)
+	if err != nil {
+		return nil, err
+	}
+	if paidAt == nil {
+		log.Warnw("receipt of unpaid order requested", "order_id", o.ID, "status", o.Status)
+		return nil, pkgerrors.ErrReceiptNotFound
+	}
+
+	r := &receipt.Receipt{
+		OrderID:     o.ID,
+		Date:        *paidAt,
+		PickupPoint: o.PickupPoint,
+		Lines:       make([]receipt.Line, 0, len(o.Items)),
+		Subtotal:    o.SubtotalAmount,
+		Discount:    o.DiscountAmount,
+		Net:         o.NetAmount,
+		Tax:         o.TaxAmount,
+		Total:       o.TotalAmount,
+	}
+	for _, item := range o.Items {
+		name := fmt.Sprintf("#%d", item.ProductID)
+		p, err := s.products.GetProductByID(ctx, item.ProductID)
+		switch err {
+		case nil:
+			name = p.Name
+		case pkgerrors.ErrNotFound:
+			// The product was deleted since; its ID still identifies it.
+		default:
+			return nil, pkgerrors.ErrInternal
+		}
+		r.Lines = append(r.Lines, receipt.Line{
+			Name:     name,
+			Quantity: item.Quantity,
+			Price:    item.Price,
+			Discount: item.DiscountAmount,
+			VATRate:  item.VATRate,
+			Tax:      item.TaxAmount,
+			Gross:    item.GrossAmount,
+		})
+	}
+
+	doc, err := s.renderer.Render(r)
+	if err != nil {
+		log.Errorw("render receipt failed", "order_id", o.ID, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	if err := s.storage.Put(ctx, key(o.ID), bytes.NewReader(doc.Data)); err != nil {
+		log.Errorw("store receipt failed", "order_id", o.ID, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	if err := s.orders.SetReceiptURL(ctx, o.ID, fmt.Sprintf("/api/v1/orders/%d/receipt", o.ID)); err != nil {
+		return nil, err
+	}
+
+	log.Infow("receipt generated", "order_id", o.ID, "content_type", doc.ContentType)
+	return doc, nil
+}
+
+// key is the storage key of the receipt of an order. It has no extension
+// because the receipt may be a PDF or an HTML document.
+func key(orderID int64) string {
+	return fmt.Sprintf("receipts/%d", orderID)
+}
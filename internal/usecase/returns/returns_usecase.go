@@ -0,0 +1,442 @@
+package returns
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	orderRepo "github.com/Cora23tt/order_service/internal/repository/order"
+	returnRepo "github.com/Cora23tt/order_service/internal/repository/returns"
+	"github.com/Cora23tt/order_service/internal/repository/uow"
+	"github.com/Cora23tt/order_service/internal/usecase/order"
+	"github.com/Cora23tt/order_service/internal/usecase/payment"
+	"github.com/Cora23tt/order_service/pkg/config"
+	"github.com/Cora23tt/order_service/pkg/enums"
+	pkgerrors "github.com/Cora23tt/order_service/pkg/errors"
+	"github.com/Cora23tt/order_service/pkg/logger"
+	"github.com/Cora23tt/order_service/pkg/utils"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
+)
+
+var tracer = otel.Tracer("github.com/Cora23tt/order_service/internal/usecase/returns")
+
+type Service struct {
+	repo      *returnRepo.Repo
+	orders    *order.Service
+	payments  *payment.Service
+	uow       uow.UnitOfWork
+	window    time.Duration
+	maxPhotos int
+	uploadDir string
+	log       *zap.SugaredLogger
+}
+
+func NewService(repo *returnRepo.Repo, orders *order.Service, payments *payment.Service, uow uow.UnitOfWork, cfg *config.Config, log *zap.SugaredLogger) *Service {
+	return &Service{
+		repo:      repo,
+		orders:    orders,
+		payments:  payments,
+		uow:       uow,
+		window:    cfg.Returns.Window,
+		maxPhotos: int(cfg.Returns.MaxPhotos),
+		uploadDir: cfg.Upload.Dir,
+		log:       log,
+	}
+}
+
+type CreateInput struct {
+	OrderID int64
+	Items   []order.RefundItemInput
+	Reason  string
+	Actor   order.Actor
+}
+
+// Create opens a return request for items of a delivered order. Items can
+// only be returned within the return window after delivery, and never more
+// of them than were delivered, refunded or already asked to be returned.
+func (s *Service) Create(ctx context.Context, input CreateInput) (*returnRepo.Return, error) {
+	ctx, span := tracer.Start(ctx, "returns.Service.Create")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
+	tx, err := s.uow.Begin(ctx)
+	if err != nil {
+		log.Errorw("begin transaction failed", "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	orders := orderRepo.NewWithTx(tx.GetTx(), s.log)
+	returns := returnRepo.NewWithTx(tx.GetTx(), s.log)
+
+	// Locking the order serializes the returns of the same order.
+	o, err := orders.GetByIDForUpdate(ctx, input.OrderID)
+	switch err {
+	case nil:
+	case pkgerrors.ErrNotFound:
+		return nil, pkgerrors.ErrOrderNotFound
+	default:
+		return nil, err
+	}
+	if input.Actor.Role != "admin" && o.UserID != input.Actor.UserID {
+		log.Warnw("return of another user's order", "order_id", o.ID, "requester_id", input.Actor.UserID)
+		return nil, pkgerrors.ErrOrderNotFound
+	}
+
+	if o.Status != enums.StatusDelivered && o.Status != enums.StatusPartiallyRefunded {
+		log.Warnw("return of undelivered order", "order_id", o.ID, "status", o.Status)
+		return nil, pkgerrors.ErrReturnNotAllowed
+	}
+	deliveredAt, err := orders.StatusReachedAt(ctx, o.ID, enums.StatusDelivered)
+	if err != nil {
+		return nil, err
+	}
+	if deliveredAt == nil {
+		log.Warnw("return of undelivered order", "order_id", o.ID, "status", o.Status)
+		return nil, pkgerrors.ErrReturnNotAllowed
+	}
+	if time.Since(*deliveredAt) > s.window {
+		log.Warnw("return window expired", "order_id", o.ID, "delivered_at", *deliveredAt)
+		return nil, pkgerrors.ErrReturnWindowExpired
+	}
+
+	held, err := returns.HeldQuantities(ctx, o.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	quantities := make(map[int64]int64, len(input.Items))
+	for _, item := range input.Items {
+		quantities[item.ItemID] += item.Quantity
+	}
+	ret := &returnRepo.Return{OrderID: o.ID, UserID: o.UserID, Reason: input.Reason}
+	for _, item := range o.Items {
+		quantity, ok := quantities[item.ID]
+		if !ok {
+			continue
+		}
+		delete(quantities, item.ID)
+		if quantity > item.Quantity-item.RefundedQuantity-held[item.ID] {
+			log.Warnw("return quantity exceeded", "order_id", o.ID, "item_id", item.ID, "quantity", quantity,
+				"refunded", item.RefundedQuantity, "held", held[item.ID])
+			return nil, pkgerrors.ErrReturnQuantityExceeded
+		}
+		ret.Items = append(ret.Items, returnRepo.Item{OrderItemID: item.ID, ProductID: item.ProductID, Quantity: quantity})
+	}
+	for itemID := range quantities {
+		log.Warnw("return of unknown order item", "order_id", o.ID, "item_id", itemID)
+		return nil, pkgerrors.ErrOrderItemNotFound
+	}
+
+	if err := returns.Create(ctx, ret); err != nil {
+		return nil, err
+	}
+	err = returns.AddStatusChange(ctx, &returnRepo.StatusChange{
+		ReturnID:    ret.ID,
+		ToStatus:    ret.Status,
+		ActorUserID: actorUserID(input.Actor),
+		ActorRole:   input.Actor.Role,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Errorw("commit transaction failed", "return_id", ret.ID, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	committed = true
+
+	log.Infow("return requested", "return_id", ret.ID, "order_id", o.ID)
+	return s.get(ctx, ret.ID)
+}
+
+// Get returns the return with its photos and history. Users only see their
+// own returns.
+func (s *Service) Get(ctx context.Context, id, userID int64, role string) (*returnRepo.Return, error) {
+	ctx, span := tracer.Start(ctx, "returns.Service.Get")
+	defer span.End()
+
+	ret, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if role != "admin" && ret.UserID != userID {
+		logger.FromContext(ctx, s.log).Warnw("unauthorized access to return", "return_id", id, "requester_id", userID)
+		return nil, pkgerrors.ErrReturnNotFound
+	}
+	return ret, nil
+}
+
+func (s *Service) get(ctx context.Context, id int64) (*returnRepo.Return, error) {
+	ret, err := s.repo.GetByID(ctx, id)
+	switch err {
+	case nil:
+	case pkgerrors.ErrNotFound:
+		return nil, pkgerrors.ErrReturnNotFound
+	default:
+		return nil, err
+	}
+	for i := range ret.Photos {
+		ret.Photos[i].URL = photoURL(ret.ID, ret.Photos[i].ID)
+	}
+	return ret, nil
+}
+
+// List returns the returns matching the filter. Users only see their own
+// returns.
+func (s *Service) List(ctx context.Context, filter returnRepo.ListFilter, userID int64, role string) ([]*returnRepo.Return, error) {
+	ctx, span := tracer.Start(ctx, "returns.Service.List")
+	defer span.End()
+
+	if role != "admin" {
+		filter.UserID = &userID
+	}
+	return s.repo.List(ctx, filter)
+}
+
+// AddPhoto stores a photo of the returned goods. Photos can be added by the
+// customer while the return awaits a decision.
+func (s *Service) AddPhoto(ctx context.Context, id, userID int64, ext string, src io.Reader) (*returnRepo.Photo, error) {
+	ctx, span := tracer.Start(ctx, "returns.Service.AddPhoto")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
+	tx, err := s.uow.Begin(ctx)
+	if err != nil {
+		log.Errorw("begin transaction failed", "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	returns := returnRepo.NewWithTx(tx.GetTx(), s.log)
+	ret, err := returns.GetByIDForUpdate(ctx, id)
+	switch err {
+	case nil:
+	case pkgerrors.ErrNotFound:
+		return nil, pkgerrors.ErrReturnNotFound
+	default:
+		return nil, err
+	}
+	if ret.UserID != userID {
+		log.Warnw("photo for another user's return", "return_id", id, "requester_id", userID)
+		return nil, pkgerrors.ErrReturnNotFound
+	}
+	if ret.Status != enums.ReturnRequested {
+		return nil, pkgerrors.ErrReturnNotEditable
+	}
+	if len(ret.Photos) >= s.maxPhotos {
+		return nil, pkgerrors.ErrReturnPhotoLimit
+	}
+
+	photo := &returnRepo.Photo{Path: filepath.Join("returns", fmt.Sprint(id), utils.NewUUID()+ext)}
+	fullPath := filepath.Join(s.uploadDir, photo.Path)
+	if err := saveFile(fullPath, src); err != nil {
+		log.Errorw("save return photo failed", "return_id", id, "path", fullPath, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	defer func() {
+		if !committed {
+			_ = os.Remove(fullPath)
+		}
+	}()
+
+	if err := returns.AddPhoto(ctx, id, photo); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		log.Errorw("commit transaction failed", "return_id", id, "error", err)
+		return nil, pkgerrors.ErrInternal
+	}
+	committed = true
+
+	photo.URL = photoURL(id, photo.ID)
+	log.Infow("return photo added", "return_id", id, "photo_id", photo.ID)
+	return photo, nil
+}
+
+// PhotoPath returns the file of a photo of the return. Users only see the
+// photos of their own returns.
+func (s *Service) PhotoPath(ctx context.Context, id, photoID, userID int64, role string) (string, error) {
+	ctx, span := tracer.Start(ctx, "returns.Service.PhotoPath")
+	defer span.End()
+
+	ret, err := s.Get(ctx, id, userID, role)
+	if err != nil {
+		return "", err
+	}
+	for _, p := range ret.Photos {
+		if p.ID == photoID {
+			return filepath.Join(s.uploadDir, p.Path), nil
+		}
+	}
+	return "", pkgerrors.ErrPhotoNotFound
+}
+
+// UpdateStatus moves the return to the next step of its workflow. Settling
+// it as refunded refunds the returned items through the payment provider and
+// puts them back to stock; settling it as replaced creates a replacement
+// order for them. Either happens in one transaction with the status change.
+func (s *Service) UpdateStatus(ctx context.Context, id int64, status enums.ReturnStatus, actor order.Actor, reason string) (*returnRepo.Return, error) {
+	ctx, span := tracer.Start(ctx, "returns.Service.UpdateStatus")
+	defer span.End()
+	log := logger.FromContext(ctx, s.log)
+
+	if status == enums.ReturnRejected && reason == "" {
+		return nil, pkgerrors.InvalidField("reason", "required", "")
+	}
+
+	ret, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !ret.Status.CanTransitionTo(status) {
+		log.Warnw("invalid return status transition", "return_id", id, "from", ret.Status, "to", status)
+		return nil, pkgerrors.ErrInvalidTransition
+	}
+
+	items := make([]order.RefundItemInput, 0, len(ret.Items))
+	for _, item := range ret.Items {
+		items = append(items, order.RefundItemInput{ItemID: item.OrderItemID, Quantity: item.Quantity})
+	}
+
+	switch status {
+	case enums.ReturnRefunded:
+		refundReason := fmt.Sprintf("return #%d: %s", ret.ID, ret.Reason)
+		_, err = s.payments.Refund(ctx, ret.OrderID, order.RefundInput{
+			Items:  items,
+			Reason: refundReason,
+			BeforeCommit: func(ctx context.Context, tx pgx.Tx, refund *orderRepo.Refund) error {
+				return s.transition(ctx, tx, ret, status, actor, reason, &refund.ID, nil)
+			},
+		}, actor)
+	case enums.ReturnReplaced:
+		replacementReason := fmt.Sprintf("replacement for return #%d", ret.ID)
+		_, err = s.orders.CreateReplacement(ctx, ret.OrderID, items, actor, replacementReason, func(ctx context.Context, tx pgx.Tx, replacementID int64) error {
+			return s.transition(ctx, tx, ret, status, actor, reason, nil, &replacementID)
+		})
+	default:
+		err = s.update(ctx, ret, status, actor, reason)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infow("return status changed", "return_id", id, "from", ret.Status, "to", status)
+	return s.get(ctx, id)
+}
+
+func (s *Service) update(ctx context.Context, ret *returnRepo.Return, to enums.ReturnStatus, actor order.Actor, reason string) error {
+	log := logger.FromContext(ctx, s.log)
+
+	tx, err := s.uow.Begin(ctx)
+	if err != nil {
+		log.Errorw("begin transaction failed", "error", err)
+		return pkgerrors.ErrInternal
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	if err := s.transition(ctx, tx.GetTx(), ret, to, actor, reason, nil, nil); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		log.Errorw("commit transaction failed", "return_id", ret.ID, "error", err)
+		return pkgerrors.ErrInternal
+	}
+	committed = true
+	return nil
+}
+
+// transition moves the return from the status it was loaded in to the given
+// one and records the change in its history. The return stays locked until
+// tx ends. It fails with ErrInvalidTransition if a concurrent request changed
+// the return meanwhile, which rolls back the refund or replacement made for
+// it; a refund runs it before the money is returned, so concurrent refunds of
+// one return never reach the payment provider twice.
+func (s *Service) transition(ctx context.Context, tx pgx.Tx, ret *returnRepo.Return, to enums.ReturnStatus, actor order.Actor, reason string, refundID, replacementOrderID *int64) error {
+	log := logger.FromContext(ctx, s.log)
+	returns := returnRepo.NewWithTx(tx, s.log)
+
+	current, err := returns.GetByIDForUpdate(ctx, ret.ID)
+	if err != nil {
+		return err
+	}
+	if current.Status != ret.Status {
+		log.Warnw("return changed concurrently", "return_id", ret.ID, "from", ret.Status, "status", current.Status, "to", to)
+		return pkgerrors.ErrInvalidTransition
+	}
+
+	updated, err := returns.UpdateStatus(ctx, ret.ID, ret.Status, to)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		log.Warnw("return changed concurrently", "return_id", ret.ID, "from", ret.Status, "to", to)
+		return pkgerrors.ErrInvalidTransition
+	}
+	if refundID != nil || replacementOrderID != nil {
+		if err := returns.SetSettlement(ctx, ret.ID, refundID, replacementOrderID); err != nil {
+			return err
+		}
+	}
+
+	from := ret.Status
+	change := &returnRepo.StatusChange{
+		ReturnID:    ret.ID,
+		FromStatus:  &from,
+		ToStatus:    to,
+		ActorUserID: actorUserID(actor),
+		ActorRole:   actor.Role,
+	}
+	if reason != "" {
+		change.Reason = &reason
+	}
+	return returns.AddStatusChange(ctx, change)
+}
+
+func actorUserID(a order.Actor) *int64 {
+	if a.UserID == 0 {
+		return nil
+	}
+	return &a.UserID
+}
+
+func photoURL(returnID, photoID int64) string {
+	return fmt.Sprintf("/api/v1/returns/%d/photos/%d", returnID, photoID)
+}
+
+func saveFile(path string, src io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, src); err != nil {
+		_ = f.Close()
+		_ = os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
@@ -6,8 +6,11 @@ import (
 
 	"github.com/Cora23tt/order_service/internal/repository/user"
 	pkgerrors "github.com/Cora23tt/order_service/pkg/errors"
+	"go.opentelemetry.io/otel"
 )
 
+var tracer = otel.Tracer("github.com/Cora23tt/order_service/internal/usecase/user")
+
 type Service struct {
 	repo *user.Repo
 }
@@ -22,13 +25,17 @@ type Profile struct {
 	Role        string  `json:"role" example:"user"`
 	PINFL       *string `json:"pinfl,omitempty" example:"12345678901234"`
 	AvatarURL   *string `json:"avatar_url,omitempty" example:"/profile/1/photo"`
+	Language    *string `json:"language,omitempty" example:"uz"`
 }
 
 func (s *Service) GetProfile(ctx context.Context, userID int64) (*Profile, error) {
+	ctx, span := tracer.Start(ctx, "user.Service.GetProfile")
+	defer span.End()
+
 	u, err := s.repo.GetByID(ctx, userID)
 	if err != nil {
 		if err == pkgerrors.ErrNotFound {
-			return nil, pkgerrors.ErrNotFound
+			return nil, pkgerrors.ErrUserNotFound
 		}
 		return nil, fmt.Errorf("get profile: %w", err)
 	}
@@ -39,20 +46,29 @@ func (s *Service) GetProfile(ctx context.Context, userID int64) (*Profile, error
 		Role:        u.Role,
 		PINFL:       u.PINFL,
 		AvatarURL:   u.AvatarURL,
+		Language:    u.Language,
 	}, nil
 }
 
-func (s *Service) UpdateProfile(ctx context.Context, userID int64, pinfl *string, avatarURL *string) error {
+// UpdateProfile replaces the PINFL and avatar of the user. The language is
+// only changed when one is given.
+func (s *Service) UpdateProfile(ctx context.Context, userID int64, pinfl *string, avatarURL *string, language *string) error {
+	ctx, span := tracer.Start(ctx, "user.Service.UpdateProfile")
+	defer span.End()
+
 	u, err := s.repo.GetByID(ctx, userID)
 	if err != nil {
 		if err == pkgerrors.ErrNotFound {
-			return pkgerrors.ErrNotFound
+			return pkgerrors.ErrUserNotFound
 		}
 		return fmt.Errorf("get user before update: %w", err)
 	}
 
 	u.PINFL = pinfl
 	u.AvatarURL = avatarURL
+	if language != nil {
+		u.Language = language
+	}
 
 	if err := s.repo.Update(ctx, u); err != nil {
 		return fmt.Errorf("update profile: %w", err)
@@ -62,6 +78,9 @@ func (s *Service) UpdateProfile(ctx context.Context, userID int64, pinfl *string
 }
 
 func (s *Service) ListAllUsers(ctx context.Context) ([]Profile, error) {
+	ctx, span := tracer.Start(ctx, "user.Service.ListAllUsers")
+	defer span.End()
+
 	users, err := s.repo.ListAll(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("list users: %w", err)
@@ -75,6 +94,7 @@ func (s *Service) ListAllUsers(ctx context.Context) ([]Profile, error) {
 			Role:        u.Role,
 			PINFL:       u.PINFL,
 			AvatarURL:   u.AvatarURL,
+			Language:    u.Language,
 		})
 	}
 
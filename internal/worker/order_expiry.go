@@ -0,0 +1,92 @@
+// Package worker holds the background jobs that run alongside the HTTP
+// server.
+package worker
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Cora23tt/order_service/internal/usecase/order"
+	"github.com/Cora23tt/order_service/pkg/config"
+	"github.com/Cora23tt/order_service/pkg/lifecycle"
+)
+
+// OrderExpiry periodically cancels orders that were not paid within the
+// payment timeout. Every instance of the service runs it; the orders are
+// locked with SKIP LOCKED, so instances share the work instead of competing
+// for it.
+type OrderExpiry struct {
+	orders   *order.Service
+	timeout  time.Duration
+	interval time.Duration
+	batch    int
+	log      *zap.SugaredLogger
+}
+
+func NewOrderExpiry(cfg *config.Config, orders *order.Service, lc *lifecycle.Lifecycle, log *zap.SugaredLogger) *OrderExpiry {
+	w := &OrderExpiry{
+		orders:   orders,
+		timeout:  cfg.Orders.PaymentTimeout,
+		interval: cfg.Orders.ExpiryInterval,
+		batch:    int(cfg.Orders.ExpiryBatchSize),
+		log:      log,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	lc.Append(lifecycle.Hook{
+		Name: "order expiry worker",
+		OnStart: func(context.Context) error {
+			lc.Go("order expiry worker", func() error {
+				defer close(done)
+				w.run(ctx)
+				return nil
+			})
+			return nil
+		},
+		OnStop: func(stopCtx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-stopCtx.Done():
+				return stopCtx.Err()
+			}
+		},
+	})
+	return w
+}
+
+func (w *OrderExpiry) run(ctx context.Context) {
+	w.log.Infow("order expiry worker started", "payment_timeout", w.timeout, "interval", w.interval)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		w.expire(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// expire cancels expired orders batch by batch until none are left. Errors
+// are logged and the orders are retried on the next tick.
+func (w *OrderExpiry) expire(ctx context.Context) {
+	for ctx.Err() == nil {
+		n, err := w.orders.CancelExpired(ctx, w.timeout, w.batch)
+		if err != nil {
+			if ctx.Err() == nil {
+				w.log.Errorw("cancel expired orders failed", "error", err)
+			}
+			return
+		}
+		if n < w.batch {
+			return
+		}
+	}
+}
@@ -0,0 +1,622 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	// Opening hours are evaluated in a configured zone, which must load on
+	// hosts without a zoneinfo database too.
+	_ "time/tzdata"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the complete application configuration. It is built from the
+// defaults below, then the optional YAML file named by CONFIG_FILE, then the
+// environment, each layer overriding the previous one.
+type Config struct {
+	Log         LogConfig         `yaml:"log"`
+	HTTP        HTTPConfig        `yaml:"http"`
+	DB          DBConfig          `yaml:"db"`
+	Auth        AuthConfig        `yaml:"auth"`
+	CORS        CORSConfig        `yaml:"cors"`
+	Upload      UploadConfig      `yaml:"upload"`
+	Tracing     TracingConfig     `yaml:"tracing"`
+	Idempotency IdempotencyConfig `yaml:"idempotency"`
+	Tax         TaxConfig         `yaml:"tax"`
+	Payment     PaymentConfig     `yaml:"payment"`
+	Orders      OrdersConfig      `yaml:"orders"`
+	Returns     ReturnsConfig     `yaml:"returns"`
+	Receipts    ReceiptsConfig    `yaml:"receipts"`
+	Pickup      PickupConfig      `yaml:"pickup"`
+	Delivery    DeliveryConfig    `yaml:"delivery"`
+}
+
+type LogConfig struct {
+	Level string `yaml:"level"`
+}
+
+type HTTPConfig struct {
+	Host              string        `yaml:"host"`
+	Port              string        `yaml:"port"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout"`
+	// DrainDelay is how long the server keeps serving while reporting
+	// not-ready before it stops accepting connections.
+	DrainDelay time.Duration `yaml:"drain_delay"`
+}
+
+type DBConfig struct {
+	URL               string        `yaml:"url"`
+	MaxConns          int32         `yaml:"max_conns"`
+	MinConns          int32         `yaml:"min_conns"`
+	MaxConnLifetime   time.Duration `yaml:"max_conn_lifetime"`
+	MaxConnIdleTime   time.Duration `yaml:"max_conn_idle_time"`
+	HealthCheckPeriod time.Duration `yaml:"health_check_period"`
+}
+
+type AuthConfig struct {
+	JWTSecret       string        `yaml:"jwt_secret"`
+	AccessTokenTTL  time.Duration `yaml:"access_token_ttl"`
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl"`
+}
+
+type CORSConfig struct {
+	AllowedOrigins []string `yaml:"allowed_origins"`
+}
+
+type UploadConfig struct {
+	Dir                string `yaml:"dir"`
+	MaxAvatarSize      int64  `yaml:"max_avatar_size"`
+	MaxReturnPhotoSize int64  `yaml:"max_return_photo_size"`
+}
+
+type TracingConfig struct {
+	// Exporter is one of none, otlp, stdout or memory. With none trace IDs
+	// are still generated for the logs but spans are not exported.
+	Exporter     string  `yaml:"exporter"`
+	OTLPEndpoint string  `yaml:"otlp_endpoint"`
+	ServiceName  string  `yaml:"service_name"`
+	SampleRatio  float64 `yaml:"sample_ratio"`
+}
+
+type TaxConfig struct {
+	// PricesIncludeTax tells whether product prices already include VAT.
+	PricesIncludeTax bool `yaml:"prices_include_tax"`
+	// Rounding is one of half_up, half_even, down or up.
+	Rounding string `yaml:"rounding"`
+	// DefaultRate is the VAT rate of new products in basis points.
+	DefaultRate int64 `yaml:"default_rate"`
+}
+
+type PaymentConfig struct {
+	// Provider is the payment provider orders are paid with. Only mock is
+	// available so far.
+	Provider string            `yaml:"provider"`
+	Mock     MockPaymentConfig `yaml:"mock"`
+}
+
+// MockPaymentConfig configures the mock provider used for local development
+// and tests.
+type MockPaymentConfig struct {
+	// Outcome is success or failure: the result of every payment and refund.
+	// It defaults to failure, so that a deployment that did not configure
+	// payments never marks orders paid without money moving.
+	Outcome string `yaml:"outcome"`
+	// Delay is how long every call of the provider takes and how long after
+	// an invoice is created its callback is sent.
+	Delay time.Duration `yaml:"delay"`
+	// CallbackURL receives the payment callbacks. When empty no callbacks
+	// are sent and they have to be posted by hand.
+	CallbackURL string `yaml:"callback_url"`
+	// Secret signs the callbacks. It has no default: anyone who knew it
+	// could post forged callbacks.
+	Secret string `yaml:"secret"`
+}
+
+type OrdersConfig struct {
+	// PaymentTimeout is how long an order may stay pending payment before it
+	// is cancelled.
+	PaymentTimeout time.Duration `yaml:"payment_timeout"`
+	// ExpiryInterval is how often expired orders are looked for.
+	ExpiryInterval time.Duration `yaml:"expiry_interval"`
+	// ExpiryBatchSize is how many orders are cancelled in one transaction.
+	ExpiryBatchSize int64 `yaml:"expiry_batch_size"`
+}
+
+type ReturnsConfig struct {
+	// Window is how long after delivery a return may be requested.
+	Window time.Duration `yaml:"window"`
+	// MaxPhotos is how many photos may be attached to a return request.
+	MaxPhotos int64 `yaml:"max_photos"`
+}
+
+type ReceiptsConfig struct {
+	// Format is pdf or html. PDF receipts fall back to HTML when they cannot
+	// be rendered.
+	Format string `yaml:"format"`
+	// FontPath is a TrueType font for PDF receipts. Without it only Latin-1
+	// text can be printed and other receipts are rendered as HTML.
+	FontPath string `yaml:"font_path"`
+}
+
+type PickupConfig struct {
+	// Timezone is the IANA time zone pickup point opening hours are in.
+	Timezone string `yaml:"timezone"`
+}
+
+type DeliveryConfig struct {
+	// BookingHorizon is how far ahead of now a delivery slot can be booked.
+	BookingHorizon time.Duration `yaml:"booking_horizon"`
+}
+
+type IdempotencyConfig struct {
+	// TTL is how long a stored Idempotency-Key response is replayed.
+	TTL time.Duration `yaml:"ttl"`
+	// LockTimeout is how long a key stays reserved by a request that has not
+	// finished. After it, a retry takes the key over, so it must be longer
+	// than any request takes.
+	LockTimeout time.Duration `yaml:"lock_timeout"`
+	// MaxBodySize limits, in bytes, the body of requests sent with an
+	// Idempotency-Key header, which is read into memory to fingerprint it.
+	MaxBodySize int64 `yaml:"max_body_size"`
+}
+
+const (
+	minJWTSecretLength         = 16
+	minPaymentMockSecretLength = 16
+)
+
+func defaults() Config {
+	return Config{
+		Log: LogConfig{Level: "info"},
+		HTTP: HTTPConfig{
+			Port:              "8080",
+			ReadTimeout:       15 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       60 * time.Second,
+			ShutdownTimeout:   15 * time.Second,
+			DrainDelay:        5 * time.Second,
+		},
+		DB: DBConfig{
+			MaxConns:          10,
+			MinConns:          0,
+			MaxConnLifetime:   time.Hour,
+			MaxConnIdleTime:   30 * time.Minute,
+			HealthCheckPeriod: time.Minute,
+		},
+		Auth: AuthConfig{
+			AccessTokenTTL:  15 * time.Minute,
+			RefreshTokenTTL: 30 * 24 * time.Hour,
+		},
+		CORS: CORSConfig{AllowedOrigins: []string{"*"}},
+		Upload: UploadConfig{
+			Dir:                "web",
+			MaxAvatarSize:      5 << 20,
+			MaxReturnPhotoSize: 5 << 20,
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			ServiceName: "order_service",
+			SampleRatio: 1,
+		},
+		Idempotency: IdempotencyConfig{
+			TTL:         24 * time.Hour,
+			LockTimeout: 2 * time.Minute,
+			MaxBodySize: 10 << 20,
+		},
+		Tax: TaxConfig{
+			PricesIncludeTax: true,
+			Rounding:         "half_up",
+			DefaultRate:      1200,
+		},
+		Orders: OrdersConfig{
+			PaymentTimeout:  30 * time.Minute,
+			ExpiryInterval:  time.Minute,
+			ExpiryBatchSize: 100,
+		},
+		Returns: ReturnsConfig{
+			Window:    14 * 24 * time.Hour,
+			MaxPhotos: 5,
+		},
+		Receipts: ReceiptsConfig{Format: "pdf"},
+		Pickup:   PickupConfig{Timezone: "Asia/Tashkent"},
+		Delivery: DeliveryConfig{BookingHorizon: 14 * 24 * time.Hour},
+		Payment: PaymentConfig{
+			Provider: "mock",
+			Mock: MockPaymentConfig{
+				Outcome:     "failure",
+				Delay:       2 * time.Second,
+				CallbackURL: "http://localhost:8080/api/v1/payments/webhook/mock",
+			},
+		},
+	}
+}
+
+// Load builds the configuration and validates it. The returned error lists
+// every problem found, not only the first one.
+func Load() (*Config, error) {
+	return load((*Config).validate)
+}
+
+// LoadDB builds the configuration like Load but only validates the logger and
+// database settings, so that the migrate subcommand runs without the
+// application secrets.
+func LoadDB() (*Config, error) {
+	return load((*Config).validateDB)
+}
+
+func load(validate func(*Config) []error) (*Config, error) {
+	cfg := defaults()
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	errs := cfg.loadEnv()
+	errs = append(errs, validate(&cfg)...)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return &cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Config) loadEnv() []error {
+	var e envLoader
+
+	e.string("LOG_LEVEL", &c.Log.Level)
+
+	e.string("HOST", &c.HTTP.Host)
+	e.string("PORT", &c.HTTP.Port)
+	e.duration("HTTP_READ_TIMEOUT", &c.HTTP.ReadTimeout)
+	e.duration("HTTP_READ_HEADER_TIMEOUT", &c.HTTP.ReadHeaderTimeout)
+	e.duration("HTTP_WRITE_TIMEOUT", &c.HTTP.WriteTimeout)
+	e.duration("HTTP_IDLE_TIMEOUT", &c.HTTP.IdleTimeout)
+	e.duration("SHUTDOWN_TIMEOUT", &c.HTTP.ShutdownTimeout)
+	e.duration("SHUTDOWN_DRAIN_DELAY", &c.HTTP.DrainDelay)
+
+	e.string("DATABASE_URL", &c.DB.URL)
+	e.int32("DB_MAX_CONNS", &c.DB.MaxConns)
+	e.int32("DB_MIN_CONNS", &c.DB.MinConns)
+	e.duration("DB_MAX_CONN_LIFETIME", &c.DB.MaxConnLifetime)
+	e.duration("DB_MAX_CONN_IDLE_TIME", &c.DB.MaxConnIdleTime)
+	e.duration("DB_HEALTH_CHECK_PERIOD", &c.DB.HealthCheckPeriod)
+
+	e.string("JWT_SECRET", &c.Auth.JWTSecret)
+	e.duration("ACCESS_TOKEN_TTL", &c.Auth.AccessTokenTTL)
+	e.duration("REFRESH_TOKEN_TTL", &c.Auth.RefreshTokenTTL)
+
+	e.list("CORS_ALLOWED_ORIGINS", &c.CORS.AllowedOrigins)
+
+	e.string("UPLOAD_DIR", &c.Upload.Dir)
+	e.int64("UPLOAD_MAX_AVATAR_SIZE", &c.Upload.MaxAvatarSize)
+	e.int64("UPLOAD_MAX_RETURN_PHOTO_SIZE", &c.Upload.MaxReturnPhotoSize)
+
+	e.string("TRACING_EXPORTER", &c.Tracing.Exporter)
+	e.string("TRACING_OTLP_ENDPOINT", &c.Tracing.OTLPEndpoint)
+	e.string("TRACING_SERVICE_NAME", &c.Tracing.ServiceName)
+	e.float64("TRACING_SAMPLE_RATIO", &c.Tracing.SampleRatio)
+
+	e.duration("IDEMPOTENCY_TTL", &c.Idempotency.TTL)
+	e.duration("IDEMPOTENCY_LOCK_TIMEOUT", &c.Idempotency.LockTimeout)
+	e.int64("IDEMPOTENCY_MAX_BODY_SIZE", &c.Idempotency.MaxBodySize)
+
+	e.bool("TAX_PRICES_INCLUDE_TAX", &c.Tax.PricesIncludeTax)
+	e.string("TAX_ROUNDING", &c.Tax.Rounding)
+	e.int64("TAX_DEFAULT_RATE", &c.Tax.DefaultRate)
+
+	e.duration("ORDER_PAYMENT_TIMEOUT", &c.Orders.PaymentTimeout)
+	e.duration("ORDER_EXPIRY_INTERVAL", &c.Orders.ExpiryInterval)
+	e.int64("ORDER_EXPIRY_BATCH_SIZE", &c.Orders.ExpiryBatchSize)
+
+	e.duration("RETURN_WINDOW", &c.Returns.Window)
+	e.int64("RETURN_MAX_PHOTOS", &c.Returns.MaxPhotos)
+
+	e.string("RECEIPT_FORMAT", &c.Receipts.Format)
+	e.string("RECEIPT_FONT_PATH", &c.Receipts.FontPath)
+
+	e.string("PICKUP_TIMEZONE", &c.Pickup.Timezone)
+
+	e.duration("DELIVERY_BOOKING_HORIZON", &c.Delivery.BookingHorizon)
+
+	e.string("PAYMENT_PROVIDER", &c.Payment.Provider)
+	e.string("PAYMENT_MOCK_OUTCOME", &c.Payment.Mock.Outcome)
+	e.duration("PAYMENT_MOCK_DELAY", &c.Payment.Mock.Delay)
+	e.string("PAYMENT_MOCK_CALLBACK_URL", &c.Payment.Mock.CallbackURL)
+	e.string("PAYMENT_MOCK_SECRET", &c.Payment.Mock.Secret)
+
+	return e.errs
+}
+
+// validateDB checks the settings needed to log and to connect to the
+// database.
+func (c *Config) validateDB() []error {
+	var errs []error
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if !slices.Contains([]string{"debug", "info", "warn", "error"}, strings.ToLower(c.Log.Level)) {
+		add("log.level: must be one of debug, info, warn, error, got %q", c.Log.Level)
+	}
+
+	if c.DB.URL == "" {
+		add("db.url: DATABASE_URL is not set")
+	}
+	if c.DB.MaxConns <= 0 {
+		add("db.max_conns: must be positive, got %d", c.DB.MaxConns)
+	}
+	if c.DB.MinConns < 0 || c.DB.MinConns > c.DB.MaxConns {
+		add("db.min_conns: must be between 0 and db.max_conns, got %d", c.DB.MinConns)
+	}
+	durations := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"db.max_conn_lifetime", c.DB.MaxConnLifetime},
+		{"db.max_conn_idle_time", c.DB.MaxConnIdleTime},
+		{"db.health_check_period", c.DB.HealthCheckPeriod},
+	}
+	for _, d := range durations {
+		if d.value <= 0 {
+			add("%s: must be positive, got %s", d.name, d.value)
+		}
+	}
+
+	return errs
+}
+
+func (c *Config) validate() []error {
+	errs := c.validateDB()
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if port, err := strconv.Atoi(c.HTTP.Port); err != nil || port < 1 || port > 65535 {
+		add("http.port: must be a number between 1 and 65535, got %q", c.HTTP.Port)
+	}
+	durations := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"http.read_timeout", c.HTTP.ReadTimeout},
+		{"http.read_header_timeout", c.HTTP.ReadHeaderTimeout},
+		{"http.write_timeout", c.HTTP.WriteTimeout},
+		{"http.idle_timeout", c.HTTP.IdleTimeout},
+		{"http.shutdown_timeout", c.HTTP.ShutdownTimeout},
+		{"auth.access_token_ttl", c.Auth.AccessTokenTTL},
+		{"auth.refresh_token_ttl", c.Auth.RefreshTokenTTL},
+		{"idempotency.ttl", c.Idempotency.TTL},
+		{"idempotency.lock_timeout", c.Idempotency.LockTimeout},
+		{"orders.payment_timeout", c.Orders.PaymentTimeout},
+		{"orders.expiry_interval", c.Orders.ExpiryInterval},
+		{"returns.window", c.Returns.Window},
+		{"delivery.booking_horizon", c.Delivery.BookingHorizon},
+	}
+	for _, d := range durations {
+		if d.value <= 0 {
+			add("%s: must be positive, got %s", d.name, d.value)
+		}
+	}
+
+	if c.Idempotency.LockTimeout <= c.HTTP.WriteTimeout || c.Idempotency.LockTimeout > c.Idempotency.TTL {
+		add("idempotency.lock_timeout: must be longer than http.write_timeout and not exceed idempotency.ttl, got %s", c.Idempotency.LockTimeout)
+	}
+	if c.Idempotency.MaxBodySize <= 0 {
+		add("idempotency.max_body_size: must be positive, got %d", c.Idempotency.MaxBodySize)
+	}
+
+	if c.HTTP.DrainDelay < 0 || c.HTTP.DrainDelay >= c.HTTP.ShutdownTimeout {
+		add("http.drain_delay: must be between 0 and http.shutdown_timeout, got %s", c.HTTP.DrainDelay)
+	}
+
+	if len(c.Auth.JWTSecret) < minJWTSecretLength {
+		add("auth.jwt_secret: JWT_SECRET must be at least %d characters long", minJWTSecretLength)
+	}
+	if c.Auth.AccessTokenTTL >= c.Auth.RefreshTokenTTL {
+		add("auth.access_token_ttl: must be shorter than auth.refresh_token_ttl")
+	}
+
+	if len(c.CORS.AllowedOrigins) == 0 {
+		add("cors.allowed_origins: at least one origin is required")
+	}
+	for _, origin := range c.CORS.AllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" {
+			add("cors.allowed_origins: %q is not an origin like https://example.com", origin)
+		}
+	}
+
+	if c.Upload.Dir == "" {
+		add("upload.dir: must not be empty")
+	}
+	if c.Upload.MaxAvatarSize <= 0 {
+		add("upload.max_avatar_size: must be positive, got %d", c.Upload.MaxAvatarSize)
+	}
+	if c.Upload.MaxReturnPhotoSize <= 0 {
+		add("upload.max_return_photo_size: must be positive, got %d", c.Upload.MaxReturnPhotoSize)
+	}
+
+	if !slices.Contains([]string{"none", "otlp", "stdout", "memory"}, c.Tracing.Exporter) {
+		add("tracing.exporter: must be one of none, otlp, stdout, memory, got %q", c.Tracing.Exporter)
+	}
+	if c.Tracing.ServiceName == "" {
+		add("tracing.service_name: must not be empty")
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		add("tracing.sample_ratio: must be between 0 and 1, got %g", c.Tracing.SampleRatio)
+	}
+
+	if !slices.Contains([]string{"half_up", "half_even", "down", "up"}, c.Tax.Rounding) {
+		add("tax.rounding: must be one of half_up, half_even, down, up, got %q", c.Tax.Rounding)
+	}
+	if c.Tax.DefaultRate < 0 || c.Tax.DefaultRate > 10000 {
+		add("tax.default_rate: must be between 0 and 10000 basis points, got %d", c.Tax.DefaultRate)
+	}
+
+	if c.Orders.ExpiryBatchSize <= 0 {
+		add("orders.expiry_batch_size: must be positive, got %d", c.Orders.ExpiryBatchSize)
+	}
+	if c.Returns.MaxPhotos < 0 {
+		add("returns.max_photos: must not be negative, got %d", c.Returns.MaxPhotos)
+	}
+
+	if !slices.Contains([]string{"pdf", "html"}, c.Receipts.Format) {
+		add("receipts.format: must be one of pdf, html, got %q", c.Receipts.Format)
+	}
+	if c.Receipts.FontPath != "" {
+		if _, err := os.Stat(c.Receipts.FontPath); err != nil {
+			add("receipts.font_path: %v", err)
+		}
+	}
+
+	if _, err := time.LoadLocation(c.Pickup.Timezone); err != nil || c.Pickup.Timezone == "" {
+		add("pickup.timezone: %q is not a known time zone", c.Pickup.Timezone)
+	}
+
+	switch c.Payment.Provider {
+	case "mock":
+		if !slices.Contains([]string{"success", "failure"}, c.Payment.Mock.Outcome) {
+			add("payment.mock.outcome: must be one of success, failure, got %q", c.Payment.Mock.Outcome)
+		}
+		if c.Payment.Mock.Delay < 0 {
+			add("payment.mock.delay: must not be negative, got %s", c.Payment.Mock.Delay)
+		}
+		if u := c.Payment.Mock.CallbackURL; u != "" {
+			if parsed, err := url.Parse(u); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+				add("payment.mock.callback_url: %q is not an absolute URL", u)
+			}
+		}
+		if len(c.Payment.Mock.Secret) < minPaymentMockSecretLength {
+			add("payment.mock.secret: PAYMENT_MOCK_SECRET must be at least %d characters long", minPaymentMockSecretLength)
+		}
+	default:
+		add("payment.provider: must be mock, got %q", c.Payment.Provider)
+	}
+
+	return errs
+}
+
+// envLoader overrides config fields from environment variables and collects
+// the variables that could not be parsed.
+type envLoader struct {
+	errs []error
+}
+
+func (e *envLoader) lookup(name string) (string, bool) {
+	v, ok := os.LookupEnv(name)
+	if !ok || strings.TrimSpace(v) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(v), true
+}
+
+func (e *envLoader) string(name string, dst *string) {
+	if v, ok := e.lookup(name); ok {
+		*dst = v
+	}
+}
+
+func (e *envLoader) list(name string, dst *[]string) {
+	v, ok := e.lookup(name)
+	if !ok {
+		return
+	}
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*dst = items
+}
+
+func (e *envLoader) duration(name string, dst *time.Duration) {
+	v, ok := e.lookup(name)
+	if !ok {
+		return
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: invalid duration %q", name, v))
+		return
+	}
+	*dst = d
+}
+
+func (e *envLoader) int32(name string, dst *int32) {
+	v, ok := e.lookup(name)
+	if !ok {
+		return
+	}
+	n, err := strconv.ParseInt(v, 10, 32)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: invalid integer %q", name, v))
+		return
+	}
+	*dst = int32(n)
+}
+
+func (e *envLoader) int64(name string, dst *int64) {
+	v, ok := e.lookup(name)
+	if !ok {
+		return
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: invalid integer %q", name, v))
+		return
+	}
+	*dst = n
+}
+
+func (e *envLoader) float64(name string, dst *float64) {
+	v, ok := e.lookup(name)
+	if !ok {
+		return
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: invalid number %q", name, v))
+		return
+	}
+	*dst = f
+}
+
+func (e *envLoader) bool(name string, dst *bool) {
+	v, ok := e.lookup(name)
+	if !ok {
+		return
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: invalid boolean %q", name, v))
+		return
+	}
+	*dst = b
+}
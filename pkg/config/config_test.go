@@ -0,0 +1,99 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// validConfig returns the defaults with the settings that have no default.
+func validConfig() Config {
+	c := defaults()
+	c.DB.URL = "postgres://localhost/orders"
+	c.Auth.JWTSecret = strings.Repeat("j", minJWTSecretLength)
+	c.Payment.Mock.Secret = strings.Repeat("p", minPaymentMockSecretLength)
+	return c
+}
+
+func TestValidate(t *testing.T) {
+	valid := validConfig()
+	if errs := valid.validate(); len(errs) > 0 {
+		t.Fatalf("valid config rejected: %v", errs)
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr string
+	}{
+		{"short jwt secret", func(c *Config) { c.Auth.JWTSecret = "short" }, "auth.jwt_secret"},
+		{"short payment secret", func(c *Config) { c.Payment.Mock.Secret = "short" }, "payment.mock.secret"},
+		{"no database url", func(c *Config) { c.DB.URL = "" }, "db.url"},
+		{"bad log level", func(c *Config) { c.Log.Level = "trace" }, "log.level"},
+		{"bad port", func(c *Config) { c.HTTP.Port = "70000" }, "http.port"},
+		{"zero duration", func(c *Config) { c.HTTP.ReadTimeout = 0 }, "http.read_timeout: must be positive"},
+		{"negative duration", func(c *Config) { c.Returns.Window = -time.Hour }, "returns.window: must be positive"},
+		{"zero db duration", func(c *Config) { c.DB.HealthCheckPeriod = 0 }, "db.health_check_period: must be positive"},
+		{"min conns above max", func(c *Config) { c.DB.MinConns = c.DB.MaxConns + 1 }, "db.min_conns"},
+		{"access token outlives refresh token", func(c *Config) { c.Auth.AccessTokenTTL = c.Auth.RefreshTokenTTL }, "auth.access_token_ttl"},
+		{"lock timeout equals write timeout", func(c *Config) {
+			c.HTTP.WriteTimeout = 30 * time.Second
+			c.Idempotency.LockTimeout = 30 * time.Second
+		}, "idempotency.lock_timeout"},
+		{"lock timeout below write timeout", func(c *Config) {
+			c.HTTP.WriteTimeout = time.Minute
+			c.Idempotency.LockTimeout = 30 * time.Second
+		}, "idempotency.lock_timeout"},
+		{"lock timeout above ttl", func(c *Config) {
+			c.Idempotency.TTL = time.Minute
+			c.Idempotency.LockTimeout = 2 * time.Minute
+		}, "idempotency.lock_timeout"},
+		{"drain delay not below shutdown timeout", func(c *Config) { c.HTTP.DrainDelay = c.HTTP.ShutdownTimeout }, "http.drain_delay"},
+		{"path in cors origin", func(c *Config) { c.CORS.AllowedOrigins = []string{"https://example.com/app"} }, "cors.allowed_origins"},
+		{"no cors origin", func(c *Config) { c.CORS.AllowedOrigins = nil }, "cors.allowed_origins"},
+		{"sample ratio above one", func(c *Config) { c.Tracing.SampleRatio = 1.5 }, "tracing.sample_ratio"},
+		{"unknown rounding", func(c *Config) { c.Tax.Rounding = "bankers" }, "tax.rounding"},
+		{"tax rate above 100%", func(c *Config) { c.Tax.DefaultRate = 10001 }, "tax.default_rate"},
+		{"unknown time zone", func(c *Config) { c.Pickup.Timezone = "Mars/Olympus" }, "pickup.timezone"},
+		{"unknown payment provider", func(c *Config) { c.Payment.Provider = "stripe" }, "payment.provider"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validConfig()
+			tt.modify(&c)
+			errs := c.validate()
+			if len(errs) != 1 {
+				t.Fatalf("validate() = %v, want one error about %s", errs, tt.wantErr)
+			}
+			if !strings.Contains(errs[0].Error(), tt.wantErr) {
+				t.Errorf("validate() = %v, want an error about %s", errs[0], tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateReportsEveryError(t *testing.T) {
+	c := validConfig()
+	c.Auth.JWTSecret = ""
+	c.Payment.Mock.Secret = ""
+	c.HTTP.Port = "http"
+	if errs := c.validate(); len(errs) != 3 {
+		t.Errorf("validate() = %v, want 3 errors", errs)
+	}
+}
+
+func TestValidateDB(t *testing.T) {
+	c := validConfig()
+	c.Auth.JWTSecret = ""
+	c.Payment.Mock.Secret = ""
+	c.HTTP.Port = "http"
+	if errs := c.validateDB(); len(errs) > 0 {
+		t.Errorf("validateDB() checked settings other than the database: %v", errs)
+	}
+
+	c.DB.URL = ""
+	c.Log.Level = "trace"
+	if errs := c.validateDB(); len(errs) != 2 {
+		t.Errorf("validateDB() = %v, want errors about db.url and log.level", errs)
+	}
+}
@@ -0,0 +1,132 @@
+package db
+
+import (
+	"fmt"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   []string
+		want    []string // <version>_<name>, followed by "-" if there is no down file
+		wantErr string
+	}{
+		{
+			name:  "sorted by version",
+			files: []string{"0010_c.up.sql", "0010_c.down.sql", "0002_b.up.sql", "0002_b.down.sql", "0001_a.up.sql", "0001_a.down.sql"},
+			want:  []string{"1_a", "2_b", "10_c"},
+		},
+		{
+			name:  "numeric not lexical order",
+			files: []string{"9_a.up.sql", "10_b.up.sql", "100_c.up.sql"},
+			want:  []string{"9_a-", "10_b-", "100_c-"},
+		},
+		{
+			name:  "gaps are kept",
+			files: []string{"0001_a.up.sql", "0001_a.down.sql", "0003_c.up.sql", "0003_c.down.sql"},
+			want:  []string{"1_a", "3_c"},
+		},
+		{
+			name:  "missing down file",
+			files: []string{"0001_a.up.sql", "0001_a.down.sql", "0002_b.up.sql"},
+			want:  []string{"1_a", "2_b-"},
+		},
+		{
+			name:  "name with underscores",
+			files: []string{"0001_order_status.up.sql", "0001_order_status.down.sql"},
+			want:  []string{"1_order_status"},
+		},
+		{
+			name:  "empty",
+			files: nil,
+			want:  []string{},
+		},
+		{
+			name:    "missing up file",
+			files:   []string{"0001_a.up.sql", "0002_b.down.sql"},
+			wantErr: "migration 2_b has no up file",
+		},
+		{
+			name:    "conflicting names",
+			files:   []string{"0001_a.up.sql", "0001_b.down.sql"},
+			wantErr: "conflicting names",
+		},
+		{
+			name:    "unexpected file",
+			files:   []string{"0001_a.up.sql", "README.md"},
+			wantErr: "unexpected migration file",
+		},
+		{
+			name:    "no name",
+			files:   []string{"0001.up.sql"},
+			wantErr: "has no name",
+		},
+		{
+			name:    "invalid version",
+			files:   []string{"one_a.up.sql"},
+			wantErr: "invalid version",
+		},
+		{
+			name:    "zero version",
+			files:   []string{"0000_a.up.sql"},
+			wantErr: "invalid version",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{"migrations": &fstest.MapFile{Mode: fs.ModeDir | 0o755}}
+			for _, f := range tt.files {
+				fsys["migrations/"+f] = &fstest.MapFile{Data: []byte("-- " + f)}
+			}
+
+			migrations, err := loadMigrations(fsys, "migrations")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("loadMigrations() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadMigrations() failed: %v", err)
+			}
+
+			got := make([]string, len(migrations))
+			for i, m := range migrations {
+				got[i] = fmt.Sprintf("%d_%s", m.Version, m.Name)
+				if !strings.HasSuffix(m.Up, ".up.sql") || (m.Down != "" && !strings.HasSuffix(m.Down, ".down.sql")) {
+					t.Errorf("migration %s: up %q, down %q", got[i], m.Up, m.Down)
+				}
+				if m.Down == "" {
+					got[i] += "-"
+				}
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("loadMigrations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestEmbeddedMigrations checks the migrations shipped in the binary: their
+// versions are contiguous from 1 and each can be rolled back.
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := loadMigrations(migrationsFS, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no embedded migrations")
+	}
+	for i, m := range migrations {
+		if m.Version != int64(i+1) {
+			t.Errorf("migration %d_%s: want version %d", m.Version, m.Name, i+1)
+		}
+		if m.Down == "" {
+			t.Errorf("migration %d_%s has no down file", m.Version, m.Name)
+		}
+	}
+}
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS order_status_history (
+	id SERIAL PRIMARY KEY,
+	order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+	from_status VARCHAR(32),
+	to_status VARCHAR(32) NOT NULL,
+	actor_user_id INTEGER REFERENCES users(id),
+	actor_role VARCHAR(16) NOT NULL,
+	reason TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_status_history_order_id ON order_status_history(order_id);
@@ -0,0 +1,17 @@
+-- Every row is one refresh token. Rotating a token revokes its row and
+-- inserts a new one with the same family_id, so a family is one login session.
+CREATE TABLE sessions (
+	id SERIAL PRIMARY KEY,
+	family_id UUID NOT NULL,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	token_hash CHAR(64) NOT NULL UNIQUE,
+	user_agent TEXT,
+	ip_address VARCHAR(64),
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	expires_at TIMESTAMP NOT NULL,
+	rotated_at TIMESTAMP,
+	revoked_at TIMESTAMP
+);
+
+CREATE INDEX idx_sessions_family_id ON sessions(family_id);
+CREATE INDEX idx_sessions_user_id ON sessions(user_id);
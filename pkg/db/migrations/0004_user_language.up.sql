@@ -0,0 +1,4 @@
+-- Language of API messages chosen by the user. NULL means the language is
+-- taken from the Accept-Language header.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS language VARCHAR(2)
+	CHECK (language IN ('ru', 'uz', 'en'));
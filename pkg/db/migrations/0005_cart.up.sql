@@ -0,0 +1,10 @@
+-- One row per product in a user's cart. Prices are not stored: the cart
+-- always shows the current product price.
+CREATE TABLE cart_items (
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+	quantity INTEGER NOT NULL CHECK (quantity > 0),
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (user_id, product_id)
+);
@@ -0,0 +1,15 @@
+-- Responses to requests sent with an Idempotency-Key header. A row without a
+-- status_code belongs to a request that is still being processed.
+CREATE TABLE idempotency_keys (
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	key VARCHAR(255) NOT NULL,
+	fingerprint CHAR(64) NOT NULL,
+	status_code INTEGER,
+	content_type TEXT,
+	response_body BYTEA,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	expires_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (user_id, key)
+);
+
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
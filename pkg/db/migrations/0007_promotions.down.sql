@@ -0,0 +1,5 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS discount_amount;
+ALTER TABLE orders DROP COLUMN IF EXISTS subtotal_amount;
+DROP TABLE IF EXISTS order_discounts;
+DROP TABLE IF EXISTS promotion_products;
+DROP TABLE IF EXISTS promotions;
@@ -0,0 +1,46 @@
+-- Promo codes. A code without product restrictions applies to the whole
+-- order, otherwise only to the lines with the listed products.
+CREATE TABLE promotions (
+	id SERIAL PRIMARY KEY,
+	code VARCHAR(64) NOT NULL UNIQUE,
+	description TEXT NOT NULL DEFAULT '',
+	discount_type VARCHAR(16) NOT NULL CHECK (discount_type IN ('percent', 'fixed')),
+	discount_value INTEGER NOT NULL CHECK (discount_value > 0),
+	min_order_amount INTEGER NOT NULL DEFAULT 0 CHECK (min_order_amount >= 0),
+	starts_at TIMESTAMPTZ,
+	ends_at TIMESTAMPTZ,
+	usage_limit INTEGER CHECK (usage_limit > 0),
+	per_user_limit INTEGER CHECK (per_user_limit > 0),
+	active BOOLEAN NOT NULL DEFAULT TRUE,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CHECK (discount_type <> 'percent' OR discount_value <= 100),
+	CHECK (starts_at IS NULL OR ends_at IS NULL OR starts_at < ends_at)
+);
+
+CREATE TABLE promotion_products (
+	promotion_id INTEGER NOT NULL REFERENCES promotions(id) ON DELETE CASCADE,
+	product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+	PRIMARY KEY (promotion_id, product_id)
+);
+
+-- Discounts applied to an order. Every row is a redemption of the promotion;
+-- the code is kept so that the order still shows it if the promotion is
+-- deleted.
+CREATE TABLE order_discounts (
+	id SERIAL PRIMARY KEY,
+	order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+	promotion_id INTEGER REFERENCES promotions(id) ON DELETE SET NULL,
+	code VARCHAR(64) NOT NULL,
+	amount INTEGER NOT NULL CHECK (amount > 0)
+);
+
+CREATE INDEX idx_order_discounts_order_id ON order_discounts(order_id);
+CREATE INDEX idx_order_discounts_promotion_id ON order_discounts(promotion_id);
+
+-- total_amount is what the customer pays: subtotal_amount minus
+-- discount_amount.
+ALTER TABLE orders ADD COLUMN subtotal_amount INTEGER;
+ALTER TABLE orders ADD COLUMN discount_amount INTEGER NOT NULL DEFAULT 0;
+UPDATE orders SET subtotal_amount = total_amount;
+ALTER TABLE orders ALTER COLUMN subtotal_amount SET NOT NULL;
@@ -0,0 +1,8 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS tax_amount;
+ALTER TABLE orders DROP COLUMN IF EXISTS net_amount;
+ALTER TABLE order_items DROP COLUMN IF EXISTS gross_amount;
+ALTER TABLE order_items DROP COLUMN IF EXISTS tax_amount;
+ALTER TABLE order_items DROP COLUMN IF EXISTS net_amount;
+ALTER TABLE order_items DROP COLUMN IF EXISTS discount_amount;
+ALTER TABLE order_items DROP COLUMN IF EXISTS vat_rate;
+ALTER TABLE products DROP COLUMN IF EXISTS vat_rate;
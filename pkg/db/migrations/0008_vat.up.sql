@@ -0,0 +1,20 @@
+-- VAT rates are stored in basis points: 1200 is 12%.
+ALTER TABLE products ADD COLUMN vat_rate INTEGER NOT NULL DEFAULT 0
+	CHECK (vat_rate BETWEEN 0 AND 10000);
+
+-- Every order line keeps the rate it was taxed at, its share of the order
+-- discount and the resulting amounts: gross_amount = net_amount + tax_amount.
+ALTER TABLE order_items ADD COLUMN vat_rate INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE order_items ADD COLUMN discount_amount INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE order_items ADD COLUMN net_amount INTEGER;
+ALTER TABLE order_items ADD COLUMN tax_amount INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE order_items ADD COLUMN gross_amount INTEGER;
+UPDATE order_items SET net_amount = quantity * price, gross_amount = quantity * price;
+ALTER TABLE order_items ALTER COLUMN net_amount SET NOT NULL;
+ALTER TABLE order_items ALTER COLUMN gross_amount SET NOT NULL;
+
+-- total_amount is the gross amount of the order: net_amount + tax_amount.
+ALTER TABLE orders ADD COLUMN net_amount INTEGER;
+ALTER TABLE orders ADD COLUMN tax_amount INTEGER NOT NULL DEFAULT 0;
+UPDATE orders SET net_amount = total_amount;
+ALTER TABLE orders ALTER COLUMN net_amount SET NOT NULL;
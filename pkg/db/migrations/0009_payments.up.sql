@@ -0,0 +1,20 @@
+-- Payments of orders with a payment provider. provider_ref identifies the
+-- invoice at the provider; callbacks are matched by it.
+CREATE TABLE payments (
+	id SERIAL PRIMARY KEY,
+	order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+	provider VARCHAR(32) NOT NULL,
+	provider_ref VARCHAR(128) NOT NULL,
+	amount INTEGER NOT NULL CHECK (amount >= 0),
+	status VARCHAR(16) NOT NULL CHECK (status IN ('pending', 'paid', 'failed')),
+	payment_url TEXT,
+	paid_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (provider, provider_ref)
+);
+
+CREATE INDEX idx_payments_order_id ON payments(order_id);
+
+-- An order has at most one invoice waiting to be paid.
+CREATE UNIQUE INDEX idx_payments_pending_order ON payments(order_id) WHERE status = 'pending';
@@ -0,0 +1,4 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS refunded_amount;
+ALTER TABLE order_items DROP COLUMN IF EXISTS refunded_quantity;
+DROP TABLE IF EXISTS refund_items;
+DROP TABLE IF EXISTS refunds;
@@ -0,0 +1,27 @@
+-- Refunds of paid orders. provider_ref identifies the refund at the payment
+-- provider; it is empty for refunds of free items, which return no money.
+CREATE TABLE refunds (
+	id SERIAL PRIMARY KEY,
+	order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+	amount INTEGER NOT NULL CHECK (amount >= 0),
+	reason TEXT NOT NULL,
+	provider_ref VARCHAR(128) NOT NULL DEFAULT '',
+	actor_user_id INTEGER REFERENCES users(id),
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_refunds_order_id ON refunds(order_id);
+
+-- The returned quantities of the order lines and their share of the refund.
+CREATE TABLE refund_items (
+	refund_id INTEGER NOT NULL REFERENCES refunds(id) ON DELETE CASCADE,
+	order_item_id INTEGER NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+	quantity INTEGER NOT NULL CHECK (quantity > 0),
+	amount INTEGER NOT NULL CHECK (amount >= 0),
+	PRIMARY KEY (refund_id, order_item_id)
+);
+
+ALTER TABLE order_items ADD COLUMN refunded_quantity INTEGER NOT NULL DEFAULT 0
+	CHECK (refunded_quantity BETWEEN 0 AND quantity);
+ALTER TABLE orders ADD COLUMN refunded_amount INTEGER NOT NULL DEFAULT 0
+	CHECK (refunded_amount BETWEEN 0 AND total_amount);
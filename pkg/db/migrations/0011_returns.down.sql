@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS return_status_history;
+DROP TABLE IF EXISTS return_photos;
+DROP TABLE IF EXISTS return_items;
+DROP TABLE IF EXISTS returns;
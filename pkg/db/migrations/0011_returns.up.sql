@@ -0,0 +1,49 @@
+-- Customer requests to return items of delivered orders. A request is
+-- settled either by a refund or by a replacement order.
+CREATE TABLE returns (
+	id SERIAL PRIMARY KEY,
+	order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+	user_id INTEGER NOT NULL REFERENCES users(id),
+	status VARCHAR(32) NOT NULL DEFAULT 'requested'
+		CHECK (status IN ('requested', 'approved', 'rejected', 'received', 'refunded', 'replaced')),
+	reason TEXT NOT NULL,
+	refund_id INTEGER REFERENCES refunds(id) ON DELETE SET NULL,
+	replacement_order_id INTEGER REFERENCES orders(id) ON DELETE SET NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_returns_order_id ON returns(order_id);
+CREATE INDEX idx_returns_user_id ON returns(user_id);
+CREATE INDEX idx_returns_status ON returns(status);
+
+CREATE TABLE return_items (
+	return_id INTEGER NOT NULL REFERENCES returns(id) ON DELETE CASCADE,
+	order_item_id INTEGER NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+	quantity INTEGER NOT NULL CHECK (quantity > 0),
+	PRIMARY KEY (return_id, order_item_id)
+);
+
+-- Photos of the returned goods. path is relative to the upload directory.
+CREATE TABLE return_photos (
+	id SERIAL PRIMARY KEY,
+	return_id INTEGER NOT NULL REFERENCES returns(id) ON DELETE CASCADE,
+	path VARCHAR(255) NOT NULL,
+	content_type VARCHAR(64) NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_return_photos_return_id ON return_photos(return_id);
+
+CREATE TABLE return_status_history (
+	id SERIAL PRIMARY KEY,
+	return_id INTEGER NOT NULL REFERENCES returns(id) ON DELETE CASCADE,
+	from_status VARCHAR(32),
+	to_status VARCHAR(32) NOT NULL,
+	actor_user_id INTEGER REFERENCES users(id),
+	actor_role VARCHAR(16) NOT NULL,
+	reason TEXT,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_return_status_history_return_id ON return_status_history(return_id);
@@ -0,0 +1,2 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS pickup_point_id;
+DROP TABLE IF EXISTS pickup_points;
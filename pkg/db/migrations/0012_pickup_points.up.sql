@@ -0,0 +1,21 @@
+-- Points where customers pick up their orders. opening_hours is a list of
+-- {"weekday", "opens", "closes"} intervals in the pickup point timezone,
+-- weekday 1 being Monday; a day without intervals is a day off.
+CREATE TABLE pickup_points (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	address VARCHAR(255) NOT NULL,
+	latitude DOUBLE PRECISION NOT NULL CHECK (latitude BETWEEN -90 AND 90),
+	longitude DOUBLE PRECISION NOT NULL CHECK (longitude BETWEEN -180 AND 180),
+	opening_hours JSONB NOT NULL DEFAULT '[]',
+	capacity INTEGER NOT NULL CHECK (capacity > 0),
+	active BOOLEAN NOT NULL DEFAULT TRUE,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- orders.pickup_point keeps the name and address the order was placed with,
+-- so it still reads correctly after the point is changed or deleted.
+ALTER TABLE orders ADD COLUMN pickup_point_id INTEGER REFERENCES pickup_points(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_orders_pickup_point_id ON orders(pickup_point_id);
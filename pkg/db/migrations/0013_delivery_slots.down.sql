@@ -0,0 +1,2 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS delivery_slot_id;
+DROP TABLE IF EXISTS delivery_slots;
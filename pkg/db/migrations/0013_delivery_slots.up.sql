@@ -0,0 +1,20 @@
+-- Time windows in which orders can be delivered to a pickup point. capacity
+-- is how many orders a slot takes; orders that are not cancelled hold a
+-- place in their slot.
+CREATE TABLE delivery_slots (
+	id SERIAL PRIMARY KEY,
+	pickup_point_id INTEGER NOT NULL REFERENCES pickup_points(id) ON DELETE CASCADE,
+	starts_at TIMESTAMPTZ NOT NULL,
+	ends_at TIMESTAMPTZ NOT NULL,
+	capacity INTEGER NOT NULL CHECK (capacity > 0),
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CHECK (starts_at < ends_at),
+	UNIQUE (pickup_point_id, starts_at)
+);
+
+-- orders.delivery_date keeps the date of the slot in the pickup point
+-- timezone, so it still reads correctly after the slot is deleted.
+ALTER TABLE orders ADD COLUMN delivery_slot_id INTEGER REFERENCES delivery_slots(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_orders_delivery_slot_id ON orders(delivery_slot_id);
@@ -0,0 +1,4 @@
+UPDATE payments SET status = 'paid' WHERE status = 'refunded';
+ALTER TABLE payments DROP CONSTRAINT payments_status_check;
+ALTER TABLE payments ADD CONSTRAINT payments_status_check
+	CHECK (status IN ('pending', 'paid', 'failed'));
@@ -0,0 +1,5 @@
+-- A payment that arrives for an order that can no longer be paid, e.g. one
+-- cancelled for non-payment, is returned to the customer in full.
+ALTER TABLE payments DROP CONSTRAINT payments_status_check;
+ALTER TABLE payments ADD CONSTRAINT payments_status_check
+	CHECK (status IN ('pending', 'paid', 'failed', 'refunded'));
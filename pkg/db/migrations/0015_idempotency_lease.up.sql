@@ -0,0 +1,3 @@
+-- A key is reserved by an unfinished request only until locked_until, so the
+-- key of a request that crashed can be taken over long before it expires.
+ALTER TABLE idempotency_keys ADD COLUMN locked_until TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
@@ -0,0 +1,14 @@
+ALTER TABLE return_status_history
+	DROP CONSTRAINT return_status_history_actor_user_id_fkey,
+	ADD CONSTRAINT return_status_history_actor_user_id_fkey
+		FOREIGN KEY (actor_user_id) REFERENCES users(id);
+
+ALTER TABLE refunds
+	DROP CONSTRAINT refunds_actor_user_id_fkey,
+	ADD CONSTRAINT refunds_actor_user_id_fkey
+		FOREIGN KEY (actor_user_id) REFERENCES users(id);
+
+ALTER TABLE order_status_history
+	DROP CONSTRAINT order_status_history_actor_user_id_fkey,
+	ADD CONSTRAINT order_status_history_actor_user_id_fkey
+		FOREIGN KEY (actor_user_id) REFERENCES users(id);
@@ -0,0 +1,16 @@
+-- History and refunds outlive the user who made them: deleting the user only
+-- forgets who the actor was.
+ALTER TABLE order_status_history
+	DROP CONSTRAINT order_status_history_actor_user_id_fkey,
+	ADD CONSTRAINT order_status_history_actor_user_id_fkey
+		FOREIGN KEY (actor_user_id) REFERENCES users(id) ON DELETE SET NULL;
+
+ALTER TABLE refunds
+	DROP CONSTRAINT refunds_actor_user_id_fkey,
+	ADD CONSTRAINT refunds_actor_user_id_fkey
+		FOREIGN KEY (actor_user_id) REFERENCES users(id) ON DELETE SET NULL;
+
+ALTER TABLE return_status_history
+	DROP CONSTRAINT return_status_history_actor_user_id_fkey,
+	ADD CONSTRAINT return_status_history_actor_user_id_fkey
+		FOREIGN KEY (actor_user_id) REFERENCES users(id) ON DELETE SET NULL;
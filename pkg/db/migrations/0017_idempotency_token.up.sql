@@ -0,0 +1,3 @@
+-- token identifies the request holding the key, so a request whose key was
+-- taken over after its lock ran out cannot complete or release it.
+ALTER TABLE idempotency_keys ADD COLUMN token UUID;
@@ -3,23 +3,26 @@ package db
 import (
 	"context"
 	"fmt"
-	"os"
 
 	"github.com/jackc/pgx/v5/pgxpool"
-)
 
-func NewDB() (*pgxpool.Pool, error) {
-	dsn := os.Getenv("DATABASE_URL")
-	if dsn == "" {
-		return nil, fmt.Errorf("DATABASE_URL is not set")
-	}
+	"github.com/Cora23tt/order_service/pkg/config"
+	"github.com/Cora23tt/order_service/pkg/lifecycle"
+)
 
-	config, err := pgxpool.ParseConfig(dsn)
+func NewDB(cfg *config.Config, lc *lifecycle.Lifecycle) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(cfg.DB.URL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse dsn: %w", err)
 	}
-
-	pool, err := pgxpool.NewWithConfig(context.Background(), config)
+	poolConfig.MaxConns = cfg.DB.MaxConns
+	poolConfig.MinConns = cfg.DB.MinConns
+	poolConfig.MaxConnLifetime = cfg.DB.MaxConnLifetime
+	poolConfig.MaxConnIdleTime = cfg.DB.MaxConnIdleTime
+	poolConfig.HealthCheckPeriod = cfg.DB.HealthCheckPeriod
+	poolConfig.ConnConfig.Tracer = newQueryTracer()
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create pool: %w", err)
 	}
@@ -28,9 +31,13 @@ func NewDB() (*pgxpool.Pool, error) {
 		return nil, fmt.Errorf("failed to ping pool: %w", err)
 	}
 
-	if err := InitAllSchemas(context.Background(), pool); err != nil {
-		return nil, fmt.Errorf("failed to initialize schemas: %w", err)
-	}
+	lc.Append(lifecycle.Hook{
+		Name: "postgres pool",
+		OnStop: func(context.Context) error {
+			pool.Close()
+			return nil
+		},
+	})
 
 	return pool, nil
 }
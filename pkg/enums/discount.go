@@ -0,0 +1,15 @@
+package enums
+
+// DiscountType is how the value of a promotion is applied.
+type DiscountType string
+
+const (
+	// DiscountPercent takes a percentage off the eligible amount.
+	DiscountPercent DiscountType = "percent"
+	// DiscountFixed takes a fixed amount off, at most the eligible amount.
+	DiscountFixed DiscountType = "fixed"
+)
+
+func (t DiscountType) IsValid() bool {
+	return t == DiscountPercent || t == DiscountFixed
+}
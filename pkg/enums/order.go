@@ -1,5 +1,7 @@
 package enums
 
+import "slices"
+
 type OrderStatus string
 
 const (
@@ -9,8 +11,28 @@ const (
 	StatusShipped        OrderStatus = "shipped"
 	StatusDelivered      OrderStatus = "delivered"
 	StatusCancelled      OrderStatus = "cancelled"
+
+	// StatusPartiallyRefunded and StatusRefunded are set by refunds only.
+	StatusPartiallyRefunded OrderStatus = "partially_refunded"
+	StatusRefunded          OrderStatus = "refunded"
 )
 
+// orderTransitions lists, for every status, the statuses an order may move to
+// next. Statuses without an entry are final. Only unpaid orders can be
+// cancelled: a paid order is called off by refunding it in full, so that the
+// customer gets the money back.
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	StatusPendingPayment: {StatusPaid, StatusCancelled},
+	StatusPaid:           {StatusProcessing, StatusPartiallyRefunded, StatusRefunded},
+	StatusProcessing:     {StatusShipped, StatusPartiallyRefunded, StatusRefunded},
+	StatusShipped:        {StatusDelivered, StatusPartiallyRefunded, StatusRefunded},
+	StatusDelivered:      {StatusPartiallyRefunded, StatusRefunded},
+	// The rest of a partially refunded order is still fulfilled, and it may
+	// be refunded again. It only moves on from the fulfilment stage it had
+	// reached, which the order service looks up in the status history.
+	StatusPartiallyRefunded: {StatusProcessing, StatusShipped, StatusDelivered, StatusPartiallyRefunded, StatusRefunded},
+}
+
 func (s OrderStatus) IsValid() bool {
 	switch s {
 	case StatusPendingPayment,
@@ -18,9 +40,48 @@ func (s OrderStatus) IsValid() bool {
 		StatusProcessing,
 		StatusShipped,
 		StatusDelivered,
-		StatusCancelled:
+		StatusCancelled,
+		StatusPartiallyRefunded,
+		StatusRefunded:
 		return true
 	default:
 		return false
 	}
 }
+
+// HoldsStock reports whether an order in this status still keeps its items
+// reserved, i.e. whether cancelling or deleting it must return them to stock.
+// A partially refunded order holds its unrefunded items until it is
+// delivered, so whether it still does depends on its fulfilment stage.
+func (s OrderStatus) HoldsStock() bool {
+	switch s {
+	case StatusPendingPayment,
+		StatusPaid,
+		StatusProcessing,
+		StatusShipped,
+		StatusPartiallyRefunded:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsFinal reports whether no further transitions are allowed from the status.
+func (s OrderStatus) IsFinal() bool {
+	return len(orderTransitions[s]) == 0
+}
+
+// IsRefund reports whether the status is set by refunds rather than by
+// changing the status directly.
+func (s OrderStatus) IsRefund() bool {
+	return s == StatusPartiallyRefunded || s == StatusRefunded
+}
+
+// NextStatuses returns the statuses an order in this status may move to.
+func (s OrderStatus) NextStatuses() []OrderStatus {
+	return slices.Clone(orderTransitions[s])
+}
+
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	return slices.Contains(orderTransitions[s], next)
+}
@@ -0,0 +1,20 @@
+package enums
+
+// PaymentStatus is the state of a payment of an order with a payment provider.
+type PaymentStatus string
+
+const (
+	// PaymentPending is an invoice that is waiting for the customer to pay.
+	PaymentPending PaymentStatus = "pending"
+	// PaymentPaid is a payment the provider confirmed.
+	PaymentPaid PaymentStatus = "paid"
+	// PaymentFailed is a payment the provider reported as failed.
+	PaymentFailed PaymentStatus = "failed"
+	// PaymentRefunded is a confirmed payment returned in full because its
+	// order could not be paid any more.
+	PaymentRefunded PaymentStatus = "refunded"
+)
+
+func (s PaymentStatus) IsValid() bool {
+	return s == PaymentPending || s == PaymentPaid || s == PaymentFailed || s == PaymentRefunded
+}
@@ -0,0 +1,48 @@
+package enums
+
+import "slices"
+
+// ReturnStatus is the state of a customer's request to return items of a
+// delivered order.
+type ReturnStatus string
+
+const (
+	// ReturnRequested is a request waiting for an admin decision.
+	ReturnRequested ReturnStatus = "requested"
+	// ReturnApproved is an accepted request whose goods are on their way back.
+	ReturnApproved ReturnStatus = "approved"
+	// ReturnRejected is a declined request.
+	ReturnRejected ReturnStatus = "rejected"
+	// ReturnReceived is a request whose goods arrived and wait for settlement.
+	ReturnReceived ReturnStatus = "received"
+	// ReturnRefunded is a request settled by refunding the returned items.
+	ReturnRefunded ReturnStatus = "refunded"
+	// ReturnReplaced is a request settled by a replacement order.
+	ReturnReplaced ReturnStatus = "replaced"
+)
+
+// returnTransitions lists, for every status, the statuses a return may move
+// to next. Statuses without an entry are final.
+var returnTransitions = map[ReturnStatus][]ReturnStatus{
+	ReturnRequested: {ReturnApproved, ReturnRejected},
+	ReturnApproved:  {ReturnReceived, ReturnRejected},
+	ReturnReceived:  {ReturnRefunded, ReturnReplaced},
+}
+
+func (s ReturnStatus) IsValid() bool {
+	switch s {
+	case ReturnRequested,
+		ReturnApproved,
+		ReturnRejected,
+		ReturnReceived,
+		ReturnRefunded,
+		ReturnReplaced:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s ReturnStatus) CanTransitionTo(next ReturnStatus) bool {
+	return slices.Contains(returnTransitions[s], next)
+}
@@ -1,21 +1,150 @@
 package errors
 
-import "errors"
+import (
+	"errors"
+	"net/http"
+)
+
+// Error is an error that can be shown to API clients. Code is a stable
+// machine readable identifier, Status the HTTP status it is answered with
+// and Message a human readable description.
+//
+// The sentinels below are *Error values, so they can still be compared with
+// == and errors.Is. Errors derived from a sentinel unwrap to it.
+type Error struct {
+	Code    string
+	Status  int
+	Message string
+	Fields  []FieldError
+	cause   error
+}
+
+// FieldError describes why a single request field was rejected. Rule is the
+// validation rule that failed (required, gte, ...) and Param its argument.
+type FieldError struct {
+	Field   string `json:"field" example:"items"`
+	Rule    string `json:"rule" example:"required"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message" example:"items is required"`
+}
+
+func New(code string, status int, message string) *Error {
+	return &Error{Code: code, Status: status, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Derive returns a more specific error with the same status that still
+// matches e with errors.Is.
+func (e *Error) Derive(code, message string) *Error {
+	return &Error{Code: code, Status: e.Status, Message: message, cause: e}
+}
 
 var (
-	ErrNotFound           = errors.New("not found")
-	ErrAlreadyExists      = errors.New("already exists")
-	ErrUnauthorized       = errors.New("unauthorized")
-	ErrInvalidInput       = errors.New("invalid input")
-	ErrInvalidCredentials = errors.New("invalid credentials")
-	ErrInternal           = errors.New("internal server error")
-	ErrForbidden          = errors.New("forbidden")
-	ErrInvalidTransition  = errors.New("invalid status transition")
-	ErrOrderCompleted     = errors.New("order already completed")
-	ErrInsufficientStock  = errors.New("insufficient stock")
+	ErrNotFound           = New("not_found", http.StatusNotFound, "not found")
+	ErrAlreadyExists      = New("already_exists", http.StatusConflict, "already exists")
+	ErrUnauthorized       = New("unauthorized", http.StatusUnauthorized, "unauthorized")
+	ErrInvalidInput       = New("invalid_input", http.StatusBadRequest, "invalid input")
+	ErrInvalidCredentials = New("invalid_credentials", http.StatusUnauthorized, "invalid credentials")
+	ErrInternal           = New("internal_error", http.StatusInternalServerError, "internal server error")
+	ErrForbidden          = New("forbidden", http.StatusForbidden, "forbidden")
+	ErrInvalidTransition  = New("invalid_status_transition", http.StatusConflict, "invalid status transition")
+	ErrOrderCompleted     = New("order_completed", http.StatusConflict, "order already completed")
+	ErrInsufficientStock  = New("insufficient_stock", http.StatusConflict, "insufficient stock")
+	ErrPriceChanged       = New("price_changed", http.StatusConflict, "price changed")
+	ErrValidation         = New("validation_failed", http.StatusBadRequest, "request validation failed")
+
+	ErrOrderNotFound    = ErrNotFound.Derive("order_not_found", "order not found")
+	ErrProductNotFound  = ErrNotFound.Derive("product_not_found", "product not found")
+	ErrUserNotFound     = ErrNotFound.Derive("user_not_found", "user not found")
+	ErrSessionNotFound  = ErrNotFound.Derive("session_not_found", "session not found")
+	ErrPhotoNotFound    = ErrNotFound.Derive("photo_not_found", "photo not found")
+	ErrRouteNotFound    = ErrNotFound.Derive("route_not_found", "route not found")
+	ErrUserExists       = ErrAlreadyExists.Derive("user_exists", "a user with this phone number already exists")
+	ErrProductExists    = ErrAlreadyExists.Derive("product_exists", "product already exists")
+	ErrUnknownProduct   = ErrInvalidInput.Derive("unknown_product", "order contains an unknown product")
+	ErrInvalidDateRange = ErrInvalidInput.Derive("invalid_date_range", "from must not be after to")
+	ErrCancelNotAllowed = ErrInvalidTransition.Derive("cancel_not_allowed", "only orders pending payment can be cancelled")
+	ErrFileTooLarge     = New("file_too_large", http.StatusRequestEntityTooLarge, "file is too large")
+	ErrBodyTooLarge     = New("body_too_large", http.StatusRequestEntityTooLarge, "request body is too large")
+	ErrCartItemNotFound = ErrNotFound.Derive("cart_item_not_found", "product is not in the cart")
+	ErrCartEmpty        = New("cart_empty", http.StatusConflict, "cart is empty")
+	ErrCartChanged      = New("cart_changed", http.StatusConflict, "cart changed during checkout")
+
+	ErrPromotionNotFound  = ErrNotFound.Derive("promotion_not_found", "promotion not found")
+	ErrPromotionExists    = ErrAlreadyExists.Derive("promotion_exists", "a promotion with this code already exists")
+	ErrPromoCodeInvalid   = New("promo_code_invalid", http.StatusUnprocessableEntity, "promo code is invalid or expired")
+	ErrPromoCodeExhausted = New("promo_code_exhausted", http.StatusConflict, "promo code usage limit reached")
+	ErrPromoMinAmount     = New("promo_min_amount", http.StatusUnprocessableEntity, "order amount is below the promo code minimum")
+	ErrPromoNotApplicable = New("promo_not_applicable", http.StatusUnprocessableEntity, "promo code does not apply to the ordered products")
+
+	ErrPaymentNotFound         = ErrNotFound.Derive("payment_not_found", "payment not found")
+	ErrPaymentProviderNotFound = ErrNotFound.Derive("payment_provider_not_found", "unknown payment provider")
+	ErrOrderNotPayable         = New("order_not_payable", http.StatusConflict, "only orders pending payment can be paid")
+	ErrInvalidSignature        = New("invalid_signature", http.StatusUnauthorized, "callback signature is invalid")
+	ErrInvalidCallback         = ErrInvalidInput.Derive("invalid_callback", "callback cannot be parsed")
+	ErrPaymentAmountMismatch   = New("payment_amount_mismatch", http.StatusUnprocessableEntity, "paid amount does not match the invoice")
+	ErrPaymentProvider         = New("payment_provider_error", http.StatusBadGateway, "payment provider is unavailable")
+
+	ErrOrderNotRefundable     = New("order_not_refundable", http.StatusConflict, "only paid orders can be refunded")
+	ErrOrderItemNotFound      = ErrNotFound.Derive("order_item_not_found", "order item not found")
+	ErrRefundQuantityExceeded = New("refund_quantity_exceeded", http.StatusUnprocessableEntity, "refund quantity exceeds the quantity not refunded yet")
+	ErrRefundExceedsPaid      = New("refund_exceeds_paid", http.StatusUnprocessableEntity, "refund total exceeds the paid amount")
+	ErrRefundDeclined         = ErrPaymentProvider.Derive("refund_declined", "payment provider declined the refund")
+	ErrRefundStatusNotAllowed = ErrInvalidTransition.Derive("refund_status_not_allowed", "refund statuses are set by refunds")
+	ErrCancelRequiresRefund   = ErrInvalidTransition.Derive("cancel_requires_refund", "a paid order cannot be cancelled; refund it in full through POST /api/v1/orders/{id}/refunds")
+
+	ErrReturnNotFound         = ErrNotFound.Derive("return_not_found", "return not found")
+	ErrReturnNotAllowed       = New("return_not_allowed", http.StatusConflict, "only delivered orders can be returned")
+	ErrReturnWindowExpired    = New("return_window_expired", http.StatusConflict, "the return window of the order has expired")
+	ErrReturnQuantityExceeded = New("return_quantity_exceeded", http.StatusUnprocessableEntity, "return quantity exceeds the quantity that can still be returned")
+	ErrReturnNotEditable      = New("return_not_editable", http.StatusConflict, "photos can only be added while the return awaits a decision")
+	ErrReturnPhotoLimit       = New("return_photo_limit", http.StatusUnprocessableEntity, "the return already has the maximum number of photos")
+
+	ErrReceiptNotFound = ErrNotFound.Derive("receipt_not_found", "the order has no receipt until it is paid")
+
+	ErrPickupPointNotFound = ErrNotFound.Derive("pickup_point_not_found", "pickup point not found")
+	ErrPickupPointInactive = New("pickup_point_inactive", http.StatusConflict, "the pickup point does not accept orders")
+	ErrPickupPointClosed   = New("pickup_point_closed", http.StatusConflict, "the pickup point is closed at that time")
+	ErrPickupPointFull     = New("pickup_point_full", http.StatusConflict, "the pickup point has no room for more orders")
+
+	ErrDeliverySlotNotFound      = ErrNotFound.Derive("delivery_slot_not_found", "delivery slot not found")
+	ErrDeliverySlotFull          = New("delivery_slot_full", http.StatusConflict, "the delivery slot is fully booked")
+	ErrDeliverySlotPast          = New("delivery_slot_past", http.StatusUnprocessableEntity, "the delivery slot has already started")
+	ErrDeliverySlotBeyondHorizon = New("delivery_slot_beyond_horizon", http.StatusUnprocessableEntity, "the delivery slot is too far ahead to be booked")
+	ErrDeliverySlotMismatch      = New("delivery_slot_mismatch", http.StatusUnprocessableEntity, "the delivery slot belongs to another pickup point")
+	ErrDeliverySlotExists        = ErrAlreadyExists.Derive("delivery_slot_exists", "the pickup point already has a delivery slot starting at that time")
+	ErrDeliverySlotBooked        = New("delivery_slot_booked", http.StatusConflict, "the delivery slot has bookings")
+
+	ErrIdempotencyKeyReused  = New("idempotency_key_reused", http.StatusUnprocessableEntity, "idempotency key was already used for a different request")
+	ErrIdempotencyInProgress = New("idempotency_in_progress", http.StatusConflict, "a request with this idempotency key is still being processed")
 
 	PGErrForeignKeyViolation = "23503"
 	PGErrUniqueViolation     = "23505"
 	PGErrInvalidTextRep      = "22P02"
 	PGErrInvalidType         = "42804"
 )
+
+// From returns the API error for err. Errors that are not an *Error are
+// reported as internal errors so that no details leak to clients.
+func From(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return ErrInternal
+}
+
+// InvalidField reports a single invalid request parameter, e.g. a malformed
+// path or query parameter. Rule and param are named after the validator tags
+// (integer, gte=0, oneof=a b, ...), so clients see the same rules as for
+// request bodies.
+func InvalidField(field, rule, param string) *Error {
+	return validationError([]FieldError{newFieldError(field, rule, param)})
+}
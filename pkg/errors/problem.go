@@ -0,0 +1,33 @@
+package errors
+
+import "net/http"
+
+const ProblemContentType = "application/problem+json"
+
+// Problem is the RFC 7807 representation of an Error. Code, RequestID and
+// Errors are extension members.
+type Problem struct {
+	Type      string       `json:"type" example:"about:blank"`
+	Title     string       `json:"title" example:"Conflict"`
+	Status    int          `json:"status" example:"409"`
+	Detail    string       `json:"detail,omitempty" example:"insufficient stock"`
+	Instance  string       `json:"instance,omitempty" example:"/api/v1/orders/"`
+	Code      string       `json:"code" example:"insufficient_stock"`
+	RequestID string       `json:"request_id,omitempty" example:"5f0c7d2e-8a4b-4c1e-9d57-2f0e6b1c3a90"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// Problem builds the response body for e. The error code identifies the
+// problem, so the type is always about:blank.
+func (e *Error) Problem(instance, requestID string) Problem {
+	return Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(e.Status),
+		Status:    e.Status,
+		Detail:    e.Message,
+		Instance:  instance,
+		Code:      e.Code,
+		RequestID: requestID,
+		Errors:    e.Fields,
+	}
+}
@@ -0,0 +1,83 @@
+package errors
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var ErrInvalidBody = ErrInvalidInput.Derive("invalid_body", "request body is not valid JSON")
+
+// Validation converts an error returned by Gin's request binding into an API
+// error with one entry per rejected field.
+func Validation(err error) *Error {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make([]FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			fields = append(fields, newFieldError(fieldPath(fe), fe.Tag(), fe.Param()))
+		}
+		return validationError(fields)
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return InvalidField(typeErr.Field, "type", typeErr.Type.Kind().String())
+	}
+
+	return ErrInvalidBody
+}
+
+func validationError(fields []FieldError) *Error {
+	return &Error{
+		Code:    ErrValidation.Code,
+		Status:  ErrValidation.Status,
+		Message: ErrValidation.Message,
+		Fields:  fields,
+		cause:   ErrValidation,
+	}
+}
+
+func newFieldError(field, rule, param string) FieldError {
+	return FieldError{Field: field, Rule: rule, Param: param, Message: fieldMessage(field, rule, param)}
+}
+
+// fieldPath returns the JSON path of the field without the name of the
+// top-level struct, e.g. items[0].quantity.
+func fieldPath(fe validator.FieldError) string {
+	_, path, found := strings.Cut(fe.Namespace(), ".")
+	if !found {
+		return fe.Field()
+	}
+	return path
+}
+
+func fieldMessage(field, rule, param string) string {
+	switch rule {
+	case "required":
+		return fmt.Sprintf("%s is required", field)
+	case "gte", "min":
+		return fmt.Sprintf("%s must be at least %s", field, param)
+	case "gt":
+		return fmt.Sprintf("%s must be greater than %s", field, param)
+	case "lte", "max":
+		return fmt.Sprintf("%s must be at most %s", field, param)
+	case "oneof":
+		return fmt.Sprintf("%s must be one of: %s", field, param)
+	case "gtfield":
+		return fmt.Sprintf("%s must be greater than %s", field, param)
+	case "ltefield":
+		return fmt.Sprintf("%s must be less than or equal to %s", field, param)
+	case "integer":
+		return fmt.Sprintf("%s must be an integer", field)
+	case "date":
+		return fmt.Sprintf("%s must be a date in %s format", field, param)
+	case "type":
+		return fmt.Sprintf("%s must be of type %s", field, param)
+	default:
+		return fmt.Sprintf("%s is invalid", field)
+	}
+}
@@ -0,0 +1,158 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/Cora23tt/order_service/pkg/config"
+	"github.com/Cora23tt/order_service/pkg/db"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// checkTimeout bounds a single readiness check so that one hanging
+// dependency does not block the probe.
+const checkTimeout = 2 * time.Second
+
+// CheckFunc reports whether a dependency is usable.
+type CheckFunc func(ctx context.Context) error
+
+type CheckResult struct {
+	Status    string  `json:"status" example:"up"`
+	LatencyMS float64 `json:"latency_ms" example:"1.25"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status string                 `json:"status" example:"up"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+type namedCheck struct {
+	name string
+	fn   CheckFunc
+}
+
+// Checker runs the readiness checks of the service. Components register
+// their own checks; the server marks the checker as draining when it starts
+// shutting down so that load balancers stop sending traffic first.
+type Checker struct {
+	mu       sync.RWMutex
+	checks   []namedCheck
+	draining atomic.Bool
+}
+
+// New returns a checker with the checks of the database, the schema version
+// and the upload directory registered.
+func New(pool *pgxpool.Pool, migrator *db.Migrator, cfg *config.Config) *Checker {
+	c := &Checker{}
+	c.Register("postgres", pool.Ping)
+	c.Register("migrations", func(ctx context.Context) error {
+		current, err := migrator.CurrentVersion(ctx)
+		if err != nil {
+			return err
+		}
+		// A newer schema is fine: during a rolling deploy the new release
+		// migrates it while the old instances keep serving.
+		if expected := migrator.LatestVersion(); current < expected {
+			return fmt.Errorf("schema version is %d, expected at least %d", current, expected)
+		}
+		return nil
+	})
+	c.Register("storage", DirCheck(cfg.Upload.Dir))
+	return c
+}
+
+func (c *Checker) Register(name string, fn CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, namedCheck{name: name, fn: fn})
+}
+
+// SetDraining makes every following readiness check fail.
+func (c *Checker) SetDraining() {
+	c.draining.Store(true)
+}
+
+func (c *Checker) Draining() bool {
+	return c.draining.Load()
+}
+
+// Check runs all registered checks concurrently and reports the result of
+// each of them. The overall status is down if any check failed or the server
+// is draining.
+func (c *Checker) Check(ctx context.Context) Report {
+	c.mu.RLock()
+	checks := c.checks
+	c.mu.RUnlock()
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = run(ctx, check.fn)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusUp, Checks: make(map[string]CheckResult, len(checks)+1)}
+	for i, check := range checks {
+		report.Checks[check.name] = results[i]
+		if results[i].Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+	if c.Draining() {
+		report.Status = StatusDown
+		report.Checks["shutdown"] = CheckResult{Status: StatusDown, Error: "server is shutting down"}
+	}
+	return report
+}
+
+func run(ctx context.Context, fn CheckFunc) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := fn(ctx)
+	result := CheckResult{
+		Status:    StatusUp,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// DirCheck checks that dir exists and files can be created in it.
+func DirCheck(dir string) CheckFunc {
+	return func(context.Context) error {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", dir)
+		}
+		f, err := os.CreateTemp(dir, ".healthcheck-*")
+		if err != nil {
+			return err
+		}
+		name := f.Name()
+		f.Close()
+		return os.Remove(name)
+	}
+}
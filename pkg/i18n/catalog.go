@@ -0,0 +1,524 @@
+package i18n
+
+import (
+	"regexp"
+	"strings"
+)
+
+// messages holds the text of every API error, keyed by error code.
+var messages = map[string]map[Lang]string{
+	"not_found": {
+		Russian: "Ресурс не найден",
+		Uzbek:   "Maʼlumot topilmadi",
+		English: "not found",
+	},
+	"already_exists": {
+		Russian: "Запись уже существует",
+		Uzbek:   "Maʼlumot allaqachon mavjud",
+		English: "already exists",
+	},
+	"unauthorized": {
+		Russian: "Требуется авторизация",
+		Uzbek:   "Avtorizatsiya talab qilinadi",
+		English: "unauthorized",
+	},
+	"invalid_input": {
+		Russian: "Некорректные данные",
+		Uzbek:   "Notoʻgʻri maʼlumotlar",
+		English: "invalid input",
+	},
+	"invalid_credentials": {
+		Russian: "Неверный номер телефона или пароль",
+		Uzbek:   "Telefon raqami yoki parol notoʻgʻri",
+		English: "invalid credentials",
+	},
+	"internal_error": {
+		Russian: "Внутренняя ошибка сервера",
+		Uzbek:   "Serverning ichki xatosi",
+		English: "internal server error",
+	},
+	"forbidden": {
+		Russian: "Доступ запрещён",
+		Uzbek:   "Kirish taqiqlangan",
+		English: "forbidden",
+	},
+	"invalid_status_transition": {
+		Russian: "Недопустимый переход статуса",
+		Uzbek:   "Holatni bunday oʻzgartirib boʻlmaydi",
+		English: "invalid status transition",
+	},
+	"order_completed": {
+		Russian: "Заказ уже завершён",
+		Uzbek:   "Buyurtma allaqachon yakunlangan",
+		English: "order already completed",
+	},
+	"insufficient_stock": {
+		Russian: "Недостаточно товара на складе",
+		Uzbek:   "Omborda mahsulot yetarli emas",
+		English: "insufficient stock",
+	},
+	"price_changed": {
+		Russian: "Цена товара изменилась",
+		Uzbek:   "Mahsulot narxi oʻzgardi",
+		English: "price changed",
+	},
+	"validation_failed": {
+		Russian: "Запрос не прошёл проверку",
+		Uzbek:   "Soʻrov tekshiruvdan oʻtmadi",
+		English: "request validation failed",
+	},
+	"order_not_found": {
+		Russian: "Заказ не найден",
+		Uzbek:   "Buyurtma topilmadi",
+		English: "order not found",
+	},
+	"product_not_found": {
+		Russian: "Товар не найден",
+		Uzbek:   "Mahsulot topilmadi",
+		English: "product not found",
+	},
+	"user_not_found": {
+		Russian: "Пользователь не найден",
+		Uzbek:   "Foydalanuvchi topilmadi",
+		English: "user not found",
+	},
+	"session_not_found": {
+		Russian: "Сессия не найдена",
+		Uzbek:   "Seans topilmadi",
+		English: "session not found",
+	},
+	"photo_not_found": {
+		Russian: "Фотография не найдена",
+		Uzbek:   "Rasm topilmadi",
+		English: "photo not found",
+	},
+	"route_not_found": {
+		Russian: "Запрошенный адрес не найден",
+		Uzbek:   "Soʻralgan manzil topilmadi",
+		English: "route not found",
+	},
+	"user_exists": {
+		Russian: "Пользователь с таким номером телефона уже существует",
+		Uzbek:   "Bu telefon raqami bilan foydalanuvchi allaqachon mavjud",
+		English: "a user with this phone number already exists",
+	},
+	"product_exists": {
+		Russian: "Такой товар уже существует",
+		Uzbek:   "Bunday mahsulot allaqachon mavjud",
+		English: "product already exists",
+	},
+	"unknown_product": {
+		Russian: "Заказ содержит неизвестный товар",
+		Uzbek:   "Buyurtmada nomaʼlum mahsulot bor",
+		English: "order contains an unknown product",
+	},
+	"invalid_date_range": {
+		Russian: "Дата начала периода не может быть позже даты окончания",
+		Uzbek:   "Boshlanish sanasi tugash sanasidan keyin boʻlishi mumkin emas",
+		English: "from must not be after to",
+	},
+	"cancel_not_allowed": {
+		Russian: "Отменить можно только заказ, ожидающий оплаты",
+		Uzbek:   "Faqat toʻlov kutilayotgan buyurtmani bekor qilish mumkin",
+		English: "only orders pending payment can be cancelled",
+	},
+	"file_too_large": {
+		Russian: "Файл слишком большой",
+		Uzbek:   "Fayl hajmi juda katta",
+		English: "file is too large",
+	},
+	"body_too_large": {
+		Russian: "Тело запроса слишком большое",
+		Uzbek:   "So'rov tanasi juda katta",
+		English: "request body is too large",
+	},
+	"cart_item_not_found": {
+		Russian: "Товара нет в корзине",
+		Uzbek:   "Mahsulot savatda yoʻq",
+		English: "product is not in the cart",
+	},
+	"cart_empty": {
+		Russian: "Корзина пуста",
+		Uzbek:   "Savat boʻsh",
+		English: "cart is empty",
+	},
+	"cart_changed": {
+		Russian: "Корзина изменилась во время оформления заказа",
+		Uzbek:   "Buyurtma rasmiylashtirilayotganda savat oʻzgardi",
+		English: "cart changed during checkout",
+	},
+	"promotion_not_found": {
+		Russian: "Акция не найдена",
+		Uzbek:   "Aksiya topilmadi",
+		English: "promotion not found",
+	},
+	"promotion_exists": {
+		Russian: "Акция с таким промокодом уже существует",
+		Uzbek:   "Bunday promokodli aksiya allaqachon mavjud",
+		English: "a promotion with this code already exists",
+	},
+	"promo_code_invalid": {
+		Russian: "Промокод недействителен или срок его действия истёк",
+		Uzbek:   "Promokod yaroqsiz yoki muddati tugagan",
+		English: "promo code is invalid or expired",
+	},
+	"promo_code_exhausted": {
+		Russian: "Лимит использования промокода исчерпан",
+		Uzbek:   "Promokoddan foydalanish limiti tugagan",
+		English: "promo code usage limit reached",
+	},
+	"promo_min_amount": {
+		Russian: "Сумма заказа меньше минимальной для промокода",
+		Uzbek:   "Buyurtma summasi promokod uchun minimal summadan kam",
+		English: "order amount is below the promo code minimum",
+	},
+	"promo_not_applicable": {
+		Russian: "Промокод не распространяется на товары в заказе",
+		Uzbek:   "Promokod buyurtmadagi mahsulotlarga taalluqli emas",
+		English: "promo code does not apply to the ordered products",
+	},
+	"payment_not_found": {
+		Russian: "Платёж не найден",
+		Uzbek:   "Toʻlov topilmadi",
+		English: "payment not found",
+	},
+	"payment_provider_not_found": {
+		Russian: "Неизвестная платёжная система",
+		Uzbek:   "Nomaʼlum toʻlov tizimi",
+		English: "unknown payment provider",
+	},
+	"order_not_payable": {
+		Russian: "Оплатить можно только заказ, ожидающий оплаты",
+		Uzbek:   "Faqat toʻlov kutilayotgan buyurtmani toʻlash mumkin",
+		English: "only orders pending payment can be paid",
+	},
+	"invalid_signature": {
+		Russian: "Неверная подпись уведомления",
+		Uzbek:   "Bildirishnoma imzosi notoʻgʻri",
+		English: "callback signature is invalid",
+	},
+	"invalid_callback": {
+		Russian: "Не удалось разобрать уведомление",
+		Uzbek:   "Bildirishnomani tahlil qilib boʻlmadi",
+		English: "callback cannot be parsed",
+	},
+	"payment_amount_mismatch": {
+		Russian: "Оплаченная сумма не совпадает с суммой счёта",
+		Uzbek:   "Toʻlangan summa hisob summasiga mos kelmaydi",
+		English: "paid amount does not match the invoice",
+	},
+	"payment_provider_error": {
+		Russian: "Платёжная система недоступна",
+		Uzbek:   "Toʻlov tizimi mavjud emas",
+		English: "payment provider is unavailable",
+	},
+	"order_not_refundable": {
+		Russian: "Вернуть деньги можно только за оплаченный заказ",
+		Uzbek:   "Faqat toʻlangan buyurtma uchun pul qaytarish mumkin",
+		English: "only paid orders can be refunded",
+	},
+	"order_item_not_found": {
+		Russian: "Позиция заказа не найдена",
+		Uzbek:   "Buyurtma pozitsiyasi topilmadi",
+		English: "order item not found",
+	},
+	"refund_quantity_exceeded": {
+		Russian: "Количество к возврату больше, чем осталось невозвращённым",
+		Uzbek:   "Qaytariladigan miqdor qaytarilmagan miqdordan koʻp",
+		English: "refund quantity exceeds the quantity not refunded yet",
+	},
+	"refund_exceeds_paid": {
+		Russian: "Сумма возвратов превышает оплаченную сумму",
+		Uzbek:   "Qaytarishlar summasi toʻlangan summadan oshib ketdi",
+		English: "refund total exceeds the paid amount",
+	},
+	"refund_declined": {
+		Russian: "Платёжная система отклонила возврат",
+		Uzbek:   "Toʻlov tizimi qaytarishni rad etdi",
+		English: "payment provider declined the refund",
+	},
+	"refund_status_not_allowed": {
+		Russian: "Статусы возврата устанавливаются только при оформлении возврата",
+		Uzbek:   "Qaytarish holatlari faqat qaytarish rasmiylashtirilganda oʻrnatiladi",
+		English: "refund statuses are set by refunds",
+	},
+	"cancel_requires_refund": {
+		Russian: "Оплаченный заказ нельзя отменить; оформите полный возврат через POST /api/v1/orders/{id}/refunds",
+		Uzbek:   "Toʻlangan buyurtmani bekor qilib boʻlmaydi; POST /api/v1/orders/{id}/refunds orqali toʻliq qaytarishni rasmiylashtiring",
+		English: "a paid order cannot be cancelled; refund it in full through POST /api/v1/orders/{id}/refunds",
+	},
+	"return_not_found": {
+		Russian: "Заявка на возврат не найдена",
+		Uzbek:   "Qaytarish arizasi topilmadi",
+		English: "return not found",
+	},
+	"return_not_allowed": {
+		Russian: "Вернуть можно только доставленный заказ",
+		Uzbek:   "Faqat yetkazib berilgan buyurtmani qaytarish mumkin",
+		English: "only delivered orders can be returned",
+	},
+	"return_window_expired": {
+		Russian: "Срок возврата по заказу истёк",
+		Uzbek:   "Buyurtmani qaytarish muddati tugagan",
+		English: "the return window of the order has expired",
+	},
+	"return_quantity_exceeded": {
+		Russian: "Количество к возврату больше, чем ещё можно вернуть",
+		Uzbek:   "Qaytariladigan miqdor hali qaytarish mumkin boʻlgan miqdordan koʻp",
+		English: "return quantity exceeds the quantity that can still be returned",
+	},
+	"return_not_editable": {
+		Russian: "Фотографии можно добавлять, только пока заявка ожидает решения",
+		Uzbek:   "Rasmlarni faqat ariza qaror kutayotganda qoʻshish mumkin",
+		English: "photos can only be added while the return awaits a decision",
+	},
+	"return_photo_limit": {
+		Russian: "К заявке уже приложено максимальное количество фотографий",
+		Uzbek:   "Arizaga rasmlarning maksimal soni allaqachon biriktirilgan",
+		English: "the return already has the maximum number of photos",
+	},
+	"receipt_not_found": {
+		Russian: "Чек появится после оплаты заказа",
+		Uzbek:   "Chek buyurtma toʻlangandan keyin paydo boʻladi",
+		English: "the order has no receipt until it is paid",
+	},
+	"pickup_point_not_found": {
+		Russian: "Пункт выдачи не найден",
+		Uzbek:   "Olib ketish punkti topilmadi",
+		English: "pickup point not found",
+	},
+	"pickup_point_inactive": {
+		Russian: "Пункт выдачи не принимает заказы",
+		Uzbek:   "Olib ketish punkti buyurtmalarni qabul qilmaydi",
+		English: "the pickup point does not accept orders",
+	},
+	"pickup_point_closed": {
+		Russian: "Пункт выдачи в это время закрыт",
+		Uzbek:   "Olib ketish punkti bu vaqtda yopiq",
+		English: "the pickup point is closed at that time",
+	},
+	"pickup_point_full": {
+		Russian: "В пункте выдачи нет места для новых заказов",
+		Uzbek:   "Olib ketish punktida yangi buyurtmalar uchun joy yoʻq",
+		English: "the pickup point has no room for more orders",
+	},
+	"delivery_slot_not_found": {
+		Russian: "Слот доставки не найден",
+		Uzbek:   "Yetkazib berish vaqti topilmadi",
+		English: "delivery slot not found",
+	},
+	"delivery_slot_full": {
+		Russian: "Слот доставки полностью занят",
+		Uzbek:   "Yetkazib berish vaqti toʻliq band",
+		English: "the delivery slot is fully booked",
+	},
+	"delivery_slot_past": {
+		Russian: "Слот доставки уже начался",
+		Uzbek:   "Yetkazib berish vaqti allaqachon boshlangan",
+		English: "the delivery slot has already started",
+	},
+	"delivery_slot_beyond_horizon": {
+		Russian: "Слот доставки слишком далеко, чтобы его забронировать",
+		Uzbek:   "Yetkazib berish vaqtini bunchalik oldindan band qilib boʻlmaydi",
+		English: "the delivery slot is too far ahead to be booked",
+	},
+	"delivery_slot_mismatch": {
+		Russian: "Слот доставки относится к другому пункту выдачи",
+		Uzbek:   "Yetkazib berish vaqti boshqa olib ketish punktiga tegishli",
+		English: "the delivery slot belongs to another pickup point",
+	},
+	"delivery_slot_exists": {
+		Russian: "У пункта выдачи уже есть слот доставки с этим временем начала",
+		Uzbek:   "Olib ketish punktida shu vaqtda boshlanadigan yetkazib berish vaqti allaqachon bor",
+		English: "the pickup point already has a delivery slot starting at that time",
+	},
+	"delivery_slot_booked": {
+		Russian: "На слот доставки уже оформлены заказы",
+		Uzbek:   "Yetkazib berish vaqtiga buyurtmalar allaqachon rasmiylashtirilgan",
+		English: "the delivery slot has bookings",
+	},
+	"idempotency_key_reused": {
+		Russian: "Ключ идемпотентности уже использован для другого запроса",
+		Uzbek:   "Idempotentlik kaliti boshqa soʻrov uchun ishlatilgan",
+		English: "idempotency key was already used for a different request",
+	},
+	"idempotency_in_progress": {
+		Russian: "Запрос с этим ключом идемпотентности ещё обрабатывается",
+		Uzbek:   "Ushbu idempotentlik kaliti bilan soʻrov hali bajarilmoqda",
+		English: "a request with this idempotency key is still being processed",
+	},
+	"invalid_body": {
+		Russian: "Тело запроса не является корректным JSON",
+		Uzbek:   "Soʻrov tanasi yaroqli JSON emas",
+		English: "request body is not valid JSON",
+	},
+}
+
+// rules holds the text of a rejected field, keyed by validation rule.
+// {field} is replaced with the field name and {param} with the rule argument.
+var rules = map[string]map[Lang]string{
+	"required": {
+		Russian: "Поле «{field}» обязательно",
+		Uzbek:   "«{field}» maydoni majburiy",
+		English: "{field} is required",
+	},
+	"gte": {
+		Russian: "Поле «{field}» должно быть не меньше {param}",
+		Uzbek:   "«{field}» maydoni {param} dan kam boʻlmasligi kerak",
+		English: "{field} must be at least {param}",
+	},
+	"gt": {
+		Russian: "Поле «{field}» должно быть больше {param}",
+		Uzbek:   "«{field}» maydoni {param} dan katta boʻlishi kerak",
+		English: "{field} must be greater than {param}",
+	},
+	"lte": {
+		Russian: "Поле «{field}» должно быть не больше {param}",
+		Uzbek:   "«{field}» maydoni {param} dan oshmasligi kerak",
+		English: "{field} must be at most {param}",
+	},
+	"oneof": {
+		Russian: "Поле «{field}» должно принимать одно из значений: {param}",
+		Uzbek:   "«{field}» maydoni quyidagilardan biri boʻlishi kerak: {param}",
+		English: "{field} must be one of: {param}",
+	},
+	"gtfield": {
+		Russian: "Поле «{field}» должно быть больше поля «{param}»",
+		Uzbek:   "«{field}» maydoni «{param}» maydonidan katta boʻlishi kerak",
+		English: "{field} must be greater than {param}",
+	},
+	"ltefield": {
+		Russian: "Поле «{field}» не должно превышать поле «{param}»",
+		Uzbek:   "«{field}» maydoni «{param}» maydonidan oshmasligi kerak",
+		English: "{field} must be less than or equal to {param}",
+	},
+	"integer": {
+		Russian: "Поле «{field}» должно быть целым числом",
+		Uzbek:   "«{field}» maydoni butun son boʻlishi kerak",
+		English: "{field} must be an integer",
+	},
+	"date": {
+		Russian: "Поле «{field}» должно быть датой в формате {param}",
+		Uzbek:   "«{field}» maydoni {param} formatidagi sana boʻlishi kerak",
+		English: "{field} must be a date in {param} format",
+	},
+	"type": {
+		Russian: "Поле «{field}» имеет неверный тип",
+		Uzbek:   "«{field}» maydonining turi notoʻgʻri",
+		English: "{field} must be of type {param}",
+	},
+	"invalid": {
+		Russian: "Поле «{field}» заполнено неверно",
+		Uzbek:   "«{field}» maydoni notoʻgʻri toʻldirilgan",
+		English: "{field} is invalid",
+	},
+}
+
+// ruleAliases maps validator tags that share a message with another rule.
+var ruleAliases = map[string]string{
+	"min":      "gte",
+	"max":      "lte",
+	"datetime": "date",
+}
+
+// fields holds the names of request fields, keyed by their JSON name. English
+// messages use the JSON name itself.
+var fields = map[string]map[Lang]string{
+	"phone_number":     {Russian: "номер телефона", Uzbek: "telefon raqami"},
+	"password":         {Russian: "пароль", Uzbek: "parol"},
+	"refresh_token":    {Russian: "refresh-токен", Uzbek: "refresh-token"},
+	"name":             {Russian: "название", Uzbek: "nomi"},
+	"description":      {Russian: "описание", Uzbek: "tavsif"},
+	"image_url":        {Russian: "ссылка на изображение", Uzbek: "rasm havolasi"},
+	"price":            {Russian: "цена", Uzbek: "narx"},
+	"quantity":         {Russian: "количество", Uzbek: "miqdor"},
+	"items":            {Russian: "товары", Uzbek: "mahsulotlar"},
+	"product_id":       {Russian: "ID товара", Uzbek: "mahsulot ID"},
+	"expected_price":   {Russian: "ожидаемая цена", Uzbek: "kutilgan narx"},
+	"pickup_point":     {Russian: "пункт выдачи", Uzbek: "olib ketish punkti"},
+	"status":           {Russian: "статус", Uzbek: "holat"},
+	"reason":           {Russian: "причина", Uzbek: "sabab"},
+	"id":               {Russian: "ID", Uzbek: "ID"},
+	"from":             {Russian: "дата начала", Uzbek: "boshlanish sanasi"},
+	"to":               {Russian: "дата окончания", Uzbek: "tugash sanasi"},
+	"user_id":          {Russian: "ID пользователя", Uzbek: "foydalanuvchi ID"},
+	"min_amount":       {Russian: "минимальная сумма", Uzbek: "minimal summa"},
+	"max_amount":       {Russian: "максимальная сумма", Uzbek: "maksimal summa"},
+	"limit":            {Russian: "лимит", Uzbek: "limit"},
+	"offset":           {Russian: "смещение", Uzbek: "siljish"},
+	"pinfl":            {Russian: "ПИНФЛ", Uzbek: "JShShIR"},
+	"language":         {Russian: "язык", Uzbek: "til"},
+	"avatar":           {Russian: "аватар", Uzbek: "avatar"},
+	"promo_code":       {Russian: "промокод", Uzbek: "promokod"},
+	"code":             {Russian: "код", Uzbek: "kod"},
+	"discount_type":    {Russian: "тип скидки", Uzbek: "chegirma turi"},
+	"discount_value":   {Russian: "размер скидки", Uzbek: "chegirma miqdori"},
+	"min_order_amount": {Russian: "минимальная сумма заказа", Uzbek: "buyurtmaning minimal summasi"},
+	"starts_at":        {Russian: "начало действия", Uzbek: "amal qilish boshlanishi"},
+	"ends_at":          {Russian: "окончание действия", Uzbek: "amal qilish tugashi"},
+	"usage_limit":      {Russian: "лимит использований", Uzbek: "foydalanish limiti"},
+	"per_user_limit":   {Russian: "лимит на пользователя", Uzbek: "foydalanuvchi uchun limit"},
+	"product_ids":      {Russian: "товары", Uzbek: "mahsulotlar"},
+	"active":           {Russian: "активность", Uzbek: "faollik"},
+	"vat_rate":         {Russian: "ставка НДС", Uzbek: "QQS stavkasi"},
+	"item_id":          {Russian: "ID позиции заказа", Uzbek: "buyurtma pozitsiyasi ID"},
+	"order_id":         {Russian: "ID заказа", Uzbek: "buyurtma ID"},
+	"photo":            {Russian: "фотография", Uzbek: "rasm"},
+	"pickup_point_id":  {Russian: "ID пункта выдачи", Uzbek: "olib ketish punkti ID"},
+	"address":          {Russian: "адрес", Uzbek: "manzil"},
+	"latitude":         {Russian: "широта", Uzbek: "kenglik"},
+	"longitude":        {Russian: "долгота", Uzbek: "uzunlik"},
+	"lat":              {Russian: "широта", Uzbek: "kenglik"},
+	"lon":              {Russian: "долгота", Uzbek: "uzunlik"},
+	"opening_hours":    {Russian: "часы работы", Uzbek: "ish vaqti"},
+	"weekday":          {Russian: "день недели", Uzbek: "hafta kuni"},
+	"opens":            {Russian: "время открытия", Uzbek: "ochilish vaqti"},
+	"closes":           {Russian: "время закрытия", Uzbek: "yopilish vaqti"},
+	"capacity":         {Russian: "вместимость", Uzbek: "sigʻim"},
+	"delivery_slot_id": {Russian: "ID слота доставки", Uzbek: "yetkazib berish vaqti ID"},
+	"slot_id":          {Russian: "ID слота доставки", Uzbek: "yetkazib berish vaqti ID"},
+	"Idempotency-Key":  {Russian: "заголовок Idempotency-Key", Uzbek: "Idempotency-Key sarlavhasi"},
+}
+
+// Message returns the text for an error code in lang, or fallback if the
+// catalog does not know the code.
+func Message(lang Lang, code, fallback string) string {
+	if text, ok := messages[code][lang]; ok {
+		return text
+	}
+	return fallback
+}
+
+// FieldMessage returns the text for a request field that failed a validation
+// rule. Field is the JSON path of the field, e.g. items[0].quantity.
+func FieldMessage(lang Lang, field, rule, param string) string {
+	if alias, ok := ruleAliases[rule]; ok {
+		rule = alias
+	}
+	template, ok := rules[rule][lang]
+	if !ok {
+		template = rules["invalid"][lang]
+	}
+	if rule == "ltefield" || rule == "gtfield" {
+		param = FieldName(lang, param)
+	}
+	return strings.NewReplacer("{field}", FieldName(lang, field), "{param}", param).Replace(template)
+}
+
+var indexPattern = regexp.MustCompile(`\[\d+\]`)
+
+// FieldName returns the name of the field at path in lang. Nested paths are
+// named after their last element.
+func FieldName(lang Lang, path string) string {
+	if lang == English {
+		return path
+	}
+	name := indexPattern.ReplaceAllString(path, "")
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	if text, ok := fields[name][lang]; ok {
+		return text
+	}
+	return path
+}
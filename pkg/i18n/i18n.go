@@ -0,0 +1,84 @@
+package i18n
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Lang is a language the API can answer in.
+type Lang string
+
+const (
+	Russian Lang = "ru"
+	Uzbek   Lang = "uz" // Latin script
+	English Lang = "en"
+)
+
+// Default is used when neither the user profile nor Accept-Language names a
+// supported language.
+const Default = English
+
+// Supported lists the languages in validator oneof format.
+const Supported = "ru uz en"
+
+// Parse returns the language for a language tag such as "ru", "ru-RU" or
+// "uz-Latn-UZ". Only the primary subtag is taken into account.
+func Parse(tag string) (Lang, bool) {
+	primary, _, _ := strings.Cut(strings.TrimSpace(tag), "-")
+	switch lang := Lang(strings.ToLower(primary)); lang {
+	case Russian, Uzbek, English:
+		return lang, true
+	default:
+		return "", false
+	}
+}
+
+// FromAcceptLanguage picks the supported language the client prefers most in
+// an Accept-Language header value.
+func FromAcceptLanguage(header string) (Lang, bool) {
+	type candidate struct {
+		tag string
+		q   float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{tag: tag, q: q})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	for _, c := range candidates {
+		if lang, ok := Parse(c.tag); ok {
+			return lang, true
+		}
+	}
+	return "", false
+}
+
+type ctxKey struct{}
+
+// WithLang returns a copy of ctx that carries the language of the response.
+func WithLang(ctx context.Context, lang Lang) context.Context {
+	return context.WithValue(ctx, ctxKey{}, lang)
+}
+
+// FromContext returns the language stored by WithLang, or Default.
+func FromContext(ctx context.Context) Lang {
+	if lang, ok := ctx.Value(ctxKey{}).(Lang); ok {
+		return lang
+	}
+	return Default
+}
@@ -0,0 +1,115 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// Hook is a component that has to be started with the application and
+// stopped when it shuts down. Either function may be nil.
+type Hook struct {
+	Name    string
+	OnStart func(ctx context.Context) error
+	OnStop  func(ctx context.Context) error
+}
+
+// Lifecycle starts hooks in the order they were appended and stops them in
+// reverse order. Constructors provided to the dig container append hooks for
+// the resources they create, so dependencies are always started before and
+// stopped after the components that use them.
+type Lifecycle struct {
+	log *zap.SugaredLogger
+
+	mu      sync.Mutex
+	hooks   []Hook
+	started int
+
+	errOnce sync.Once
+	errc    chan error
+}
+
+func New(log *zap.SugaredLogger) *Lifecycle {
+	return &Lifecycle{log: log, errc: make(chan error, 1)}
+}
+
+func (l *Lifecycle) Append(h Hook) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hooks = append(l.hooks, h)
+}
+
+// Start runs the start hooks. If one fails, the hooks that were already
+// started are stopped and the error is returned.
+func (l *Lifecycle) Start(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for l.started < len(l.hooks) {
+		h := l.hooks[l.started]
+		if h.OnStart != nil {
+			if err := h.OnStart(ctx); err != nil {
+				startErr := fmt.Errorf("start %s: %w", h.Name, err)
+				if stopErr := l.stopLocked(ctx); stopErr != nil {
+					return errors.Join(startErr, stopErr)
+				}
+				return startErr
+			}
+		}
+		l.log.Debugw("component started", "name", h.Name)
+		l.started++
+	}
+	return nil
+}
+
+// Stop runs the stop hooks of the started components in reverse order. Every
+// hook is called even if an earlier one fails; the errors are joined.
+func (l *Lifecycle) Stop(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stopLocked(ctx)
+}
+
+func (l *Lifecycle) stopLocked(ctx context.Context) error {
+	var errs []error
+	for ; l.started > 0; l.started-- {
+		h := l.hooks[l.started-1]
+		if h.OnStop == nil {
+			continue
+		}
+		if err := h.OnStop(ctx); err != nil {
+			l.log.Errorw("component stop failed", "name", h.Name, "error", err)
+			errs = append(errs, fmt.Errorf("stop %s: %w", h.Name, err))
+			continue
+		}
+		l.log.Debugw("component stopped", "name", h.Name)
+	}
+	return errors.Join(errs...)
+}
+
+// Go runs fn in a background goroutine. If it returns an error, the error is
+// reported on Err so the application can shut down.
+func (l *Lifecycle) Go(name string, fn func() error) {
+	go func() {
+		if err := fn(); err != nil {
+			l.Fail(fmt.Errorf("%s: %w", name, err))
+		}
+	}()
+}
+
+// Fail reports a fatal error of a running component. Only the first error is
+// kept.
+func (l *Lifecycle) Fail(err error) {
+	l.errOnce.Do(func() {
+		l.errc <- err
+	})
+}
+
+// Err returns a channel that receives the first fatal error of a running
+// component.
+func (l *Lifecycle) Err() <-chan error {
+	return l.errc
+}
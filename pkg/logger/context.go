@@ -0,0 +1,24 @@
+package logger
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type ctxKey struct{}
+
+// WithContext returns a copy of ctx that carries log. Request handling code
+// stores a logger with the request ID, user ID and route here.
+func WithContext(ctx context.Context, log *zap.SugaredLogger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, log)
+}
+
+// FromContext returns the logger stored in ctx, or fallback if there is none,
+// e.g. for work that was not started by a request.
+func FromContext(ctx context.Context, fallback *zap.SugaredLogger) *zap.SugaredLogger {
+	if log, ok := ctx.Value(ctxKey{}).(*zap.SugaredLogger); ok {
+		return log
+	}
+	return fallback
+}
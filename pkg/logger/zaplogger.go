@@ -2,15 +2,16 @@ package logger
 
 import (
 	"fmt"
-	"os"
 	"strings"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+
+	"github.com/Cora23tt/order_service/pkg/config"
 )
 
-func New() (*zap.SugaredLogger, error) {
-	levelStr := strings.ToLower(os.Getenv("LOG_LEVEL"))
+func New(cfg *config.Config) (*zap.SugaredLogger, error) {
+	levelStr := strings.ToLower(cfg.Log.Level)
 
 	var lvl zapcore.Level
 	switch levelStr {
@@ -26,7 +27,7 @@ func New() (*zap.SugaredLogger, error) {
 		lvl = zap.InfoLevel
 	}
 
-	cfg := zap.Config{
+	zapConfig := zap.Config{
 		Level:            zap.NewAtomicLevelAt(lvl),
 		Development:      true,
 		Encoding:         "console",
@@ -43,7 +44,7 @@ func New() (*zap.SugaredLogger, error) {
 		},
 	}
 
-	zapLogger, err := cfg.Build()
+	zapLogger, err := zapConfig.Build()
 	if err != nil {
 		return nil, fmt.Errorf("logger init error: %w", err)
 	}
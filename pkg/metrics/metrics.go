@@ -0,0 +1,145 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "order_service"
+
+// Metrics holds the Prometheus collectors of the service. It uses its own
+// registry so that only the metrics registered here are exported.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+
+	ordersCreated     prometheus.Counter
+	orderTransitions  *prometheus.CounterVec
+	revenue           prometheus.Counter
+	refunds           prometheus.Counter
+	stockOutRejection prometheus.Counter
+	ordersExpired     prometheus.Counter
+	signInFailures    *prometheus.CounterVec
+}
+
+func New(pool *pgxpool.Pool) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests by route template, method and status code.",
+		}, []string{"route", "method", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route template and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		ordersCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "orders_created_total",
+			Help:      "Number of created orders.",
+		}),
+		orderTransitions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "order_status_transitions_total",
+			Help:      "Number of order status changes by source and target status.",
+		}, []string{"from", "to"}),
+		revenue: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "revenue_total",
+			Help:      "Sum of the totals of paid orders.",
+		}),
+		refunds: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "refunded_total",
+			Help:      "Sum of the refunds of paid orders.",
+		}),
+		stockOutRejection: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "stock_out_rejections_total",
+			Help:      "Number of orders rejected because of insufficient stock.",
+		}),
+		ordersExpired: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "orders_expired_total",
+			Help:      "Number of unpaid orders cancelled after the payment timeout.",
+		}),
+		signInFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "sign_in_failures_total",
+			Help:      "Number of failed sign-in attempts by reason.",
+		}, []string{"reason"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		newPoolCollector(pool),
+		m.httpRequests,
+		m.httpDuration,
+		m.ordersCreated,
+		m.orderTransitions,
+		m.revenue,
+		m.refunds,
+		m.stockOutRejection,
+		m.ordersExpired,
+		m.signInFailures,
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Registerer lets other packages add their own collectors.
+func (m *Metrics) Registerer() prometheus.Registerer {
+	return m.registry
+}
+
+func (m *Metrics) ObserveHTTPRequest(route, method string, status int, duration time.Duration) {
+	m.httpRequests.WithLabelValues(route, method, strconv.Itoa(status)).Inc()
+	m.httpDuration.WithLabelValues(route, method).Observe(duration.Seconds())
+}
+
+func (m *Metrics) OrderCreated() {
+	m.ordersCreated.Inc()
+}
+
+func (m *Metrics) OrderStatusChanged(from, to string) {
+	m.orderTransitions.WithLabelValues(from, to).Inc()
+}
+
+func (m *Metrics) RevenueReceived(amount int64) {
+	m.revenue.Add(float64(amount))
+}
+
+func (m *Metrics) RefundIssued(amount int64) {
+	m.refunds.Add(float64(amount))
+}
+
+func (m *Metrics) StockOutRejected() {
+	m.stockOutRejection.Inc()
+}
+
+// OrdersExpired counts unpaid orders cancelled by the expiry worker.
+func (m *Metrics) OrdersExpired(n int) {
+	m.ordersExpired.Add(float64(n))
+}
+
+// SignInFailed counts a rejected sign-in. Reason is a small fixed set such
+// as "unknown_user" or "invalid_password".
+func (m *Metrics) SignInFailed(reason string) {
+	m.signInFailures.WithLabelValues(reason).Inc()
+}
@@ -0,0 +1,195 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Cora23tt/order_service/pkg/config"
+	"github.com/Cora23tt/order_service/pkg/enums"
+	"github.com/Cora23tt/order_service/pkg/lifecycle"
+	"github.com/Cora23tt/order_service/pkg/utils"
+)
+
+// MockSignatureHeader carries the hex HMAC-SHA256 of a mock callback body.
+const MockSignatureHeader = "X-Mock-Signature"
+
+const (
+	mockCallbackAttempts   = 3
+	mockCallbackRetryPause = time.Second
+	mockCallbackTimeout    = 10 * time.Second
+)
+
+// MockCallback is the body of a mock provider callback.
+type MockCallback struct {
+	InvoiceID string              `json:"invoice_id"`
+	Status    enums.PaymentStatus `json:"status"`
+	Amount    int64               `json:"amount"`
+}
+
+// Mock is a provider for local development and tests. It accepts every
+// invoice and, after the configured delay, posts a signed callback with the
+// configured outcome to the callback URL. Refunds succeed unless the outcome
+// is failure.
+type Mock struct {
+	cfg    config.MockPaymentConfig
+	client *http.Client
+	log    *zap.SugaredLogger
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewMock(cfg config.MockPaymentConfig, lc *lifecycle.Lifecycle, log *zap.SugaredLogger) *Mock {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Mock{
+		cfg:    cfg,
+		client: &http.Client{Timeout: mockCallbackTimeout},
+		log:    log,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	lc.Append(lifecycle.Hook{
+		Name: "mock payment provider",
+		OnStop: func(context.Context) error {
+			m.cancel()
+			m.wg.Wait()
+			return nil
+		},
+	})
+	return m
+}
+
+func (m *Mock) Name() string {
+	return "mock"
+}
+
+func (m *Mock) CreateInvoice(ctx context.Context, invoice Invoice) (*InvoiceResult, error) {
+	if err := m.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	ref := "mock_" + utils.NewUUID()
+	status := enums.PaymentPaid
+	if m.cfg.Outcome == "failure" {
+		status = enums.PaymentFailed
+	}
+	if m.cfg.CallbackURL != "" {
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			m.sendCallback(MockCallback{InvoiceID: ref, Status: status, Amount: invoice.Amount})
+		}()
+	}
+
+	m.log.Infow("mock invoice created", "order_id", invoice.OrderID, "provider_ref", ref, "amount", invoice.Amount, "outcome", status)
+	return &InvoiceResult{ProviderRef: ref, PaymentURL: "https://pay.example.com/mock/" + ref}, nil
+}
+
+func (m *Mock) ParseCallback(_ context.Context, header http.Header, body []byte) (*Callback, error) {
+	if !hmac.Equal([]byte(header.Get(MockSignatureHeader)), []byte(m.Sign(body))) {
+		return nil, ErrInvalidSignature
+	}
+
+	var cb MockCallback
+	if err := json.Unmarshal(body, &cb); err != nil || cb.InvoiceID == "" || !cb.Status.IsValid() {
+		return nil, ErrInvalidCallback
+	}
+	return &Callback{ProviderRef: cb.InvoiceID, Status: cb.Status, Amount: cb.Amount}, nil
+}
+
+func (m *Mock) Refund(ctx context.Context, refund Refund) (*RefundResult, error) {
+	if err := m.wait(ctx); err != nil {
+		return nil, err
+	}
+	if m.cfg.Outcome == "failure" {
+		return nil, ErrDeclined
+	}
+
+	ref := "mock_refund_" + utils.NewUUID()
+	m.log.Infow("mock refund created", "payment_ref", refund.ProviderRef, "provider_ref", ref, "amount", refund.Amount)
+	return &RefundResult{ProviderRef: ref}, nil
+}
+
+// Sign returns the signature of a callback body, so that callbacks can be
+// sent by hand or from tests.
+func (m *Mock) Sign(body []byte) string {
+	mac := hmac.New(sha256.New, []byte(m.cfg.Secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// wait simulates the latency of the provider.
+func (m *Mock) wait(ctx context.Context) error {
+	return sleep(ctx, m.cfg.Delay)
+}
+
+// sendCallback posts the callback after the delay. Failed attempts are
+// retried, as the callback may arrive before the invoice is stored.
+func (m *Mock) sendCallback(cb MockCallback) {
+	body, err := json.Marshal(cb)
+	if err != nil {
+		m.log.Errorw("marshal mock callback failed", "error", err)
+		return
+	}
+
+	pause := m.cfg.Delay
+	for attempt := 1; ; attempt++ {
+		if err := sleep(m.ctx, pause); err != nil {
+			return
+		}
+		err := m.post(body)
+		if err == nil {
+			return
+		}
+		m.log.Warnw("mock callback failed", "provider_ref", cb.InvoiceID, "attempt", attempt, "error", err)
+		if attempt == mockCallbackAttempts {
+			return
+		}
+		pause = max(m.cfg.Delay, mockCallbackRetryPause)
+	}
+}
+
+func (m *Mock) post(body []byte) error {
+	req, err := http.NewRequestWithContext(m.ctx, http.MethodPost, m.cfg.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(MockSignatureHeader, m.Sign(body))
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
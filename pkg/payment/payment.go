@@ -0,0 +1,83 @@
+// Package payment defines the interface the service uses to take payments
+// through a payment provider, and the providers themselves.
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/Cora23tt/order_service/pkg/config"
+	"github.com/Cora23tt/order_service/pkg/enums"
+	"github.com/Cora23tt/order_service/pkg/lifecycle"
+)
+
+var (
+	// ErrInvalidSignature is returned for callbacks that were not signed by
+	// the provider.
+	ErrInvalidSignature = errors.New("invalid callback signature")
+	// ErrInvalidCallback is returned for callbacks that cannot be parsed.
+	ErrInvalidCallback = errors.New("invalid callback")
+	// ErrDeclined is returned when the provider refuses an operation.
+	ErrDeclined = errors.New("declined by the payment provider")
+)
+
+// Invoice is a request to pay Amount for an order.
+type Invoice struct {
+	OrderID     int64
+	Amount      int64
+	Description string
+}
+
+// InvoiceResult identifies a created invoice at the provider. The customer
+// pays it at PaymentURL.
+type InvoiceResult struct {
+	ProviderRef string
+	PaymentURL  string
+}
+
+// Callback is a payment status update sent by the provider for the invoice
+// with ProviderRef.
+type Callback struct {
+	ProviderRef string
+	Status      enums.PaymentStatus
+	Amount      int64
+}
+
+// Refund is a request to return Amount of the payment with ProviderRef.
+type Refund struct {
+	ProviderRef string
+	Amount      int64
+	Reason      string
+}
+
+// RefundResult identifies a refund at the provider.
+type RefundResult struct {
+	ProviderRef string
+}
+
+// Provider is a payment provider. Providers only talk to the outside world;
+// storing payments and changing orders is up to the caller.
+type Provider interface {
+	// Name is the name the provider is configured and its callbacks are
+	// routed by.
+	Name() string
+	CreateInvoice(ctx context.Context, invoice Invoice) (*InvoiceResult, error)
+	// ParseCallback verifies that a callback request was sent by the provider
+	// and returns the status update it carries.
+	ParseCallback(ctx context.Context, header http.Header, body []byte) (*Callback, error)
+	Refund(ctx context.Context, refund Refund) (*RefundResult, error)
+}
+
+// New returns the configured provider.
+func New(cfg *config.Config, lc *lifecycle.Lifecycle, log *zap.SugaredLogger) (Provider, error) {
+	switch cfg.Payment.Provider {
+	case "mock":
+		return NewMock(cfg.Payment.Mock, lc, log), nil
+	default:
+		return nil, fmt.Errorf("unknown payment provider %q", cfg.Payment.Provider)
+	}
+}
@@ -0,0 +1,66 @@
+package receipt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"html/template"
+)
+
+var htmlTemplate = template.Must(template.New("receipt").Funcs(template.FuncMap{
+	"date": func(r *Receipt) string { return r.Date.Format(dateLayout) },
+	"rate": formatRate,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Receipt for order {{.Receipt.OrderID}}</title>
+<style>
+body { font-family: sans-serif; max-width: 800px; margin: 2em auto; }
+header { display: flex; justify-content: space-between; }
+table { border-collapse: collapse; width: 100%; margin: 1.5em 0; }
+th, td { border: 1px solid #999; padding: 4px 8px; }
+th { background: #ebebeb; }
+td.num, th.num { text-align: right; }
+.totals td { border: none; text-align: right; }
+.totals tr:last-child { font-weight: bold; }
+</style>
+</head>
+<body>
+<header>
+<div>
+<h1>Receipt</h1>
+<p>Order #{{.Receipt.OrderID}}<br>
+Date: {{date .Receipt}}<br>
+Pickup point: {{.Receipt.PickupPoint}}</p>
+</div>
+<img src="{{.QR}}" width="140" height="140" alt="Order {{.Receipt.OrderID}}">
+</header>
+<table>
+<tr><th>Item</th><th class="num">Qty</th><th class="num">Price</th><th class="num">Discount</th><th class="num">VAT</th><th class="num">VAT amount</th><th class="num">Total</th></tr>
+{{range .Receipt.Lines}}<tr><td>{{.Name}}</td><td class="num">{{.Quantity}}</td><td class="num">{{.Price}}</td><td class="num">{{.Discount}}</td><td class="num">{{rate .VATRate}}</td><td class="num">{{.Tax}}</td><td class="num">{{.Gross}}</td></tr>
+{{end}}</table>
+<table class="totals">
+<tr><td>Subtotal</td><td>{{.Receipt.Subtotal}}</td></tr>
+<tr><td>Discount</td><td>{{.Receipt.Discount}}</td></tr>
+<tr><td>Net</td><td>{{.Receipt.Net}}</td></tr>
+<tr><td>VAT</td><td>{{.Receipt.Tax}}</td></tr>
+<tr><td>Total</td><td>{{.Receipt.Total}}</td></tr>
+</table>
+</body>
+</html>
+`))
+
+func renderHTML(r *Receipt, qr []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	err := htmlTemplate.Execute(&buf, struct {
+		Receipt *Receipt
+		QR      template.URL
+	}{
+		Receipt: r,
+		QR:      template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(qr)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
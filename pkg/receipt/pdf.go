@@ -0,0 +1,131 @@
+package receipt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// errUnsupportedText is returned when a receipt contains characters the
+// built-in PDF fonts cannot print and no TrueType font is configured.
+var errUnsupportedText = errors.New("text is not printable without a unicode font")
+
+const dateLayout = "2006-01-02 15:04"
+
+// columns of the item table: width in mm, header and alignment.
+var columns = []struct {
+	width  float64
+	header string
+	align  string
+}{
+	{70, "Item", "L"},
+	{14, "Qty", "R"},
+	{22, "Price", "R"},
+	{22, "Discount", "R"},
+	{16, "VAT", "R"},
+	{22, "VAT amount", "R"},
+	{24, "Total", "R"},
+}
+
+func (rd *Renderer) renderPDF(r *Receipt, qr []byte) ([]byte, error) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle(fmt.Sprintf("Receipt for order %d", r.OrderID), true)
+	pdf.SetCreator("order_service", true)
+
+	family := "Helvetica"
+	tr := func(s string) string { return s }
+	if rd.fontPath != "" {
+		family = "receipt"
+		pdf.AddUTF8Font(family, "", rd.fontPath)
+		pdf.AddUTF8Font(family, "B", rd.fontPath)
+	} else {
+		if !latin1(r) {
+			return nil, errUnsupportedText
+		}
+		tr = pdf.UnicodeTranslatorFromDescriptor("")
+	}
+
+	pdf.AddPage()
+
+	pdf.RegisterImageOptionsReader("qr", fpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(qr))
+	pageWidth, _ := pdf.GetPageSize()
+	_, top, right, _ := pdf.GetMargins()
+	pdf.ImageOptions("qr", pageWidth-right-35, top, 35, 35, false, fpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+
+	pdf.SetFont(family, "B", 18)
+	pdf.CellFormat(0, 10, tr("Receipt"), "", 1, "L", false, 0, "")
+	pdf.SetFont(family, "", 11)
+	pdf.CellFormat(0, 6, tr(fmt.Sprintf("Order #%d", r.OrderID)), "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 6, tr("Date: "+r.Date.Format(dateLayout)), "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 6, tr("Pickup point: "+r.PickupPoint), "", 1, "L", false, 0, "")
+	pdf.SetY(top + 40)
+
+	pdf.SetFont(family, "B", 9)
+	pdf.SetFillColor(235, 235, 235)
+	for _, col := range columns {
+		pdf.CellFormat(col.width, 7, tr(col.header), "1", 0, col.align, true, 0, "")
+	}
+	pdf.Ln(-1)
+
+	pdf.SetFont(family, "", 9)
+	for _, l := range r.Lines {
+		cells := []string{
+			l.Name,
+			strconv.FormatInt(l.Quantity, 10),
+			strconv.FormatInt(l.Price, 10),
+			strconv.FormatInt(l.Discount, 10),
+			formatRate(l.VATRate),
+			strconv.FormatInt(l.Tax, 10),
+			strconv.FormatInt(l.Gross, 10),
+		}
+		for i, col := range columns {
+			pdf.CellFormat(col.width, 7, tr(cells[i]), "1", 0, col.align, false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+	pdf.Ln(4)
+
+	totals := []struct {
+		label  string
+		amount int64
+	}{
+		{"Subtotal", r.Subtotal},
+		{"Discount", r.Discount},
+		{"Net", r.Net},
+		{"VAT", r.Tax},
+		{"Total", r.Total},
+	}
+	for i, t := range totals {
+		if i == len(totals)-1 {
+			pdf.SetFont(family, "B", 11)
+		}
+		pdf.CellFormat(166, 6, tr(t.label), "", 0, "R", false, 0, "")
+		pdf.CellFormat(24, 6, strconv.FormatInt(t.amount, 10), "", 1, "R", false, 0, "")
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// latin1 reports whether all text of r can be printed with the built-in
+// PDF fonts.
+func latin1(r *Receipt) bool {
+	texts := []string{r.PickupPoint}
+	for _, l := range r.Lines {
+		texts = append(texts, l.Name)
+	}
+	for _, s := range texts {
+		for _, c := range s {
+			if c > 0xFF {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,86 @@
+// Package receipt renders the receipt documents issued for paid orders.
+package receipt
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/skip2/go-qrcode"
+	"go.uber.org/zap"
+
+	"github.com/Cora23tt/order_service/pkg/config"
+)
+
+// Receipt is the content of a receipt. Amounts are in the same units as
+// order amounts.
+type Receipt struct {
+	OrderID     int64
+	Date        time.Time
+	PickupPoint string
+	Lines       []Line
+	Subtotal    int64
+	Discount    int64
+	Net         int64
+	Tax         int64
+	Total       int64
+}
+
+// Line is one order item on a receipt.
+type Line struct {
+	Name     string
+	Quantity int64
+	Price    int64
+	Discount int64
+	// VATRate is in basis points.
+	VATRate int64
+	Tax     int64
+	Gross   int64
+}
+
+// Document is a rendered receipt.
+type Document struct {
+	Data        []byte
+	ContentType string
+	// Ext is the file extension matching ContentType, with the dot.
+	Ext string
+}
+
+// Renderer renders receipts in the configured format.
+type Renderer struct {
+	format   string
+	fontPath string
+	log      *zap.SugaredLogger
+}
+
+func New(cfg *config.Config, log *zap.SugaredLogger) *Renderer {
+	return &Renderer{format: cfg.Receipts.Format, fontPath: cfg.Receipts.FontPath, log: log}
+}
+
+// Render renders r as a PDF, or as HTML if the renderer is configured for
+// HTML or the PDF cannot be rendered.
+func (rd *Renderer) Render(r *Receipt) (*Document, error) {
+	qr, err := qrcode.Encode(strconv.FormatInt(r.OrderID, 10), qrcode.Medium, 256)
+	if err != nil {
+		return nil, fmt.Errorf("encode qr code: %w", err)
+	}
+
+	if rd.format == "pdf" {
+		data, err := rd.renderPDF(r, qr)
+		if err == nil {
+			return &Document{Data: data, ContentType: "application/pdf", Ext: ".pdf"}, nil
+		}
+		rd.log.Warnw("pdf receipt failed, falling back to html", "orderID", r.OrderID, "error", err)
+	}
+
+	data, err := renderHTML(r, qr)
+	if err != nil {
+		return nil, err
+	}
+	return &Document{Data: data, ContentType: "text/html; charset=utf-8", Ext: ".html"}, nil
+}
+
+// formatRate formats a rate in basis points as a percentage.
+func formatRate(bp int64) string {
+	return strconv.FormatFloat(float64(bp)/100, 'f', -1, 64) + "%"
+}
@@ -0,0 +1,30 @@
+// Package storage keeps files the service generates or receives, such as
+// receipts and return photos, behind a small key-value interface so that
+// the backing store can be swapped without touching the callers.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/Cora23tt/order_service/pkg/config"
+)
+
+// ErrNotFound is returned when no file is stored under a key.
+var ErrNotFound = errors.New("file not found")
+
+// Storage stores files under slash-separated keys such as
+// "receipts/15.pdf".
+type Storage interface {
+	// Put stores the contents of r under key, replacing any previous file.
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Get opens the file stored under key. The caller closes it.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// New returns the storage files are kept in: the local upload directory.
+func New(cfg *config.Config) Storage {
+	return NewLocal(cfg.Upload.Dir)
+}
@@ -0,0 +1,74 @@
+// Package tax splits order amounts into net amount and VAT.
+package tax
+
+import "github.com/Cora23tt/order_service/pkg/config"
+
+// Rounding is how a fractional tax amount is rounded to a whole unit.
+type Rounding string
+
+const (
+	RoundHalfUp   Rounding = "half_up"
+	RoundHalfEven Rounding = "half_even"
+	RoundDown     Rounding = "down"
+	RoundUp       Rounding = "up"
+)
+
+// Rates are given in basis points: 1200 is 12%.
+const rateBase = 10000
+
+// Amounts is an amount split into its net part and VAT. Gross is Net + Tax.
+type Amounts struct {
+	Net   int64
+	Tax   int64
+	Gross int64
+}
+
+// Calculator computes VAT on order lines. Product prices include VAT if
+// PricesIncludeTax is set, otherwise VAT is added on top of them.
+type Calculator struct {
+	PricesIncludeTax bool
+	Rounding         Rounding
+	DefaultRate      int64
+}
+
+func New(cfg *config.Config) *Calculator {
+	return &Calculator{
+		PricesIncludeTax: cfg.Tax.PricesIncludeTax,
+		Rounding:         Rounding(cfg.Tax.Rounding),
+		DefaultRate:      cfg.Tax.DefaultRate,
+	}
+}
+
+// Line splits the amount of an order line taxed at rate basis points.
+func (c *Calculator) Line(amount, rate int64) Amounts {
+	if c.PricesIncludeTax {
+		tax := c.Rounding.div(amount*rate, rateBase+rate)
+		return Amounts{Net: amount - tax, Tax: tax, Gross: amount}
+	}
+	tax := c.Rounding.div(amount*rate, rateBase)
+	return Amounts{Net: amount, Tax: tax, Gross: amount + tax}
+}
+
+// div returns num/den rounded according to r. Both must not be negative.
+func (r Rounding) div(num, den int64) int64 {
+	q, rem := num/den, num%den
+	switch r {
+	case RoundDown:
+		return q
+	case RoundUp:
+		if rem > 0 {
+			q++
+		}
+		return q
+	case RoundHalfEven:
+		if 2*rem > den || (2*rem == den && q%2 == 1) {
+			q++
+		}
+		return q
+	default:
+		if 2*rem >= den {
+			q++
+		}
+		return q
+	}
+}
@@ -0,0 +1,67 @@
+package tax
+
+import "testing"
+
+func TestRoundingDiv(t *testing.T) {
+	tests := []struct {
+		num, den int64
+		want     map[Rounding]int64
+	}{
+		{0, 7, map[Rounding]int64{RoundHalfUp: 0, RoundHalfEven: 0, RoundDown: 0, RoundUp: 0}},
+		{9, 3, map[Rounding]int64{RoundHalfUp: 3, RoundHalfEven: 3, RoundDown: 3, RoundUp: 3}},
+		{1, 3, map[Rounding]int64{RoundHalfUp: 0, RoundHalfEven: 0, RoundDown: 0, RoundUp: 1}},
+		{2, 3, map[Rounding]int64{RoundHalfUp: 1, RoundHalfEven: 1, RoundDown: 0, RoundUp: 1}},
+		// Exact halves: half_even rounds to the even neighbour.
+		{5, 2, map[Rounding]int64{RoundHalfUp: 3, RoundHalfEven: 2, RoundDown: 2, RoundUp: 3}},
+		{7, 2, map[Rounding]int64{RoundHalfUp: 4, RoundHalfEven: 4, RoundDown: 3, RoundUp: 4}},
+		{15000, 10000, map[Rounding]int64{RoundHalfUp: 2, RoundHalfEven: 2, RoundDown: 1, RoundUp: 2}},
+		{45000, 10000, map[Rounding]int64{RoundHalfUp: 5, RoundHalfEven: 4, RoundDown: 4, RoundUp: 5}},
+	}
+	for _, tt := range tests {
+		for r, want := range tt.want {
+			if got := r.div(tt.num, tt.den); got != want {
+				t.Errorf("%s.div(%d, %d) = %d, want %d", r, tt.num, tt.den, got, want)
+			}
+		}
+	}
+}
+
+func TestLine(t *testing.T) {
+	tests := []struct {
+		name         string
+		includesTax  bool
+		rounding     Rounding
+		amount, rate int64
+		want         Amounts
+	}{
+		{"added exact", false, RoundHalfUp, 1000, 1200, Amounts{Net: 1000, Tax: 120, Gross: 1120}},
+		{"added zero rate", false, RoundHalfUp, 1000, 0, Amounts{Net: 1000, Tax: 0, Gross: 1000}},
+		{"added zero amount", false, RoundUp, 0, 1200, Amounts{Net: 0, Tax: 0, Gross: 0}},
+		// 10% of 15 and 45 are exact halves.
+		{"added half up", false, RoundHalfUp, 15, 1000, Amounts{Net: 15, Tax: 2, Gross: 17}},
+		{"added half even odd", false, RoundHalfEven, 15, 1000, Amounts{Net: 15, Tax: 2, Gross: 17}},
+		{"added half down", false, RoundDown, 15, 1000, Amounts{Net: 15, Tax: 1, Gross: 16}},
+		{"added half even even", false, RoundHalfEven, 45, 1000, Amounts{Net: 45, Tax: 4, Gross: 49}},
+		{"added half up above even", false, RoundHalfUp, 45, 1000, Amounts{Net: 45, Tax: 5, Gross: 50}},
+		{"included exact", true, RoundHalfUp, 1120, 1200, Amounts{Net: 1000, Tax: 120, Gross: 1120}},
+		{"included zero rate", true, RoundHalfUp, 1120, 0, Amounts{Net: 1120, Tax: 0, Gross: 1120}},
+		// 12/112 of 14 and 42 are exact halves.
+		{"included half up", true, RoundHalfUp, 14, 1200, Amounts{Net: 12, Tax: 2, Gross: 14}},
+		{"included half even odd", true, RoundHalfEven, 14, 1200, Amounts{Net: 12, Tax: 2, Gross: 14}},
+		{"included half down", true, RoundDown, 14, 1200, Amounts{Net: 13, Tax: 1, Gross: 14}},
+		{"included half even even", true, RoundHalfEven, 42, 1200, Amounts{Net: 38, Tax: 4, Gross: 42}},
+		{"included half up above even", true, RoundHalfUp, 42, 1200, Amounts{Net: 37, Tax: 5, Gross: 42}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Calculator{PricesIncludeTax: tt.includesTax, Rounding: tt.rounding}
+			got := c.Line(tt.amount, tt.rate)
+			if got != tt.want {
+				t.Errorf("Line(%d, %d) = %+v, want %+v", tt.amount, tt.rate, got, tt.want)
+			}
+			if got.Net+got.Tax != got.Gross {
+				t.Errorf("Net %d + Tax %d != Gross %d", got.Net, got.Tax, got.Gross)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+
+	"github.com/Cora23tt/order_service/pkg/config"
+	"github.com/Cora23tt/order_service/pkg/lifecycle"
+)
+
+// Provider is the tracer provider of the service. New installs it as the
+// global provider, so packages create their tracers with otel.Tracer.
+type Provider struct {
+	*sdktrace.TracerProvider
+	memory *tracetest.InMemoryExporter
+}
+
+func New(cfg *config.Config, lc *lifecycle.Lifecycle) (*Provider, error) {
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(cfg.Tracing.ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("build trace resource: %w", err)
+	}
+
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.Tracing.SampleRatio))),
+	}
+
+	p := &Provider{}
+	switch cfg.Tracing.Exporter {
+	case "otlp":
+		var exporterOpts []otlptracehttp.Option
+		if cfg.Tracing.OTLPEndpoint != "" {
+			exporterOpts = append(exporterOpts, otlptracehttp.WithEndpointURL(cfg.Tracing.OTLPEndpoint))
+		}
+		exporter, err := otlptracehttp.New(context.Background(), exporterOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("create otlp exporter: %w", err)
+		}
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	case "stdout":
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, fmt.Errorf("create stdout exporter: %w", err)
+		}
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	case "memory":
+		// Spans are exported synchronously so tests can inspect them as soon
+		// as the request returns.
+		p.memory = tracetest.NewInMemoryExporter()
+		opts = append(opts, sdktrace.WithSyncer(p.memory))
+	}
+
+	p.TracerProvider = sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(p.TracerProvider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	lc.Append(lifecycle.Hook{
+		Name:   "tracer provider",
+		OnStop: p.Shutdown,
+	})
+	return p, nil
+}
+
+// MemoryExporter returns the exporter holding the finished spans when the
+// memory exporter is configured, and nil otherwise.
+func (p *Provider) MemoryExporter() *tracetest.InMemoryExporter {
+	return p.memory
+}